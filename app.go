@@ -6,40 +6,71 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
-	"golang.org/x/crypto/ssh"
 )
 
 // App struct
 type App struct {
-	ctx                context.Context
-	jsonService        *services.JsonService
-	aesService         *services.AesService
-	kvService          *services.KvService
-	cloudflareService  *services.CloudflareService
-	pageCaptureService *services.PageCaptureService
+	ctx                 context.Context
+	jsonService         *services.JsonService
+	aesService          *services.AesService
+	kvService           *services.KvService
+	cloudflareService   *services.CloudflareService
+	pageCaptureService  *services.PageCaptureService
+	sshService          *services.SSHService
+	jobService          *services.JobService
+	terminalService     *services.TerminalService
+	transferService     *services.TransferService
+	releaseService      *services.ReleaseService
+	portService         *services.PortService
+	rolloutService      *services.RolloutService
+	healthService       *services.HealthService
+	auditService        *services.AuditService
+	projectLogService   *services.ProjectLogService
+	metricsService      *services.MetricsService
+	connectivityService *services.ConnectivityService
+	commandService      *services.CommandService
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
+	jsonService := services.NewJsonService()
+	sshService := services.NewSSHService(jsonService.Vault())
+	// 跳板机按ID引用其他服务器，SSH服务使用最近加载的服务器列表解析
+	jsonService.SetLoadCallback(sshService.RememberServers)
 	return &App{
-		jsonService:        services.NewJsonService(),
-		aesService:         services.NewAesService(),
-		kvService:          services.NewKvService(),
-		cloudflareService:  services.NewCloudflareService(),
-		pageCaptureService: services.NewPageCaptureService(),
+		jsonService:         jsonService,
+		aesService:          services.NewAesService(),
+		kvService:           services.NewKvService(),
+		cloudflareService:   services.NewCloudflareService(),
+		pageCaptureService:  services.NewPageCaptureService(),
+		sshService:          sshService,
+		jobService:          services.NewJobService(sshService),
+		terminalService:     services.NewTerminalService(sshService),
+		transferService:     services.NewTransferService(sshService),
+		releaseService:      services.NewReleaseService(sshService),
+		portService:         services.NewPortService(sshService),
+		rolloutService:      services.NewRolloutService(),
+		healthService:       services.NewHealthService(sshService),
+		auditService:        services.NewAuditService(),
+		projectLogService:   services.NewProjectLogService(sshService),
+		metricsService:      services.NewMetricsService(sshService),
+		connectivityService: services.NewConnectivityService(sshService),
+		commandService:      services.NewCommandService(),
 	}
 }
 
@@ -47,6 +78,29 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	log.Printf("Inventory store backend: %s", a.jsonService.StoreName())
+
+	// 远程命令输出和状态通过 Wails 事件推送到前端
+	emit := func(event string, data interface{}) {
+		wailsruntime.EventsEmit(a.ctx, event, data)
+	}
+	a.jobService.SetEventCallback(emit)
+	a.terminalService.SetEventCallback(emit)
+	a.transferService.SetEventCallback(emit)
+	a.rolloutService.SetEventCallback(emit)
+	a.projectLogService.SetEventCallback(emit)
+	a.metricsService.SetEventCallback(emit)
+	a.connectivityService.SetEventCallback(emit)
+}
+
+// shutdown is called when the application is shutting down
+func (a *App) shutdown(ctx context.Context) {
+	// 停止定时采集和检测，关闭打开的终端、日志跟踪和池化的SSH连接
+	a.metricsService.StopSchedule()
+	a.connectivityService.StopSchedule()
+	a.terminalService.CloseAll()
+	a.projectLogService.StopAll()
+	a.sshService.Close()
 }
 
 // beforeClose is called when the application is about to quit,
@@ -95,6 +149,98 @@ type ApiResponse struct {
 	Data interface{} `json:"data,omitempty"`
 }
 
+// conflictResponse 若错误为并发修改冲突，返回 409 响应
+func conflictResponse(err error) (string, bool) {
+	var conflict *services.ConflictError
+	if !errors.As(err, &conflict) {
+		return "", false
+	}
+	response := ApiResponse{Code: 409, Msg: conflict.Error(), Data: conflict}
+	result, _ := json.Marshal(response)
+	return string(result), true
+}
+
+// portConflictResponse 若错误为端口冲突，返回 422 响应（附带冲突详情和建议端口）
+func portConflictResponse(err error) (string, bool) {
+	var conflict *services.PortConflictError
+	if !errors.As(err, &conflict) {
+		return "", false
+	}
+	response := ApiResponse{Code: 422, Msg: conflict.Error(), Data: conflict}
+	result, _ := json.Marshal(response)
+	return string(result), true
+}
+
+// hostKeyErrorResponse 若错误为主机密钥变化，返回 460 响应（附带记录的与当前的指纹）
+func hostKeyErrorResponse(err error) (string, bool) {
+	var mismatch *services.HostKeyMismatchError
+	if !errors.As(err, &mismatch) {
+		return "", false
+	}
+	response := ApiResponse{Code: 460, Msg: mismatch.Error(), Data: mismatch}
+	result, _ := json.Marshal(response)
+	return string(result), true
+}
+
+// vaultErrorResponse 若错误为保险库未解锁、主密码错误或尚未设置保险库，返回 423 响应
+func vaultErrorResponse(err error) (string, bool) {
+	if !errors.Is(err, services.ErrVaultLocked) && !errors.Is(err, services.ErrVaultPassphrase) && !errors.Is(err, services.ErrVaultRequired) {
+		return "", false
+	}
+	response := ApiResponse{Code: 423, Msg: err.Error()}
+	result, _ := json.Marshal(response)
+	return string(result), true
+}
+
+// audited 执行修改类操作并写入审计日志（操作者、目标服务器/项目、脱敏后的参数、结果、输出和耗时）
+func (a *App) audited(action, authorization string, params map[string]interface{}, call func() string) string {
+	start := time.Now()
+	result := call()
+
+	entry := services.AuditEntry{
+		Time:       start.Format("2006-01-02 15:04:05"),
+		TokenID:    services.AuditTokenID(authorization),
+		Action:     action,
+		Params:     params,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+
+	// 目标服务器/项目优先取显式参数，其次取前端传入的服务器数据
+	for _, key := range []string{"new_server_id", "server_id", "old_server_id"} {
+		if value, _ := params[key].(string); value != "" {
+			entry.ServerID = value
+			break
+		}
+	}
+	if entry.ServerID == "" {
+		if serverData, _ := params["server_data_json"].(string); serverData != "" {
+			var server services.ServerData
+			if json.Unmarshal([]byte(serverData), &server) == nil {
+				entry.ServerID = server.ServerID
+			}
+		}
+	}
+	entry.ProjectID, _ = params["project_id"].(string)
+
+	var response struct {
+		Code int                    `json:"code"`
+		Msg  string                 `json:"msg"`
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(result), &response); err == nil {
+		entry.Code = response.Code
+		entry.Msg = response.Msg
+		entry.Output, _ = response.Data["output"].(string)
+		if entry.Output == "" {
+			entry.Output, _ = response.Data["error"].(string)
+		}
+	}
+	entry.Success = entry.Code == 200
+
+	a.auditService.Record(entry)
+	return result
+}
+
 // List 获取服务器列表 (对应 Rust 的 list 函数)
 func (a *App) List(authorization, clientJson string) string {
 	log.Printf("List called with authorization: %s", authorization)
@@ -110,6 +256,14 @@ func (a *App) List(authorization, clientJson string) string {
 	servers, kvResponse, err := a.jsonService.LoadJsonFileWithResponse(authorization, clientJson)
 	if err != nil {
 		log.Printf("Failed to load JSON file: %v", err)
+		// KV服务异常（错误状态码或非JSON响应）返回 502，便于前端区分
+		var httpErr *services.KvHTTPError
+		var decodeErr *services.KvDecodeError
+		if errors.As(err, &httpErr) || errors.As(err, &decodeErr) {
+			response := ApiResponse{Code: 502, Msg: err.Error()}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
 		response := ApiResponse{Code: 500, Msg: "Internal server error"}
 		result, _ := json.Marshal(response)
 		return string(result)
@@ -125,7 +279,8 @@ func (a *App) List(authorization, clientJson string) string {
 
 	log.Printf("Loaded %d servers from JSON", len(servers))
 
-	result, err := json.Marshal(servers)
+	// 凭据只以掩码返回给前端，无论是否已启用保险库
+	result, err := json.Marshal(services.RedactSecrets(servers))
 	if err != nil {
 		log.Printf("Failed to marshal servers: %v", err)
 		response := ApiResponse{Code: 500, Msg: "Failed to marshal data"}
@@ -161,7 +316,7 @@ func (a *App) ServerInfo(serverID, authorization, clientJson string) string {
 		return string(result)
 	}
 
-	result, err := json.Marshal(server)
+	result, err := json.Marshal(services.RedactSecrets([]services.ServerData{*server})[0])
 	if err != nil {
 		log.Printf("Failed to marshal server: %v", err)
 		response := ApiResponse{Code: 500, Msg: "Failed to marshal data"}
@@ -210,6 +365,16 @@ func (a *App) ProjectInfo(projectID, authorization, clientJson string) string {
 
 // ProjectForm 创建或更新项目 (对应 Rust 的 project_form 函数)
 func (a *App) ProjectForm(serverID, projectInfo, authorization, clientJson string) string {
+	return a.audited("ProjectForm", authorization, map[string]interface{}{
+		"server_id":    serverID,
+		"project_info": projectInfo,
+	}, func() string {
+		return a.projectForm(serverID, projectInfo, authorization, clientJson)
+	})
+}
+
+// projectForm ProjectForm 的实现
+func (a *App) projectForm(serverID, projectInfo, authorization, clientJson string) string {
 	log.Printf("ProjectForm called with serverID: %s, projectInfo: %s, authorization: %s",
 		serverID, projectInfo, authorization)
 
@@ -231,6 +396,12 @@ func (a *App) ProjectForm(serverID, projectInfo, authorization, clientJson strin
 	err := a.jsonService.AddOrUpdateProject(serverID, project, authorization, clientJson)
 	if err != nil {
 		log.Printf("Failed to add/update project: %v", err)
+		if result, ok := conflictResponse(err); ok {
+			return result
+		}
+		if result, ok := portConflictResponse(err); ok {
+			return result
+		}
 		response := ApiResponse{Code: 500, Msg: err.Error()}
 		result, _ := json.Marshal(response)
 		return string(result)
@@ -243,6 +414,16 @@ func (a *App) ProjectForm(serverID, projectInfo, authorization, clientJson strin
 
 // ProjectDelete 删除项目 (对应 Rust 的 project_delete 函数)
 func (a *App) ProjectDelete(serverID, projectID, authorization, clientJson string) string {
+	return a.audited("ProjectDelete", authorization, map[string]interface{}{
+		"server_id":  serverID,
+		"project_id": projectID,
+	}, func() string {
+		return a.projectDelete(serverID, projectID, authorization, clientJson)
+	})
+}
+
+// projectDelete ProjectDelete 的实现
+func (a *App) projectDelete(serverID, projectID, authorization, clientJson string) string {
 	log.Printf("ProjectDelete called with serverID: %s, projectID: %s, authorization: %s",
 		serverID, projectID, authorization)
 
@@ -256,6 +437,9 @@ func (a *App) ProjectDelete(serverID, projectID, authorization, clientJson strin
 	err := a.jsonService.DeleteProject(serverID, projectID, authorization, clientJson)
 	if err != nil {
 		log.Printf("Failed to delete project: %v", err)
+		if result, ok := conflictResponse(err); ok {
+			return result
+		}
 		response := ApiResponse{Code: 500, Msg: "Failed to delete project"}
 		result, _ := json.Marshal(response)
 		return string(result)
@@ -268,80 +452,129 @@ func (a *App) ProjectDelete(serverID, projectID, authorization, clientJson strin
 
 // ExecWithProjectURL 执行SQL（前端传递项目URL，避免后端查询KV）
 func (a *App) ExecWithProjectURL(projectAPIURL, sql, sqlType, authorization string) string {
+	// 只读查询不修改数据，不记录审计日志
+	if sqlType == services.SQLSelect {
+		return a.execWithProjectURL(projectAPIURL, sql, sqlType, authorization)
+	}
+	return a.audited("ExecWithProjectURL", authorization, map[string]interface{}{
+		"project_api_url": projectAPIURL,
+		"sql":             sql,
+		"sql_type":        sqlType,
+	}, func() string {
+		return a.execWithProjectURL(projectAPIURL, sql, sqlType, authorization)
+	})
+}
+
+// execWithProjectURL ExecWithProjectURL 的实现
+func (a *App) execWithProjectURL(projectAPIURL, sql, sqlType, authorization string) string {
 	log.Printf("ExecWithProjectURL called with projectAPIURL: %s, sql: %s, sqlType: %s",
 		projectAPIURL, sql, sqlType)
 
-	if projectAPIURL == "" {
-		return `{"code": 400, "msg": "Project API URL is required"}`
-	}
+	return a.projectDBExec(services.NewProjectDBClient(projectAPIURL, a.aesService), sql, sqlType, nil)
+}
+
+// ExecWithProjectURLParams 执行参数化SQL：paramsJson 为数组（对应 ? 占位符）或对象（对应 :name 占位符），
+// 参数按 dialect（sqlite 或 mysql，默认 sqlite）转义后填入；strict 为 true 时语句中不允许出现字符串或数字字面量
+func (a *App) ExecWithProjectURLParams(projectAPIURL, sql, paramsJson, sqlType, dialect string, strict bool, authorization string) string {
+	// 只读查询不修改数据，不记录审计日志
+	if sqlType == services.SQLSelect {
+		return a.execWithProjectURLParams(projectAPIURL, sql, paramsJson, sqlType, dialect, strict, authorization)
+	}
+	return a.audited("ExecWithProjectURLParams", authorization, map[string]interface{}{
+		"project_api_url": projectAPIURL,
+		"sql":             sql,
+		"params":          paramsJson,
+		"sql_type":        sqlType,
+		"dialect":         dialect,
+		"strict":          strict,
+	}, func() string {
+		return a.execWithProjectURLParams(projectAPIURL, sql, paramsJson, sqlType, dialect, strict, authorization)
+	})
+}
 
-	// 加密 SQL
-	signature, err := a.aesService.Encrypt(sql)
+// execWithProjectURLParams ExecWithProjectURLParams 的实现
+func (a *App) execWithProjectURLParams(projectAPIURL, sql, paramsJson, sqlType, dialect string, strict bool, authorization string) string {
+	log.Printf("ExecWithProjectURLParams called with projectAPIURL: %s, sql: %s, sqlType: %s, dialect: %s, strict: %v",
+		projectAPIURL, sql, sqlType, dialect, strict)
+
+	args, err := services.ParseSQLParams(paramsJson)
 	if err != nil {
-		log.Printf("Failed to encrypt SQL: %v", err)
-		return `{"code": 500, "msg": "Failed to encrypt SQL"}`
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
 	}
 
-	// 构建请求URL
-	apiURL := fmt.Sprintf("%s/dbexec", projectAPIURL)
-
-	// 准备表单数据
-	formData := url.Values{}
-	formData.Set("sql_type", sqlType)
-	formData.Set("signature", signature)
+	client := services.NewProjectDBClient(projectAPIURL, a.aesService)
+	if dialect != "" {
+		client.Dialect = dialect
+	}
+	client.Strict = strict
+	return a.projectDBExec(client, sql, sqlType, args)
+}
 
-	// 发送POST请求
-	resp, err := http.PostForm(apiURL, formData)
-	if err != nil {
-		log.Printf("Failed to send request: %v", err)
-		return fmt.Sprintf(`{"code": 500, "msg": "%s"}`, err.Error())
+// projectDBExec 通过项目数据库客户端执行SQL；查询结果的 result 保持远端的 列名->值 格式，columns 给出列顺序和类型，
+// 远端 data 中的其他字段原样放在 data 中（与 result、columns 同名的除外）
+func (a *App) projectDBExec(client *services.ProjectDBClient, sql, sqlType string, args []interface{}) string {
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	defer resp.Body.Close()
 
-	// 读取响应
-	body := make([]byte, 0)
-	buffer := make([]byte, 1024)
-	for {
-		n, err := resp.Body.Read(buffer)
-		if n > 0 {
-			body = append(body, buffer[:n]...)
+	var data interface{}
+	if sqlType == services.SQLSelect {
+		rows, err := client.Query(ctx, sql, args...)
+		if err != nil {
+			return dbErrorResponse(err)
 		}
+		fields := map[string]interface{}{}
+		for name, value := range rows.Meta {
+			fields[name] = value
+		}
+		fields["result"] = rows.Maps()
+		fields["columns"] = rows.Columns
+		data = fields
+	} else {
+		result, err := client.Exec(ctx, sqlType, sql, args...)
 		if err != nil {
-			break
+			return dbErrorResponse(err)
 		}
+		data = result
 	}
 
-	responseBody := string(body)
-	log.Printf("API response status: %d, body: %s", resp.StatusCode, responseBody)
+	response := ApiResponse{Code: 200, Msg: "Success", Data: data}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
 
-	// 检查HTTP状态码
-	if resp.StatusCode != 200 {
-		log.Printf("API request failed with status: %d", resp.StatusCode)
-		response := ApiResponse{
-			Code: resp.StatusCode,
-			Msg:  fmt.Sprintf("API请求失败，状态码: %d", resp.StatusCode),
-			Data: responseBody,
+// dbErrorResponse 远程数据库错误转换为响应，data 中附带错误码
+// 远端的 401 转换为 403，避免前端误认为本地登录失效
+func dbErrorResponse(err error) string {
+	log.Printf("Project database request failed: %v", err)
+
+	code := 500
+	var dbErr *services.DBError
+	if errors.As(err, &dbErr) {
+		switch dbErr.Code {
+		case services.DBErrInvalid:
+			code = 400
+		case services.DBErrTimeout:
+			code = 504
+		case services.DBErrCanceled:
+			code = 499
+		case services.DBErrNetwork, services.DBErrResponse:
+			code = 502
+		default:
+			if dbErr.Status != 0 && dbErr.Status != 200 {
+				code = dbErr.Status
+			}
 		}
-		result, _ := json.Marshal(response)
-		return string(result)
 	}
-
-	// 尝试验证响应是否为有效JSON
-	var testJson interface{}
-	if err := json.Unmarshal(body, &testJson); err != nil {
-		log.Printf("API response is not valid JSON: %v", err)
-		// 如果不是有效JSON，包装成标准响应格式
-		response := ApiResponse{
-			Code: 200,
-			Msg:  "Success",
-			Data: responseBody,
-		}
-		result, _ := json.Marshal(response)
-		return string(result)
+	if code == 401 {
+		code = 403
 	}
-
-	// 如果是有效JSON，直接返回
-	return responseBody
+	response := ApiResponse{Code: code, Msg: err.Error(), Data: dbErr}
+	result, _ := json.Marshal(response)
+	return string(result)
 }
 
 // ShowMessage 显示消息对话框
@@ -359,8 +592,26 @@ func (a *App) Greet(name string) string {
 }
 
 // ServerAdd 添加新服务器
-func (a *App) ServerAdd(serverID, serverName, serverIP, serverPort, serverUser, serverPassword, defaultPath, authorization, clientJson string) string {
-	log.Printf("ServerAdd called with serverID: %s, serverName: %s, defaultPath: %s, authorization: %s", serverID, serverName, defaultPath, authorization)
+func (a *App) ServerAdd(serverID, serverName, serverIP, serverPort, serverUser, serverPassword, defaultPath, authType, privateKey, keyPassphrase, authorization, clientJson string) string {
+	return a.audited("ServerAdd", authorization, map[string]interface{}{
+		"server_id":       serverID,
+		"server_name":     serverName,
+		"server_ip":       serverIP,
+		"server_port":     serverPort,
+		"server_user":     serverUser,
+		"server_password": serverPassword,
+		"default_path":    defaultPath,
+		"auth_type":       authType,
+		"private_key":     privateKey,
+		"key_passphrase":  keyPassphrase,
+	}, func() string {
+		return a.serverAdd(serverID, serverName, serverIP, serverPort, serverUser, serverPassword, defaultPath, authType, privateKey, keyPassphrase, authorization, clientJson)
+	})
+}
+
+// serverAdd ServerAdd 的实现
+func (a *App) serverAdd(serverID, serverName, serverIP, serverPort, serverUser, serverPassword, defaultPath, authType, privateKey, keyPassphrase, authorization, clientJson string) string {
+	log.Printf("ServerAdd called with serverID: %s, serverName: %s, defaultPath: %s, authType: %s, authorization: %s", serverID, serverName, defaultPath, authType, authorization)
 
 	// 检查授权
 	if authorization == "" || strings.TrimSpace(authorization) == "" {
@@ -374,6 +625,11 @@ func (a *App) ServerAdd(serverID, serverName, serverIP, serverPort, serverUser,
 		defaultPath = "/adplace"
 	}
 
+	// 如果 authType 为空，默认使用密码认证
+	if authType == "" {
+		authType = services.AuthTypePassword
+	}
+
 	// 创建新服务器数据
 	newServer := services.ServerData{
 		ServerID:       serverID,
@@ -382,6 +638,9 @@ func (a *App) ServerAdd(serverID, serverName, serverIP, serverPort, serverUser,
 		ServerPort:     serverPort,
 		ServerUser:     serverUser,
 		ServerPassword: serverPassword,
+		AuthType:       authType,
+		PrivateKey:     privateKey,
+		KeyPassphrase:  keyPassphrase,
 		DefaultPath:    defaultPath,
 		ProjectList:    []services.ProjectData{},
 	}
@@ -391,6 +650,12 @@ func (a *App) ServerAdd(serverID, serverName, serverIP, serverPort, serverUser,
 	err := a.jsonService.AddServer(newServer, authorization, clientJson)
 	if err != nil {
 		log.Printf("Failed to add server: %v", err)
+		if result, ok := vaultErrorResponse(err); ok {
+			return result
+		}
+		if result, ok := conflictResponse(err); ok {
+			return result
+		}
 		response := ApiResponse{Code: 500, Msg: err.Error()}
 		result, _ := json.Marshal(response)
 		return string(result)
@@ -401,9 +666,29 @@ func (a *App) ServerAdd(serverID, serverName, serverIP, serverPort, serverUser,
 	return string(result)
 }
 
-// ServerUpdate 更新服务器信息
-func (a *App) ServerUpdate(oldServerID, newServerID, serverName, serverIP, serverPort, serverUser, serverPassword, defaultPath, authorization, clientJson string) string {
-	log.Printf("ServerUpdate called with oldServerID: %s, newServerID: %s, serverName: %s, defaultPath: %s, authorization: %s", oldServerID, newServerID, serverName, defaultPath, authorization)
+// ServerUpdate 更新服务器信息（expectedRevision 为列表加载时的版本号，0表示不做并发检查）
+func (a *App) ServerUpdate(oldServerID, newServerID, serverName, serverIP, serverPort, serverUser, serverPassword, defaultPath, authType, privateKey, keyPassphrase, authorization, clientJson string, expectedRevision int64) string {
+	return a.audited("ServerUpdate", authorization, map[string]interface{}{
+		"old_server_id":     oldServerID,
+		"new_server_id":     newServerID,
+		"server_name":       serverName,
+		"server_ip":         serverIP,
+		"server_port":       serverPort,
+		"server_user":       serverUser,
+		"server_password":   serverPassword,
+		"default_path":      defaultPath,
+		"auth_type":         authType,
+		"private_key":       privateKey,
+		"key_passphrase":    keyPassphrase,
+		"expected_revision": expectedRevision,
+	}, func() string {
+		return a.serverUpdate(oldServerID, newServerID, serverName, serverIP, serverPort, serverUser, serverPassword, defaultPath, authType, privateKey, keyPassphrase, authorization, clientJson, expectedRevision)
+	})
+}
+
+// serverUpdate ServerUpdate 的实现
+func (a *App) serverUpdate(oldServerID, newServerID, serverName, serverIP, serverPort, serverUser, serverPassword, defaultPath, authType, privateKey, keyPassphrase, authorization, clientJson string, expectedRevision int64) string {
+	log.Printf("ServerUpdate called with oldServerID: %s, newServerID: %s, serverName: %s, defaultPath: %s, authType: %s, revision: %d, authorization: %s", oldServerID, newServerID, serverName, defaultPath, authType, expectedRevision, authorization)
 
 	// 检查授权
 	if authorization == "" || strings.TrimSpace(authorization) == "" {
@@ -417,6 +702,11 @@ func (a *App) ServerUpdate(oldServerID, newServerID, serverName, serverIP, serve
 		defaultPath = "/adplace"
 	}
 
+	// 如果 authType 为空，默认使用密码认证
+	if authType == "" {
+		authType = services.AuthTypePassword
+	}
+
 	// 创建更新的服务器数据
 	updatedServer := services.ServerData{
 		ServerID:       newServerID,
@@ -425,7 +715,11 @@ func (a *App) ServerUpdate(oldServerID, newServerID, serverName, serverIP, serve
 		ServerPort:     serverPort,
 		ServerUser:     serverUser,
 		ServerPassword: serverPassword,
+		AuthType:       authType,
+		PrivateKey:     privateKey,
+		KeyPassphrase:  keyPassphrase,
 		DefaultPath:    defaultPath,
+		Revision:       expectedRevision,
 	}
 
 	log.Printf("Created updatedServer with DefaultPath: %s", updatedServer.DefaultPath)
@@ -433,11 +727,20 @@ func (a *App) ServerUpdate(oldServerID, newServerID, serverName, serverIP, serve
 	err := a.jsonService.UpdateServerWithNewID(oldServerID, updatedServer, authorization, clientJson)
 	if err != nil {
 		log.Printf("Failed to update server: %v", err)
+		if result, ok := vaultErrorResponse(err); ok {
+			return result
+		}
+		if result, ok := conflictResponse(err); ok {
+			return result
+		}
 		response := ApiResponse{Code: 500, Msg: err.Error()}
 		result, _ := json.Marshal(response)
 		return string(result)
 	}
 
+	// 服务器配置已变化，关闭旧的池化连接
+	a.sshService.EvictID(oldServerID)
+
 	response := ApiResponse{Code: 200, Msg: "Server updated successfully"}
 	result, _ := json.Marshal(response)
 	return string(result)
@@ -445,6 +748,15 @@ func (a *App) ServerUpdate(oldServerID, newServerID, serverName, serverIP, serve
 
 // ServerDelete 删除服务器
 func (a *App) ServerDelete(serverID, authorization, clientJson string) string {
+	return a.audited("ServerDelete", authorization, map[string]interface{}{
+		"server_id": serverID,
+	}, func() string {
+		return a.serverDelete(serverID, authorization, clientJson)
+	})
+}
+
+// serverDelete ServerDelete 的实现
+func (a *App) serverDelete(serverID, authorization, clientJson string) string {
 	log.Printf("ServerDelete called with serverID: %s, authorization: %s", serverID, authorization)
 
 	// 检查授权
@@ -457,16 +769,194 @@ func (a *App) ServerDelete(serverID, authorization, clientJson string) string {
 	err := a.jsonService.DeleteServer(serverID, authorization, clientJson)
 	if err != nil {
 		log.Printf("Failed to delete server: %v", err)
+		if result, ok := conflictResponse(err); ok {
+			return result
+		}
 		response := ApiResponse{Code: 500, Msg: "Failed to delete server"}
 		result, _ := json.Marshal(response)
 		return string(result)
 	}
 
+	a.sshService.EvictID(serverID)
+
 	response := ApiResponse{Code: 200, Msg: "Server deleted successfully"}
 	result, _ := json.Marshal(response)
 	return string(result)
 }
 
+// InventoryHistoryList 获取服务器库存的版本快照列表
+func (a *App) InventoryHistoryList(authorization, clientJson string) string {
+	log.Printf("InventoryHistoryList called")
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	snapshots, err := a.jsonService.ListSnapshots(authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to list snapshots: %v", err)
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("获取历史版本失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "Success", Data: snapshots}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// InventoryHistoryDiff 比较两个版本的服务器库存，toVersion 为0时与当前数据比较
+func (a *App) InventoryHistoryDiff(fromVersion, toVersion int64, authorization, clientJson string) string {
+	log.Printf("InventoryHistoryDiff called with fromVersion: %d, toVersion: %d", fromVersion, toVersion)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	diff, err := a.jsonService.DiffSnapshots(fromVersion, toVersion, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to diff snapshots: %v", err)
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("比较版本失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "Success", Data: diff}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// InventoryHistoryRestore 将服务器库存恢复到指定版本
+func (a *App) InventoryHistoryRestore(version int64, authorization, clientJson string) string {
+	return a.audited("InventoryHistoryRestore", authorization, map[string]interface{}{
+		"version": version,
+	}, func() string {
+		return a.inventoryHistoryRestore(version, authorization, clientJson)
+	})
+}
+
+// inventoryHistoryRestore InventoryHistoryRestore 的实现
+func (a *App) inventoryHistoryRestore(version int64, authorization, clientJson string) string {
+	log.Printf("InventoryHistoryRestore called with version: %d", version)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	missing, err := a.jsonService.RestoreSnapshot(version, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to restore snapshot: %v", err)
+		if result, ok := conflictResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("恢复版本失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 快照之后被删除的服务器没有保留凭据，需要重新填写
+	response := ApiResponse{Code: 200, Msg: fmt.Sprintf("已恢复到版本 %d", version)}
+	if len(missing) > 0 {
+		response.Msg += fmt.Sprintf("，以下服务器需要重新填写凭据: %s", strings.Join(missing, ", "))
+		response.Data = map[string]interface{}{"missing_credentials": missing}
+	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// VaultStatus 获取凭据保险库状态
+func (a *App) VaultStatus(authorization, clientJson string) string {
+	log.Printf("VaultStatus called")
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	status, err := a.jsonService.VaultStatus(authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get vault status: %v", err)
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("获取保险库状态失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "Success", Data: status}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// VaultUnlock 使用主密码解锁凭据保险库（首次解锁时初始化保险库并迁移明文密码）
+func (a *App) VaultUnlock(passphrase, authorization, clientJson string) string {
+	return a.audited("VaultUnlock", authorization, map[string]interface{}{
+		"passphrase": passphrase,
+	}, func() string {
+		return a.vaultUnlock(passphrase, authorization, clientJson)
+	})
+}
+
+// vaultUnlock VaultUnlock 的实现
+func (a *App) vaultUnlock(passphrase, authorization, clientJson string) string {
+	log.Printf("VaultUnlock called")
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if passphrase == "" {
+		response := ApiResponse{Code: 400, Msg: "主密码不能为空"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	err := a.jsonService.UnlockVault(passphrase, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to unlock vault: %v", err)
+		if result, ok := vaultErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("解锁保险库失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "保险库已解锁"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// VaultLock 锁定凭据保险库，清除内存中的主密码
+func (a *App) VaultLock() string {
+	return a.audited("VaultLock", "", nil, func() string {
+		return a.vaultLock()
+	})
+}
+
+// vaultLock VaultLock 的实现
+func (a *App) vaultLock() string {
+	log.Printf("VaultLock called")
+
+	a.jsonService.Vault().Lock()
+
+	response := ApiResponse{Code: 200, Msg: "保险库已锁定"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
 // TestStoredServerSSH 测试已存储服务器的SSH连接
 func (a *App) TestStoredServerSSH(serverID, authorization, clientJson string) string {
 	log.Printf("TestStoredServerSSH called with serverID: %s", serverID)
@@ -494,7 +984,7 @@ func (a *App) TestStoredServerSSH(serverID, authorization, clientJson string) st
 	}
 
 	// 执行SSH测试
-	testResult := a.performSSHTest(server.ServerIP, server.ServerPort, server.ServerUser, server.ServerPassword)
+	testResult := a.performSSHTest(server)
 
 	// 更新服务器的连接状态
 	err = a.jsonService.UpdateServerConnectionStatus(serverID, testResult, authorization, clientJson)
@@ -506,44 +996,38 @@ func (a *App) TestStoredServerSSH(serverID, authorization, clientJson string) st
 }
 
 // performSSHTest 执行SSH测试的核心逻辑
-func (a *App) performSSHTest(serverIP, serverPort, serverUser, serverPassword string) string {
-	log.Printf("performSSHTest called with serverIP: %s, serverPort: %s, serverUser: %s", serverIP, serverPort, serverUser)
+func (a *App) performSSHTest(server *services.ServerData) string {
+	log.Printf("performSSHTest called with serverIP: %s, serverPort: %s, serverUser: %s, authType: %s",
+		server.ServerIP, server.ServerPort, server.ServerUser, server.AuthType)
 
 	// 检查必要参数
-	if serverIP == "" {
+	if server.ServerIP == "" {
 		response := ApiResponse{Code: 400, Msg: "服务器IP不能为空"}
 		result, _ := json.Marshal(response)
 		return string(result)
 	}
 
-	// 设置默认端口
-	if serverPort == "" {
-		serverPort = "22"
-	}
-
-	// 设置默认用户名
-	if serverUser == "" {
-		serverUser = "root"
-	}
-
-	// 创建SSH配置
-	config := &ssh.ClientConfig{
-		User:            serverUser,
-		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // 注意：生产环境应该验证主机密钥
-		Timeout:         10 * time.Second,
-	}
-
-	// 添加密码认证（如果提供了密码）
-	if serverPassword != "" {
-		config.Auth = append(config.Auth, ssh.Password(serverPassword))
-	}
-
-	// 尝试连接
-	address := fmt.Sprintf("%s:%s", serverIP, serverPort)
-	client, err := ssh.Dial("tcp", address, config)
+	// 尝试连接（凭据在此时从保险库解密）
+	client, fingerprint, err := a.sshService.DialWithFingerprint(server, 10*time.Second)
 	if err != nil {
 		log.Printf("SSH connection failed: %v", err)
+		var mismatch *services.HostKeyMismatchError
+		if errors.As(err, &mismatch) {
+			response := ApiResponse{
+				Code: 460,
+				Msg:  mismatch.Error(),
+				Data: map[string]interface{}{
+					"connected":        false,
+					"host_key_changed": true,
+					"server_id":        mismatch.ServerID,
+					"expected":         mismatch.Expected,
+					"actual":           mismatch.Actual,
+					"test_time":        time.Now().Format("2006-01-02 15:04:05"),
+				},
+			}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
 		response := ApiResponse{
 			Code: 500,
 			Msg:  fmt.Sprintf("SSH连接失败: %v", err),
@@ -586,51 +1070,339 @@ func (a *App) performSSHTest(serverIP, serverPort, serverUser, serverPassword st
 		Code: 200,
 		Msg:  "SSH连接成功",
 		Data: map[string]interface{}{
-			"connected": true,
-			"user":      strings.TrimSpace(string(output)),
-			"test_time": time.Now().Format("2006-01-02 15:04:05"),
+			"connected":            true,
+			"user":                 strings.TrimSpace(string(output)),
+			"host_key_fingerprint": fingerprint,
+			"test_time":            time.Now().Format("2006-01-02 15:04:05"),
 		},
 	}
 	result, _ := json.Marshal(response)
 	return string(result)
 }
 
-// TestSSHConnection 测试SSH连接
-func (a *App) TestSSHConnection(serverIP, serverPort, serverUser, serverPassword string) string {
-	return a.performSSHTest(serverIP, serverPort, serverUser, serverPassword)
-}
-
-// TestUnauthorized 测试 401 响应 (用于测试前端的 401 处理)
-func (a *App) TestUnauthorized() string {
-	response := ApiResponse{Code: 401, Msg: "Test unauthorized response"}
-	result, _ := json.Marshal(response)
-	return string(result)
-}
+// ServerConnectivityCheck 并发检测服务器连通性并保存连接状态（serverIDsJson 为空数组时检测全部服务器）
+func (a *App) ServerConnectivityCheck(serverIDsJson string, parallelism int, authorization, clientJson string) string {
+	log.Printf("ServerConnectivityCheck called with servers: %s, parallelism: %d", serverIDsJson, parallelism)
 
-// CloudflareGetDNSRecords 获取 Cloudflare DNS 记录
-func (a *App) CloudflareGetDNSRecords(apiToken, zoneID, name, recordType string) string {
-	log.Printf("CloudflareGetDNSRecords called with name: %s, type: %s", name, recordType)
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
 
-	config := services.CloudflareConfig{
-		APIToken: apiToken,
-		ZoneID:   zoneID,
+	var serverIDs []string
+	if strings.TrimSpace(serverIDsJson) != "" {
+		if err := json.Unmarshal([]byte(serverIDsJson), &serverIDs); err != nil {
+			response := ApiResponse{Code: 400, Msg: "Invalid server list"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
 	}
 
-	records, err := a.cloudflareService.GetDNSRecords(config, name, recordType)
+	servers, err := a.loadServers(serverIDs, authorization, clientJson)
 	if err != nil {
-		log.Printf("Failed to get DNS records: %v", err)
-		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("获取 DNS 记录失败: %v", err)}
+		log.Printf("Failed to load servers: %v", err)
+		response := ApiResponse{Code: 400, Msg: err.Error()}
 		result, _ := json.Marshal(response)
 		return string(result)
 	}
 
-	response := ApiResponse{Code: 200, Msg: "Success", Data: records}
+	results := a.connectivityService.CheckAll(servers, parallelism)
+	if err := a.jsonService.UpdateConnectivity(results, authorization, clientJson); err != nil {
+		log.Printf("Failed to update server connection statuses: %v", err)
+	}
+
+	connected := 0
+	for _, item := range results {
+		if item.Connected {
+			connected++
+		}
+	}
+	response := ApiResponse{
+		Code: 200,
+		Msg:  fmt.Sprintf("%d 台服务器连接正常，%d 台连接失败", connected, len(results)-connected),
+		Data: results,
+	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ServerConnectivityStatus 最近一次连通性检测结果和定时检测状态
+func (a *App) ServerConnectivityStatus() string {
+	response := ApiResponse{
+		Code: 200,
+		Msg:  "success",
+		Data: map[string]interface{}{
+			"results":  a.connectivityService.Latest(),
+			"schedule": a.connectivityService.Schedule(),
+		},
+	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ServerConnectivityScheduleStart 开始定时检测全部服务器连通性，结果保存到连接状态并通过 connectivity_result/connectivity_changed 事件推送
+func (a *App) ServerConnectivityScheduleStart(intervalSeconds, parallelism int, authorization, clientJson string) string {
+	return a.audited("ServerConnectivityScheduleStart", authorization, map[string]interface{}{
+		"interval_seconds": intervalSeconds,
+		"parallelism":      parallelism,
+	}, func() string {
+		return a.serverConnectivityScheduleStart(intervalSeconds, parallelism, authorization, clientJson)
+	})
+}
+
+// serverConnectivityScheduleStart ServerConnectivityScheduleStart 的实现
+func (a *App) serverConnectivityScheduleStart(intervalSeconds, parallelism int, authorization, clientJson string) string {
+	log.Printf("ServerConnectivityScheduleStart called with interval: %ds, parallelism: %d", intervalSeconds, parallelism)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	schedule := a.connectivityService.StartSchedule(intervalSeconds, parallelism,
+		func() ([]services.ServerData, error) {
+			return a.loadServers(nil, authorization, clientJson)
+		},
+		func(results []services.ConnectivityResult) error {
+			return a.jsonService.UpdateConnectivity(results, authorization, clientJson)
+		})
+	response := ApiResponse{Code: 200, Msg: "定时检测已启动", Data: schedule}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ServerConnectivityScheduleStop 停止定时检测
+func (a *App) ServerConnectivityScheduleStop() string {
+	return a.audited("ServerConnectivityScheduleStop", "", nil, func() string {
+		return a.serverConnectivityScheduleStop()
+	})
+}
+
+// serverConnectivityScheduleStop ServerConnectivityScheduleStop 的实现
+func (a *App) serverConnectivityScheduleStop() string {
+	log.Printf("ServerConnectivityScheduleStop called")
+
+	a.connectivityService.StopSchedule()
+	response := ApiResponse{Code: 200, Msg: "定时检测已停止", Data: a.connectivityService.Schedule()}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ServerAcceptHostKey 确认接受服务器变化后的主机密钥（fingerprint 为用户确认过的新指纹）
+func (a *App) ServerAcceptHostKey(serverID, fingerprint, authorization, clientJson string) string {
+	return a.audited("ServerAcceptHostKey", authorization, map[string]interface{}{
+		"server_id":   serverID,
+		"fingerprint": fingerprint,
+	}, func() string {
+		return a.serverAcceptHostKey(serverID, fingerprint, authorization, clientJson)
+	})
+}
+
+// serverAcceptHostKey ServerAcceptHostKey 的实现
+func (a *App) serverAcceptHostKey(serverID, fingerprint, authorization, clientJson string) string {
+	log.Printf("ServerAcceptHostKey called with serverID: %s, fingerprint: %s", serverID, fingerprint)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if fingerprint == "" {
+		response := ApiResponse{Code: 400, Msg: "主机密钥指纹不能为空"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 重新获取服务器当前的主机密钥，确保接受的就是用户确认过的指纹
+	current, err := a.sshService.FetchHostKey(server, 10*time.Second)
+	if err != nil {
+		log.Printf("Failed to fetch host key: %v", err)
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if current != fingerprint {
+		response := ApiResponse{
+			Code: 460,
+			Msg:  "服务器当前的主机密钥与确认的指纹不一致，请重新测试连接",
+			Data: map[string]interface{}{
+				"server_id": serverID,
+				"expected":  fingerprint,
+				"actual":    current,
+			},
+		}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	err = a.jsonService.AcceptHostKey(serverID, fingerprint, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to accept host key: %v", err)
+		if result, ok := conflictResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	a.sshService.EvictID(serverID)
+
+	response := ApiResponse{
+		Code: 200,
+		Msg:  "已接受新的主机密钥",
+		Data: map[string]interface{}{
+			"server_id":            serverID,
+			"host_key_fingerprint": fingerprint,
+		},
+	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ServerJumpUpdate 设置服务器的跳板机：jumpServerID 引用已保存的服务器，或 jumpHostJson 配置临时主机；两者均为空表示直连
+func (a *App) ServerJumpUpdate(serverID, jumpServerID, jumpHostJson, authorization, clientJson string) string {
+	return a.audited("ServerJumpUpdate", authorization, map[string]interface{}{
+		"server_id":      serverID,
+		"jump_server_id": jumpServerID,
+		"jump_host_json": jumpHostJson,
+	}, func() string {
+		return a.serverJumpUpdate(serverID, jumpServerID, jumpHostJson, authorization, clientJson)
+	})
+}
+
+// serverJumpUpdate ServerJumpUpdate 的实现
+func (a *App) serverJumpUpdate(serverID, jumpServerID, jumpHostJson, authorization, clientJson string) string {
+	log.Printf("ServerJumpUpdate called with serverID: %s, jumpServerID: %s", serverID, jumpServerID)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var jumpHost *services.JumpHost
+	if strings.TrimSpace(jumpHostJson) != "" && strings.TrimSpace(jumpHostJson) != "null" {
+		jumpHost = &services.JumpHost{}
+		if err := json.Unmarshal([]byte(jumpHostJson), jumpHost); err != nil {
+			log.Printf("Failed to unmarshal jump host: %v", err)
+			response := ApiResponse{Code: 400, Msg: "Invalid jump host data"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+	}
+
+	err := a.jsonService.SetServerJump(serverID, strings.TrimSpace(jumpServerID), jumpHost, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to update jump host: %v", err)
+		if result, ok := conflictResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 连接方式已变化，关闭池中的旧连接
+	a.sshService.EvictID(serverID)
+
+	response := ApiResponse{Code: 200, Msg: "跳板机配置已更新"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// TestSSHConnection 测试SSH连接
+func (a *App) TestSSHConnection(serverIP, serverPort, serverUser, serverPassword string) string {
+	return a.performSSHTest(&services.ServerData{
+		ServerIP:       serverIP,
+		ServerPort:     serverPort,
+		ServerUser:     serverUser,
+		ServerPassword: serverPassword,
+		AuthType:       services.AuthTypePassword,
+	})
+}
+
+// TestSSHConnectionWithData 使用前端传入的服务器数据测试SSH连接（支持私钥、ssh-agent等认证方式）
+func (a *App) TestSSHConnectionWithData(serverDataJson string) string {
+	var server services.ServerData
+	if err := json.Unmarshal([]byte(serverDataJson), &server); err != nil {
+		log.Printf("Failed to unmarshal server data: %v", err)
+		response := ApiResponse{Code: 400, Msg: "服务器数据格式错误"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	return a.performSSHTest(&server)
+}
+
+// TestUnauthorized 测试 401 响应 (用于测试前端的 401 处理)
+func (a *App) TestUnauthorized() string {
+	response := ApiResponse{Code: 401, Msg: "Test unauthorized response"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// CloudflareGetDNSRecords 获取 Cloudflare DNS 记录
+func (a *App) CloudflareGetDNSRecords(apiToken, zoneID, name, recordType string) string {
+	log.Printf("CloudflareGetDNSRecords called with name: %s, type: %s", name, recordType)
+
+	config := services.CloudflareConfig{
+		APIToken: apiToken,
+		ZoneID:   zoneID,
+	}
+
+	records, err := a.cloudflareService.GetDNSRecords(config, name, recordType)
+	if err != nil {
+		log.Printf("Failed to get DNS records: %v", err)
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("获取 DNS 记录失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "Success", Data: records}
 	result, _ := json.Marshal(response)
 	return string(result)
 }
 
 // CloudflareConfigureDNSRecord 配置 Cloudflare DNS 记录
 func (a *App) CloudflareConfigureDNSRecord(apiToken, zoneID, name, recordType, content string, proxied bool) string {
+	return a.audited("CloudflareConfigureDNSRecord", "", map[string]interface{}{
+		"api_token":   apiToken,
+		"zone_id":     zoneID,
+		"name":        name,
+		"record_type": recordType,
+		"content":     content,
+		"proxied":     proxied,
+	}, func() string {
+		return a.cloudflareConfigureDNSRecord(apiToken, zoneID, name, recordType, content, proxied)
+	})
+}
+
+// cloudflareConfigureDNSRecord CloudflareConfigureDNSRecord 的实现
+func (a *App) cloudflareConfigureDNSRecord(apiToken, zoneID, name, recordType, content string, proxied bool) string {
 	log.Printf("CloudflareConfigureDNSRecord called with name: %s, type: %s, content: %s, proxied: %t",
 		name, recordType, content, proxied)
 
@@ -667,6 +1439,17 @@ func (a *App) CloudflareConfigureDNSRecord(apiToken, zoneID, name, recordType, c
 
 // CloudflareDeleteDNSRecord 删除 Cloudflare DNS 记录
 func (a *App) CloudflareDeleteDNSRecord(apiToken, zoneID, recordID string) string {
+	return a.audited("CloudflareDeleteDNSRecord", "", map[string]interface{}{
+		"api_token": apiToken,
+		"zone_id":   zoneID,
+		"record_id": recordID,
+	}, func() string {
+		return a.cloudflareDeleteDNSRecord(apiToken, zoneID, recordID)
+	})
+}
+
+// cloudflareDeleteDNSRecord CloudflareDeleteDNSRecord 的实现
+func (a *App) cloudflareDeleteDNSRecord(apiToken, zoneID, recordID string) string {
 	log.Printf("CloudflareDeleteDNSRecord called with recordID: %s", recordID)
 
 	config := services.CloudflareConfig{
@@ -689,6 +1472,17 @@ func (a *App) CloudflareDeleteDNSRecord(apiToken, zoneID, recordID string) strin
 
 // CloudflareBatchConfigureDNS 批量配置 DNS 记录
 func (a *App) CloudflareBatchConfigureDNS(apiToken, zoneID, recordsJson string) string {
+	return a.audited("CloudflareBatchConfigureDNS", "", map[string]interface{}{
+		"api_token":    apiToken,
+		"zone_id":      zoneID,
+		"records_json": recordsJson,
+	}, func() string {
+		return a.cloudflareBatchConfigureDNS(apiToken, zoneID, recordsJson)
+	})
+}
+
+// cloudflareBatchConfigureDNS CloudflareBatchConfigureDNS 的实现
+func (a *App) cloudflareBatchConfigureDNS(apiToken, zoneID, recordsJson string) string {
 	log.Printf("CloudflareBatchConfigureDNS called with records: %s", recordsJson)
 
 	config := services.CloudflareConfig{
@@ -731,6 +1525,17 @@ func (a *App) CloudflareBatchConfigureDNS(apiToken, zoneID, recordsJson string)
 
 // ProjectPortUpdate 更新项目端口信息
 func (a *App) ProjectPortUpdate(projectID, apiPort, frontPort, authorization, clientJson string) string {
+	return a.audited("ProjectPortUpdate", authorization, map[string]interface{}{
+		"project_id": projectID,
+		"api_port":   apiPort,
+		"front_port": frontPort,
+	}, func() string {
+		return a.projectPortUpdate(projectID, apiPort, frontPort, authorization, clientJson)
+	})
+}
+
+// projectPortUpdate ProjectPortUpdate 的实现
+func (a *App) projectPortUpdate(projectID, apiPort, frontPort, authorization, clientJson string) string {
 	log.Printf("ProjectPortUpdate called with projectID: %s, apiPort: %s, frontPort: %s, authorization: %s",
 		projectID, apiPort, frontPort, authorization)
 
@@ -779,6 +1584,12 @@ func (a *App) ProjectPortUpdate(projectID, apiPort, frontPort, authorization, cl
 				err := a.jsonService.AddOrUpdateProject(server.ServerID, *project, authorization, clientJson)
 				if err != nil {
 					log.Printf("Failed to update project: %v", err)
+					if result, ok := conflictResponse(err); ok {
+						return result
+					}
+					if result, ok := portConflictResponse(err); ok {
+						return result
+					}
 					response := ApiResponse{Code: 500, Msg: err.Error()}
 					result, _ := json.Marshal(response)
 					return string(result)
@@ -796,8 +1607,108 @@ func (a *App) ProjectPortUpdate(projectID, apiPort, frontPort, authorization, cl
 	return string(result)
 }
 
+// ProjectPortCheck 检查项目端口冲突并建议下一组空闲端口
+// projectInfo 为空时只返回建议端口；probe 为 true 时通过SSH检查远程主机已监听的端口
+func (a *App) ProjectPortCheck(serverID, projectInfo string, probe bool, authorization, clientJson string) string {
+	log.Printf("ProjectPortCheck called with serverID: %s, probe: %v", serverID, probe)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var project services.ProjectData
+	if strings.TrimSpace(projectInfo) != "" {
+		if err := json.Unmarshal([]byte(projectInfo), &project); err != nil {
+			log.Printf("Failed to unmarshal project info: %v", err)
+			response := ApiResponse{Code: 400, Msg: "Invalid project data"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+	}
+
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var listening map[int]bool
+	if probe {
+		listening, err = a.portService.ListeningPorts(server)
+		if err != nil {
+			log.Printf("Failed to probe listening ports: %v", err)
+			if result, ok := hostKeyErrorResponse(err); ok {
+				return result
+			}
+			response := ApiResponse{Code: 500, Msg: err.Error()}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+	}
+
+	conflicts := []services.PortConflict{}
+	if project.ProjectID != "" || project.APIPort != "" || project.FrontPort != "" {
+		var previous *services.ProjectData
+		for i := range server.ProjectList {
+			if server.ProjectList[i].ProjectID == project.ProjectID {
+				previous = &server.ProjectList[i]
+			}
+		}
+		err := services.CheckChangedProjectPorts(server, previous, project, listening)
+		var conflict *services.PortConflictError
+		if errors.As(err, &conflict) {
+			conflicts = conflict.Conflicts
+		} else if err != nil {
+			response := ApiResponse{Code: 400, Msg: err.Error()}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+	}
+
+	suggestion, err := services.SuggestProjectPorts(server, project.ProjectID, listening)
+	if err != nil {
+		log.Printf("Failed to suggest ports: %v", err)
+	}
+
+	data := map[string]interface{}{
+		"conflicts":  conflicts,
+		"suggestion": suggestion,
+	}
+	if listening != nil {
+		data["listening_ports"] = services.SortedPorts(listening)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "success", Data: data}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
 // CloudflarePagesAddDomain 为 Cloudflare Pages 项目添加自定义域名
 func (a *App) CloudflarePagesAddDomain(apiToken, zoneID, projectName, domain string) string {
+	return a.audited("CloudflarePagesAddDomain", "", map[string]interface{}{
+		"api_token":    apiToken,
+		"zone_id":      zoneID,
+		"project_name": projectName,
+		"domain":       domain,
+	}, func() string {
+		return a.cloudflarePagesAddDomain(apiToken, zoneID, projectName, domain)
+	})
+}
+
+// cloudflarePagesAddDomain CloudflarePagesAddDomain 的实现
+func (a *App) cloudflarePagesAddDomain(apiToken, zoneID, projectName, domain string) string {
 	log.Printf("CloudflarePagesAddDomain called with projectName: %s, domain: %s", projectName, domain)
 
 	config := services.CloudflareConfig{
@@ -910,6 +1821,18 @@ func (a *App) CloudflarePagesGetDomains(apiToken, zoneID, projectName string) st
 
 // CloudflarePagesDeleteDomain 删除 Cloudflare Pages 项目的自定义域名
 func (a *App) CloudflarePagesDeleteDomain(apiToken, zoneID, projectName, domain string) string {
+	return a.audited("CloudflarePagesDeleteDomain", "", map[string]interface{}{
+		"api_token":    apiToken,
+		"zone_id":      zoneID,
+		"project_name": projectName,
+		"domain":       domain,
+	}, func() string {
+		return a.cloudflarePagesDeleteDomain(apiToken, zoneID, projectName, domain)
+	})
+}
+
+// cloudflarePagesDeleteDomain CloudflarePagesDeleteDomain 的实现
+func (a *App) cloudflarePagesDeleteDomain(apiToken, zoneID, projectName, domain string) string {
 	log.Printf("CloudflarePagesDeleteDomain called with projectName: %s, domain: %s", projectName, domain)
 
 	config := services.CloudflareConfig{
@@ -945,75 +1868,74 @@ func (a *App) CloudflarePagesDeleteDomain(apiToken, zoneID, projectName, domain
 	return string(result)
 }
 
-// GenerateProjectConfig 生成项目配置文件并上传到服务器（从数据库获取最新数据）
-func (a *App) GenerateProjectConfig(serverID, authorization, clientJson string) string {
-	log.Printf("GenerateProjectConfig called with serverID: %s", serverID)
-
-	// 检查授权
-	if authorization == "" || strings.TrimSpace(authorization) == "" {
-		response := ApiResponse{Code: 401, Msg: "Authorization required"}
-		result, _ := json.Marshal(response)
-		return string(result)
+// buildServerProjectConfig 生成服务器下所有项目的 project_config.json 内容
+func buildServerProjectConfig(server *services.ServerData) (map[string]map[string]string, string, error) {
+	projectConfig := make(map[string]map[string]string)
+	for _, project := range server.ProjectList {
+		apiDomain := extractDomainFromURL(project.ProjectAPIURL)
+		projectConfig[project.ProjectID] = map[string]string{
+			"api_port":   getPortOrDefault(project.APIPort, "9000"),
+			"web_port":   getPortOrDefault(project.FrontPort, "3000"),
+			"api_domain": apiDomain,
+		}
 	}
 
-	// 获取服务器信息
-	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	configJSON, err := json.MarshalIndent(projectConfig, "", "  ")
 	if err != nil {
-		log.Printf("Failed to get server info: %v", err)
-		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
-		result, _ := json.Marshal(response)
-		return string(result)
-	}
-
-	if server == nil {
-		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
-		result, _ := json.Marshal(response)
-		return string(result)
+		return nil, "", err
 	}
+	return projectConfig, string(configJSON), nil
+}
 
-	// 生成项目配置 - 使用当前项目配置逻辑
+// generateCurrentProjectConfigJSON 生成当前项目的配置JSON
+func (a *App) generateCurrentProjectConfigJSON(server *services.ServerData, projectID string) (map[string]map[string]string, string, error) {
 	projectConfig := make(map[string]map[string]string)
+
+	log.Printf("Generating config for current project: %s", projectID)
+
+	// 查找当前项目
+	var currentProject *services.ProjectData
 	for _, project := range server.ProjectList {
-		apiDomain := extractDomainFromURL(project.ProjectAPIURL)
-		projectConfig[project.ProjectID] = map[string]string{
-			"api_port":   getPortOrDefault(project.APIPort, "9000"),
-			"web_port":   getPortOrDefault(project.FrontPort, "3000"),
-			"api_domain": apiDomain,
+		if project.ProjectID == projectID {
+			currentProject = &project
+			break
 		}
 	}
 
-	configJSON, err := json.MarshalIndent(projectConfig, "", "  ")
-	if err != nil {
-		log.Printf("Failed to generate project config: %v", err)
-		response := ApiResponse{Code: 500, Msg: "生成配置文件失败"}
-		result, _ := json.Marshal(response)
-		return string(result)
+	if currentProject == nil {
+		return nil, "", fmt.Errorf("project %s not found in server %s", projectID, server.ServerID)
+	}
+
+	// 提取API域名
+	apiDomain := extractDomainFromURL(currentProject.ProjectAPIURL)
+
+	log.Printf("Processing current project %s: API=%s, APIPort=%s, FrontPort=%s",
+		currentProject.ProjectID, apiDomain, currentProject.APIPort, currentProject.FrontPort)
+
+	// 只包含您指定的三个字段
+	projectConfig[currentProject.ProjectID] = map[string]string{
+		"api_port":   getPortOrDefault(currentProject.APIPort, "9000"),
+		"web_port":   getPortOrDefault(currentProject.FrontPort, "3000"),
+		"api_domain": apiDomain,
 	}
 
-	// 处理 release.zip 并上传配置文件
-	err = a.processReleaseAndUploadConfig(server, "project_config.json", string(configJSON))
+	// 转换为JSON
+	configJSON, err := json.MarshalIndent(projectConfig, "", "  ")
 	if err != nil {
-		log.Printf("Failed to process release and upload config: %v", err)
-		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("处理发布包和上传配置文件失败: %v", err)}
-		result, _ := json.Marshal(response)
-		return string(result)
+		return nil, "", fmt.Errorf("failed to marshal project config: %v", err)
 	}
 
-	response := ApiResponse{
-		Code: 200,
-		Msg:  "项目配置文件生成并上传成功",
-		Data: map[string]interface{}{
-			"config": projectConfig,
-			"path":   fmt.Sprintf("%s/project_config.json", server.DefaultPath),
-		},
+	log.Printf("Generated current project config JSON: %s", string(configJSON))
+	log.Printf("Config contains %d projects", len(projectConfig))
+	for id := range projectConfig {
+		log.Printf("Config project ID: %s", id)
 	}
-	result, _ := json.Marshal(response)
-	return string(result)
+	return projectConfig, string(configJSON), nil
 }
 
-// UploadProjectConfig 直接上传前端生成的项目配置JSON到服务器
-func (a *App) UploadProjectConfig(serverDataJson, projectConfigJson, authorization string) string {
-	log.Printf("UploadProjectConfig called")
+// ProjectHealthCheck 按项目配置立即执行一次健康检查
+func (a *App) ProjectHealthCheck(serverID, projectID, authorization, clientJson string) string {
+	log.Printf("ProjectHealthCheck called with serverID: %s, projectID: %s", serverID, projectID)
 
 	// 检查授权
 	if authorization == "" || strings.TrimSpace(authorization) == "" {
@@ -1022,194 +1944,223 @@ func (a *App) UploadProjectConfig(serverDataJson, projectConfigJson, authorizati
 		return string(result)
 	}
 
-	// 解析前端传入的服务器数据（只需要服务器连接信息）
-	var server services.ServerData
-	if err := json.Unmarshal([]byte(serverDataJson), &server); err != nil {
-		log.Printf("Failed to unmarshal server data: %v", err)
-		response := ApiResponse{Code: 400, Msg: "服务器数据格式错误"}
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
 		result, _ := json.Marshal(response)
 		return string(result)
 	}
 
-	log.Printf("Uploading frontend-generated config JSON: %s", projectConfigJson)
-
-	// 直接使用前端传入的JSON配置，处理 release.zip 并上传配置文件
-	err := a.processReleaseAndUploadConfig(&server, "project_config.json", projectConfigJson)
-	if err != nil {
-		log.Printf("Failed to process release and upload config: %v", err)
-		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("处理发布包和上传配置文件失败: %v", err)}
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
 		result, _ := json.Marshal(response)
 		return string(result)
 	}
 
-	// 解析配置JSON以返回给前端预览
-	var configMap map[string]interface{}
-	json.Unmarshal([]byte(projectConfigJson), &configMap)
+	for _, project := range server.ProjectList {
+		if project.ProjectID != projectID {
+			continue
+		}
+		if !project.HealthCheck.Enabled() {
+			response := ApiResponse{Code: 400, Msg: "项目未配置健康检查"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
 
-	response := ApiResponse{
-		Code: 200,
-		Msg:  "项目配置文件上传成功",
-		Data: map[string]interface{}{
-			"config":      configMap,
-			"path":        fmt.Sprintf("%s/project_config.json", server.DefaultPath),
-			"data_source": "frontend",
-		},
+		health := a.healthService.Check(server, project)
+		response := ApiResponse{Code: 200, Msg: "success", Data: health}
+		result, _ := json.Marshal(response)
+		return string(result)
 	}
+
+	response := ApiResponse{Code: 404, Msg: "项目不存在"}
 	result, _ := json.Marshal(response)
 	return string(result)
 }
 
-// GenerateProjectConfigForSingleProject 上传前端生成的项目配置（兼容绑定文件）
-// 注意：由于绑定文件的限制，参数名可能显示为serverID, projectID等，但实际用途如下：
-// 第1个参数：服务器数据JSON
-// 第2个参数：项目配置JSON
-// 第3个参数：未使用
-// 第4个参数：授权token
-func (a *App) GenerateProjectConfigForSingleProject(serverDataJson, projectConfigJson, unused, authorization string) string {
-	log.Printf("GenerateProjectConfigForSingleProject called - uploading frontend config")
-	log.Printf("Received parameters:")
-	log.Printf("  serverDataJson length: %d", len(serverDataJson))
-	log.Printf("  serverDataJson first 100 chars: %s", func() string {
-		if len(serverDataJson) > 100 {
-			return serverDataJson[:100] + "..."
-		}
-		return serverDataJson
-	}())
-	log.Printf("  projectConfigJson length: %d", len(projectConfigJson))
-	log.Printf("  projectConfigJson: %s", projectConfigJson)
-	log.Printf("  unused: %s", unused)
-	log.Printf("  authorization length: %d", len(authorization))
-
+// serverProject 按ID查找服务器和项目，失败时返回错误响应
+func (a *App) serverProject(serverID, projectID, authorization, clientJson string) (*services.ServerData, *services.ProjectData, string) {
 	// 检查授权
 	if authorization == "" || strings.TrimSpace(authorization) == "" {
-		log.Printf("Authorization is empty or whitespace")
 		response := ApiResponse{Code: 401, Msg: "Authorization required"}
 		result, _ := json.Marshal(response)
-		return string(result)
+		return nil, nil, string(result)
 	}
 
-	log.Printf("Attempting to parse server data JSON...")
-	log.Printf("Server data JSON (first 200 chars): %s", func() string {
-		if len(serverDataJson) > 200 {
-			return serverDataJson[:200] + "..."
-		}
-		return serverDataJson
-	}())
-
-	// 先尝试解析为通用map，然后提取需要的字段
-	var rawData map[string]interface{}
-	if err := json.Unmarshal([]byte(serverDataJson), &rawData); err != nil {
-		log.Printf("Failed to unmarshal as map: %v", err)
-		log.Printf("Raw server data length: %d", len(serverDataJson))
-		log.Printf("Raw server data (first 500 chars): %s", func() string {
-			if len(serverDataJson) > 500 {
-				return serverDataJson[:500] + "..."
-			}
-			return serverDataJson
-		}())
-		response := ApiResponse{Code: 400, Msg: fmt.Sprintf("服务器数据格式错误: %v", err)}
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
 		result, _ := json.Marshal(response)
-		return string(result)
+		return nil, nil, string(result)
 	}
 
-	// 手动构建ServerData结构
-	server := services.ServerData{
-		ServerID:       getStringFromMap(rawData, "server_id"),
-		ServerName:     getStringFromMap(rawData, "server_name"),
-		ServerIP:       getStringFromMap(rawData, "server_ip"),
-		ServerPort:     getStringFromMap(rawData, "server_port"),
-		ServerUser:     getStringFromMap(rawData, "server_user"),
-		ServerPassword: getStringFromMap(rawData, "server_password"),
-		DefaultPath:    getStringFromMap(rawData, "default_path"),
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return nil, nil, string(result)
 	}
 
-	// 如果关键字段为空，设置默认值
-	if server.DefaultPath == "" {
-		server.DefaultPath = "/adplace"
-	}
-	if server.ServerPort == "" {
-		server.ServerPort = "22"
+	for i := range server.ProjectList {
+		if server.ProjectList[i].ProjectID == projectID {
+			return server, &server.ProjectList[i], ""
+		}
 	}
 
-	log.Printf("Successfully parsed server data: ServerID=%s, IP=%s, DefaultPath=%s",
-		server.ServerID, server.ServerIP, server.DefaultPath)
+	response := ApiResponse{Code: 404, Msg: "项目不存在"}
+	result, _ := json.Marshal(response)
+	return nil, nil, string(result)
+}
 
-	log.Printf("Uploading frontend-generated config to server: %s", server.ServerID)
+// ProjectStatus 查询项目在服务器上的运行状态（容器、进程和端口监听）
+func (a *App) ProjectStatus(serverID, projectID, authorization, clientJson string) string {
+	log.Printf("ProjectStatus called with serverID: %s, projectID: %s", serverID, projectID)
+
+	server, project, errResult := a.serverProject(serverID, projectID, authorization, clientJson)
+	if errResult != "" {
+		return errResult
+	}
 
-	// 直接使用前端传入的JSON配置，处理 release.zip 并上传配置文件
-	err := a.processReleaseAndUploadConfig(&server, "project_config.json", projectConfigJson)
+	status, err := a.projectLogService.Status(server, *project)
 	if err != nil {
-		log.Printf("Failed to process release and upload config: %v", err)
-		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("处理发布包和上传配置文件失败: %v", err)}
+		log.Printf("Failed to query project status: %v", err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: err.Error()}
 		result, _ := json.Marshal(response)
 		return string(result)
 	}
 
-	// 解析配置JSON以返回给前端预览
-	var configMap map[string]interface{}
-	json.Unmarshal([]byte(projectConfigJson), &configMap)
+	response := ApiResponse{Code: 200, Msg: "success", Data: status}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
 
-	response := ApiResponse{
-		Code: 200,
-		Msg:  "项目配置文件上传成功（前端生成）",
-		Data: map[string]interface{}{
-			"config":      configMap,
-			"path":        fmt.Sprintf("%s/project_config.json", server.DefaultPath),
-			"data_source": "frontend",
-		},
+// ProjectLogTail 读取项目日志的最后 lines 行（lines 为 0 时默认200行）
+func (a *App) ProjectLogTail(serverID, projectID string, lines int, authorization, clientJson string) string {
+	log.Printf("ProjectLogTail called with serverID: %s, projectID: %s, lines: %d", serverID, projectID, lines)
+
+	server, _, errResult := a.serverProject(serverID, projectID, authorization, clientJson)
+	if errResult != "" {
+		return errResult
+	}
+
+	output, err := a.projectLogService.Tail(server, projectID, lines)
+	if err != nil {
+		log.Printf("Failed to tail project logs: %v", err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: err.Error(), Data: output}
+		result, _ := json.Marshal(response)
+		return string(result)
 	}
+
+	response := ApiResponse{Code: 200, Msg: "success", Data: output}
 	result, _ := json.Marshal(response)
 	return string(result)
 }
 
-// generateCurrentProjectConfigJSON 生成当前项目的配置JSON
-func (a *App) generateCurrentProjectConfigJSON(server *services.ServerData, projectID string) (map[string]map[string]string, string, error) {
-	projectConfig := make(map[string]map[string]string)
+// ProjectLogFollow 跟踪项目日志，新内容通过 log_output 事件推送，结束时发送 log_closed 事件
+func (a *App) ProjectLogFollow(serverID, projectID string, lines int, authorization, clientJson string) string {
+	log.Printf("ProjectLogFollow called with serverID: %s, projectID: %s, lines: %d", serverID, projectID, lines)
 
-	log.Printf("Generating config for current project: %s", projectID)
+	server, _, errResult := a.serverProject(serverID, projectID, authorization, clientJson)
+	if errResult != "" {
+		return errResult
+	}
 
-	// 查找当前项目
-	var currentProject *services.ProjectData
-	for _, project := range server.ProjectList {
-		if project.ProjectID == projectID {
-			currentProject = &project
-			break
+	stream, err := a.projectLogService.Follow(server, projectID, lines)
+	if err != nil {
+		log.Printf("Failed to follow project logs: %v", err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
 		}
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
 	}
 
-	if currentProject == nil {
-		return nil, "", fmt.Errorf("project %s not found in server %s", projectID, server.ServerID)
+	response := ApiResponse{Code: 200, Msg: "success", Data: stream}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ProjectLogStop 停止日志跟踪
+func (a *App) ProjectLogStop(streamID string) string {
+	log.Printf("ProjectLogStop called with streamID: %s", streamID)
+
+	if err := a.projectLogService.Stop(streamID); err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
 	}
 
-	// 提取API域名
-	apiDomain := extractDomainFromURL(currentProject.ProjectAPIURL)
+	response := ApiResponse{Code: 200, Msg: "日志跟踪已停止"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
 
-	log.Printf("Processing current project %s: API=%s, APIPort=%s, FrontPort=%s",
-		currentProject.ProjectID, apiDomain, currentProject.APIPort, currentProject.FrontPort)
+// ProjectLogList 列出正在跟踪的日志
+func (a *App) ProjectLogList() string {
+	response := ApiResponse{Code: 200, Msg: "success", Data: a.projectLogService.List()}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
 
-	// 只包含您指定的三个字段
-	projectConfig[currentProject.ProjectID] = map[string]string{
-		"api_port":   getPortOrDefault(currentProject.APIPort, "9000"),
-		"web_port":   getPortOrDefault(currentProject.FrontPort, "3000"),
-		"api_domain": apiDomain,
-	}
+// ServerLogSourceUpdate 设置服务器的日志位置（空字符串恢复默认 {default_path}/{project_id}/logs/*.log）
+func (a *App) ServerLogSourceUpdate(serverID, logSource, authorization, clientJson string) string {
+	return a.audited("ServerLogSourceUpdate", authorization, map[string]interface{}{
+		"server_id":  serverID,
+		"log_source": logSource,
+	}, func() string {
+		return a.serverLogSourceUpdate(serverID, logSource, authorization, clientJson)
+	})
+}
 
-	// 转换为JSON
-	configJSON, err := json.MarshalIndent(projectConfig, "", "  ")
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to marshal project config: %v", err)
+// serverLogSourceUpdate ServerLogSourceUpdate 的实现
+func (a *App) serverLogSourceUpdate(serverID, logSource, authorization, clientJson string) string {
+	log.Printf("ServerLogSourceUpdate called with serverID: %s, logSource: %s", serverID, logSource)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
 	}
 
-	log.Printf("Generated current project config JSON: %s", string(configJSON))
-	log.Printf("Config contains %d projects", len(projectConfig))
-	for id := range projectConfig {
-		log.Printf("Config project ID: %s", id)
+	logSource = strings.TrimSpace(logSource)
+	if err := a.jsonService.SetServerLogSource(serverID, logSource, authorization, clientJson); err != nil {
+		log.Printf("Failed to update log source: %v", err)
+		if result, ok := conflictResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
 	}
-	return projectConfig, string(configJSON), nil
+
+	response := ApiResponse{Code: 200, Msg: "日志位置已更新"}
+	result, _ := json.Marshal(response)
+	return string(result)
 }
 
 // ProjectInit SSH执行项目初始化
 func (a *App) ProjectInit(serverID, projectID, authorization, clientJson string) string {
+	return a.audited("ProjectInit", authorization, map[string]interface{}{
+		"server_id":  serverID,
+		"project_id": projectID,
+	}, func() string {
+		return a.projectInit(serverID, projectID, authorization, clientJson)
+	})
+}
+
+// projectInit ProjectInit 的实现
+func (a *App) projectInit(serverID, projectID, authorization, clientJson string) string {
 	log.Printf("ProjectInit called with serverID: %s, projectID: %s", serverID, projectID)
 
 	// 检查授权
@@ -1234,30 +2185,23 @@ func (a *App) ProjectInit(serverID, projectID, authorization, clientJson string)
 		return string(result)
 	}
 
-	// 执行SSH命令 - 先设置可执行权限，然后执行脚本
-	command := fmt.Sprintf("cd %s && chmod +x codedeploy.sh && ./codedeploy.sh init %s", server.DefaultPath, projectID)
-	output, err := a.executeSSHCommand(server, command)
-	if err != nil {
-		log.Printf("Failed to execute init command: %v", err)
-		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("执行初始化命令失败: %v", err)}
-		result, _ := json.Marshal(response)
-		return string(result)
-	}
-
-	response := ApiResponse{
-		Code: 200,
-		Msg:  "项目初始化成功",
-		Data: map[string]interface{}{
-			"command": command,
-			"output":  output,
-		},
-	}
-	result, _ := json.Marshal(response)
-	return string(result)
+	// 执行SSH命令 - 先设置可执行权限，然后执行脚本（后台执行，立即返回任务ID）
+	command := projectInitCommand(server, projectID)
+	return a.startProjectJob(server, projectID, "init", "初始化", command, nil)
 }
 
 // ProjectUpdate SSH执行项目更新（从数据库获取最新数据）
 func (a *App) ProjectUpdate(serverID, projectID, authorization, clientJson string) string {
+	return a.audited("ProjectUpdate", authorization, map[string]interface{}{
+		"server_id":  serverID,
+		"project_id": projectID,
+	}, func() string {
+		return a.projectUpdate(serverID, projectID, authorization, clientJson)
+	})
+}
+
+// projectUpdate ProjectUpdate 的实现
+func (a *App) projectUpdate(serverID, projectID, authorization, clientJson string) string {
 	log.Printf("ProjectUpdate called with serverID: %s, projectID: %s", serverID, projectID)
 
 	// 检查授权
@@ -1282,30 +2226,24 @@ func (a *App) ProjectUpdate(serverID, projectID, authorization, clientJson strin
 		return string(result)
 	}
 
-	// 执行SSH命令 - 先设置可执行权限，然后执行脚本
-	command := fmt.Sprintf("cd %s && chmod +x codedeploy.sh && ./codedeploy.sh update %s", server.DefaultPath, projectID)
-	output, err := a.executeSSHCommand(server, command)
-	if err != nil {
-		log.Printf("Failed to execute update command: %v", err)
-		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("执行更新命令失败: %v", err)}
-		result, _ := json.Marshal(response)
-		return string(result)
-	}
-
-	response := ApiResponse{
-		Code: 200,
-		Msg:  "项目更新成功",
-		Data: map[string]interface{}{
-			"command": command,
-			"output":  output,
-		},
-	}
-	result, _ := json.Marshal(response)
-	return string(result)
+	// 执行SSH命令 - 先设置可执行权限，然后执行脚本（后台执行，立即返回任务ID）
+	command := projectUpdateCommand(server, projectID)
+	return a.startProjectJob(server, projectID, "update", "更新", command, nil)
 }
 
 // ProjectInitWithData 使用前端传入的服务器数据执行项目初始化
 func (a *App) ProjectInitWithData(serverID, projectID, serverDataJson, authorization string) string {
+	return a.audited("ProjectInitWithData", authorization, map[string]interface{}{
+		"server_id":        serverID,
+		"project_id":       projectID,
+		"server_data_json": serverDataJson,
+	}, func() string {
+		return a.projectInitWithData(serverID, projectID, serverDataJson, authorization)
+	})
+}
+
+// projectInitWithData ProjectInitWithData 的实现
+func (a *App) projectInitWithData(serverID, projectID, serverDataJson, authorization string) string {
 	log.Printf("ProjectInitWithData called with serverID: %s, projectID: %s", serverID, projectID)
 
 	// 检查授权
@@ -1350,32 +2288,27 @@ func (a *App) ProjectInitWithData(serverID, projectID, serverDataJson, authoriza
 
 	log.Printf("Using frontend data to init project: %s (%s)", targetProject.ProjectName, projectID)
 
-	// 执行SSH命令 - 先设置可执行权限，然后执行脚本
-	command := fmt.Sprintf("cd %s && chmod +x codedeploy.sh && ./codedeploy.sh init %s", server.DefaultPath, projectID)
-	output, err := a.executeSSHCommand(&server, command)
-	if err != nil {
-		log.Printf("Failed to execute init command: %v", err)
-		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("执行初始化命令失败: %v", err)}
-		result, _ := json.Marshal(response)
-		return string(result)
-	}
-
-	response := ApiResponse{
-		Code: 200,
-		Msg:  "项目初始化成功（使用前端数据）",
-		Data: map[string]interface{}{
-			"command":     command,
-			"output":      output,
-			"project":     targetProject,
-			"data_source": "frontend",
-		},
-	}
-	result, _ := json.Marshal(response)
-	return string(result)
+	// 执行SSH命令 - 先设置可执行权限，然后执行脚本（后台执行，立即返回任务ID）
+	command := projectInitCommand(&server, projectID)
+	return a.startProjectJob(&server, projectID, "init", "初始化", command, map[string]interface{}{
+		"project":     targetProject,
+		"data_source": "frontend",
+	})
 }
 
 // ProjectUpdateWithData 使用前端传入的服务器数据执行项目更新
 func (a *App) ProjectUpdateWithData(serverID, projectID, serverDataJson, authorization string) string {
+	return a.audited("ProjectUpdateWithData", authorization, map[string]interface{}{
+		"server_id":        serverID,
+		"project_id":       projectID,
+		"server_data_json": serverDataJson,
+	}, func() string {
+		return a.projectUpdateWithData(serverID, projectID, serverDataJson, authorization)
+	})
+}
+
+// projectUpdateWithData ProjectUpdateWithData 的实现
+func (a *App) projectUpdateWithData(serverID, projectID, serverDataJson, authorization string) string {
 	log.Printf("ProjectUpdateWithData called with serverID: %s, projectID: %s", serverID, projectID)
 
 	// 检查授权
@@ -1420,34 +2353,18 @@ func (a *App) ProjectUpdateWithData(serverID, projectID, serverDataJson, authori
 
 	log.Printf("Using frontend data to update project: %s (%s)", targetProject.ProjectName, projectID)
 
-	// 执行SSH命令 - 先设置可执行权限，然后执行脚本
-	command := fmt.Sprintf("cd %s && chmod +x codedeploy.sh && ./codedeploy.sh update %s", server.DefaultPath, projectID)
-	output, err := a.executeSSHCommand(&server, command)
-	if err != nil {
-		log.Printf("Failed to execute update command: %v", err)
-		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("执行更新命令失败: %v", err)}
-		result, _ := json.Marshal(response)
-		return string(result)
-	}
-
-	response := ApiResponse{
-		Code: 200,
-		Msg:  "项目更新成功（使用前端数据）",
-		Data: map[string]interface{}{
-			"command":     command,
-			"output":      output,
-			"project":     targetProject,
-			"data_source": "frontend",
-		},
-	}
-	result, _ := json.Marshal(response)
-	return string(result)
-}
-
-// 辅助函数：从URL中提取域名
-func extractDomainFromURL(urlStr string) string {
-	if urlStr == "" {
-		return ""
+	// 执行SSH命令 - 先设置可执行权限，然后执行脚本（后台执行，立即返回任务ID）
+	command := projectUpdateCommand(&server, projectID)
+	return a.startProjectJob(&server, projectID, "update", "更新", command, map[string]interface{}{
+		"project":     targetProject,
+		"data_source": "frontend",
+	})
+}
+
+// 辅助函数：从URL中提取域名
+func extractDomainFromURL(urlStr string) string {
+	if urlStr == "" {
+		return ""
 	}
 
 	// 如果不包含协议，添加默认协议
@@ -1476,80 +2393,231 @@ func getPortOrDefault(port, defaultPort string) string {
 	return port
 }
 
-// 辅助函数：从map中安全获取字符串值
-func getStringFromMap(data map[string]interface{}, key string) string {
-	if val, ok := data[key]; ok {
-		if str, ok := val.(string); ok {
-			return str
+// 辅助函数：通过SSH上传文件
+func (a *App) uploadFileViaSSH(server *services.ServerData, filename, content string) error {
+	// 通过SFTP写入临时文件、校验后重命名
+	targetPath := path.Join(server.DefaultPath, filename)
+	if _, err := a.transferService.UploadBytes(server, []byte(content), targetPath, services.TransferOptions{}); err != nil {
+		return fmt.Errorf("上传文件失败: %w", err)
+	}
+
+	return nil
+}
+
+// DeployResultEvent 项目初始化/更新任务结束（含健康检查和自动回滚）后推送的结果事件名称
+const DeployResultEvent = "deploy_result"
+
+// deployResult 项目初始化/更新任务的最终结果
+type deployResult struct {
+	JobID    string      `json:"job_id"`
+	Response ApiResponse `json:"response"`
+}
+
+// startProjectJob 登记项目命令任务后立即返回 job_id；命令、健康检查和自动回滚在后台执行，
+// 结束后通过 deploy_result 事件推送与同步执行时相同格式的结果，也可通过 DeployResult 查询
+func (a *App) startProjectJob(server *services.ServerData, projectID, action, label, command string, extra map[string]interface{}) string {
+	jobID := a.jobService.Start(server, projectID, action, command)
+	go func() {
+		release := a.rollbackRelease(server, projectID)
+		job, output, err := a.jobService.Run(jobID)
+		response := a.projectJobResponse(server, projectID, label, command, release, job, output, err, extra)
+		a.jobService.SetResult(jobID, response)
+		if a.ctx != nil {
+			wailsruntime.EventsEmit(a.ctx, DeployResultEvent, deployResult{JobID: jobID, Response: response})
 		}
+	}()
+
+	response := ApiResponse{
+		Code: 200,
+		Msg:  fmt.Sprintf("项目%s任务已开始", label),
+		Data: map[string]interface{}{
+			"command": command,
+			"job_id":  jobID,
+			"status":  services.JobRunning,
+		},
 	}
-	return ""
+	result, _ := json.Marshal(response)
+	return string(result)
 }
 
-// 辅助函数：通过SSH上传文件
-func (a *App) uploadFileViaSSH(server *services.ServerData, filename, content string) error {
-	// 创建SSH配置
-	config := &ssh.ClientConfig{
-		User:            server.ServerUser,
-		Auth:            []ssh.AuthMethod{ssh.Password(server.ServerPassword)},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
+// rollbackRelease 项目配置了自动回滚时返回服务器的当前发布版本（部署失败时从该版本回滚），否则返回空
+func (a *App) rollbackRelease(server *services.ServerData, projectID string) string {
+	for _, project := range server.ProjectList {
+		if project.ProjectID != projectID || !project.HealthCheck.Enabled() || !project.HealthCheck.AutoRollback {
+			continue
+		}
+		releases, err := a.releaseService.List(server)
+		if err != nil {
+			log.Printf("Failed to read current release of %s, auto rollback disabled for this deploy: %v", server.ServerID, err)
+			return ""
+		}
+		for _, release := range releases {
+			if release.Current {
+				return release.Name
+			}
+		}
 	}
+	return ""
+}
 
-	// 连接SSH
-	address := fmt.Sprintf("%s:%s", server.ServerIP, server.ServerPort)
-	client, err := ssh.Dial("tcp", address, config)
+// projectJobResponse 项目命令任务结束后的结果，成功时执行健康检查
+// release 为任务开始时的当前发布版本，健康检查失败时从该版本自动回滚
+func (a *App) projectJobResponse(server *services.ServerData, projectID, label, command, release string, job services.JobStatus, output string, err error, extra map[string]interface{}) ApiResponse {
 	if err != nil {
-		return fmt.Errorf("SSH连接失败: %v", err)
+		log.Printf("Failed to execute %s command: %v", job.Action, err)
+		var mismatch *services.HostKeyMismatchError
+		if errors.As(err, &mismatch) {
+			return ApiResponse{Code: 460, Msg: mismatch.Error(), Data: mismatch}
+		}
+		return ApiResponse{
+			Code: 500,
+			Msg:  fmt.Sprintf("执行%s命令失败: %v", label, err),
+			Data: map[string]interface{}{
+				"job_id":    job.JobID,
+				"status":    job.Status,
+				"exit_code": job.ExitCode,
+				"output":    output,
+			},
+		}
 	}
-	defer client.Close()
 
-	// 创建会话
-	session, err := client.NewSession()
-	if err != nil {
-		return fmt.Errorf("创建SSH会话失败: %v", err)
+	msg := fmt.Sprintf("项目%s成功", label)
+	if extra != nil {
+		msg += "（使用前端数据）"
+	}
+	data := map[string]interface{}{
+		"command":   command,
+		"output":    output,
+		"job_id":    job.JobID,
+		"exit_code": job.ExitCode,
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+	response := ApiResponse{Code: 200, Msg: msg, Data: data}
+	a.applyDeployHealthCheck(server, projectID, release, &response)
+	return response
+}
+
+// runProjectCommand 以任务方式执行项目命令，输出通过 command_output 事件逐行推送到前端
+func (a *App) runProjectCommand(server *services.ServerData, projectID, action, command string) (services.JobStatus, string, error) {
+	jobID := a.jobService.Start(server, projectID, action, command)
+	return a.jobService.Run(jobID)
+}
+
+// runProjectCommandContext 与 runProjectCommand 相同，ctx 结束时取消任务
+func (a *App) runProjectCommandContext(ctx context.Context, server *services.ServerData, projectID, action, command string) (services.JobStatus, string, error) {
+	jobID := a.jobService.Start(server, projectID, action, command)
+	stop := context.AfterFunc(ctx, func() {
+		a.jobService.Cancel(jobID)
+	})
+	defer stop()
+	return a.jobService.Run(jobID)
+}
+
+// deployRollbackResult 健康检查失败后自动回滚的结果
+type deployRollbackResult struct {
+	Release  string                `json:"release,omitempty"`
+	Projects []projectUpdateResult `json:"projects,omitempty"`
+	Success  bool                  `json:"success"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// verifyDeployment 部署成功后执行项目健康检查（未配置时返回 nil）
+// release 为本次部署激活的发布版本（单独更新项目时为任务开始时的当前版本）：检查失败、配置了自动回滚且该版本仍是当前版本时，
+// 回滚到它的上一个版本并重新更新服务器上的全部项目；release 为空时（服务器上没有发布版本）不回滚
+func (a *App) verifyDeployment(server *services.ServerData, projectID, release string) (*services.HealthResult, *deployRollbackResult) {
+	var project *services.ProjectData
+	for i := range server.ProjectList {
+		if server.ProjectList[i].ProjectID == projectID {
+			project = &server.ProjectList[i]
+			break
+		}
+	}
+	if project == nil || !project.HealthCheck.Enabled() {
+		return nil, nil
 	}
-	defer session.Close()
 
-	// 创建目标文件路径
-	targetPath := fmt.Sprintf("%s/%s", server.DefaultPath, filename)
+	health := a.healthService.Check(server, *project)
+	if health.Healthy || !project.HealthCheck.AutoRollback {
+		return &health, nil
+	}
 
-	// 使用cat命令写入文件内容
-	command := fmt.Sprintf("cat > %s", targetPath)
-	session.Stdin = strings.NewReader(content)
+	if release == "" {
+		log.Printf("Project %s on %s is unhealthy, but there is no release to roll back from", projectID, server.ServerID)
+		return &health, &deployRollbackResult{Error: "服务器上没有可回滚的发布版本"}
+	}
 
-	err = session.Run(command)
+	log.Printf("Project %s on %s is unhealthy after deploying release %s, rolling back", projectID, server.ServerID, release)
+	rollback := &deployRollbackResult{}
+	name, err := a.releaseService.RollbackFrom(server, release)
 	if err != nil {
-		return fmt.Errorf("上传文件失败: %v", err)
+		rollback.Error = err.Error()
+		return &health, rollback
 	}
+	rollback.Release = name
 
-	return nil
+	// 切换版本影响服务器上的所有项目，全部重新更新
+	projects, failed := a.rerunProjectUpdates(server)
+	rollback.Projects = projects
+	if failed > 0 {
+		rollback.Error = fmt.Sprintf("已回滚到版本 %s，但有 %d 个项目更新失败", name, failed)
+		return &health, rollback
+	}
+	rollback.Success = true
+	return &health, rollback
 }
 
-// 辅助函数：执行SSH命令
-func (a *App) executeSSHCommand(server *services.ServerData, command string) (string, error) {
-	// 创建SSH配置
-	config := &ssh.ClientConfig{
-		User:            server.ServerUser,
-		Auth:            []ssh.AuthMethod{ssh.Password(server.ServerPassword)},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
+// applyDeployHealthCheck 对部署成功的响应执行健康检查，结果写入 data.health/data.rollback，检查失败时改为 500
+// release 为回滚的起始版本，见 verifyDeployment
+func (a *App) applyDeployHealthCheck(server *services.ServerData, projectID, release string, response *ApiResponse) {
+	health, rollback := a.verifyDeployment(server, projectID, release)
+	if health == nil {
+		return
 	}
 
-	// 连接SSH
-	address := fmt.Sprintf("%s:%s", server.ServerIP, server.ServerPort)
-	client, err := ssh.Dial("tcp", address, config)
-	if err != nil {
-		return "", fmt.Errorf("SSH连接失败: %v", err)
+	data, _ := response.Data.(map[string]interface{})
+	if data == nil {
+		data = map[string]interface{}{}
 	}
-	defer client.Close()
+	data["health"] = health
+	if rollback != nil {
+		data["rollback"] = rollback
+	}
+	response.Data = data
 
-	// 创建会话
-	session, err := client.NewSession()
+	if health.Healthy {
+		return
+	}
+	response.Code = 500
+	response.Msg = fmt.Sprintf("部署完成但健康检查失败: %s", health.Error)
+	if rollback != nil {
+		if rollback.Success {
+			response.Msg += fmt.Sprintf("，已自动回滚到版本 %s", rollback.Release)
+		} else {
+			response.Msg += fmt.Sprintf("，自动回滚失败: %s", rollback.Error)
+		}
+	}
+}
+
+// projectInitCommand 在服务器上初始化项目的命令
+func projectInitCommand(server *services.ServerData, projectID string) string {
+	return fmt.Sprintf("cd %s && chmod +x codedeploy.sh && ./codedeploy.sh init %s", services.ShellQuote(server.DefaultPath), services.ShellQuote(projectID))
+}
+
+// projectUpdateCommand 在服务器上更新项目的命令
+func projectUpdateCommand(server *services.ServerData, projectID string) string {
+	return fmt.Sprintf("cd %s && chmod +x codedeploy.sh && ./codedeploy.sh update %s", services.ShellQuote(server.DefaultPath), services.ShellQuote(projectID))
+}
+
+// 辅助函数：执行SSH命令
+func (a *App) executeSSHCommand(server *services.ServerData, command string) (string, error) {
+	// 从连接池获取会话（凭据在首次连接时从保险库解密）
+	session, release, err := a.sshService.Session(server)
 	if err != nil {
-		return "", fmt.Errorf("创建SSH会话失败: %v", err)
+		return "", fmt.Errorf("SSH连接失败: %w", err)
 	}
-	defer session.Close()
+	defer release()
 
 	// 执行命令
 	output, err := session.CombinedOutput(command)
@@ -1560,33 +2628,24 @@ func (a *App) executeSSHCommand(server *services.ServerData, command string) (st
 	return string(output), nil
 }
 
-// processReleaseAndUploadConfig 处理 release.zip 并上传配置文件
-func (a *App) processReleaseAndUploadConfig(server *services.ServerData, filename, content string) error {
-	// 创建SSH配置
-	config := &ssh.ClientConfig{
-		User:            server.ServerUser,
-		Auth:            []ssh.AuthMethod{ssh.Password(server.ServerPassword)},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-
-	// 连接SSH
-	address := fmt.Sprintf("%s:%s", server.ServerIP, server.ServerPort)
-	client, err := ssh.Dial("tcp", address, config)
+// processReleaseAndUploadConfig 处理 release.zip 并上传配置文件，返回激活的发布版本名（没有 release.zip 时为空）
+func (a *App) processReleaseAndUploadConfig(server *services.ServerData, filename, content string) (string, error) {
+	// 从连接池获取连接（凭据在首次连接时从保险库解密），各步骤复用同一连接
+	client, release, err := a.sshService.Client(server)
 	if err != nil {
-		return fmt.Errorf("SSH连接失败: %v", err)
+		return "", fmt.Errorf("SSH连接失败: %w", err)
 	}
-	defer client.Close()
+	defer release()
 
 	// 1. 检查 release.zip 是否存在
 	log.Printf("Checking for release.zip in %s", server.DefaultPath)
 	checkSession, err := client.NewSession()
 	if err != nil {
-		return fmt.Errorf("创建检查会话失败: %v", err)
+		return "", fmt.Errorf("创建检查会话失败: %v", err)
 	}
 
-	releaseZipPath := fmt.Sprintf("%s/release.zip", server.DefaultPath)
-	checkCommand := fmt.Sprintf("test -f %s && echo 'exists' || echo 'not_exists'", releaseZipPath)
+	releaseZipPath := path.Join(server.DefaultPath, "release.zip")
+	checkCommand := fmt.Sprintf("test -f %s && echo 'exists' || echo 'not_exists'", services.ShellQuote(releaseZipPath))
 	checkOutput, err := checkSession.CombinedOutput(checkCommand)
 	checkSession.Close()
 
@@ -1594,71 +2653,1532 @@ func (a *App) processReleaseAndUploadConfig(server *services.ServerData, filenam
 		log.Printf("检查 release.zip 失败: %v", err)
 	}
 
-	// 2. 如果 release.zip 存在，进行解压和删除操作
+	// 2. 如果 release.zip 存在，解压为新的发布版本并切换 current（保留旧版本用于回滚）
+	name := ""
 	if strings.TrimSpace(string(checkOutput)) == "exists" {
 		log.Printf("Found release.zip, processing...")
 
-		// 解压 release.zip（强制覆盖）
-		unzipSession, err := client.NewSession()
+		name, err = a.releaseService.Activate(server)
 		if err != nil {
-			return fmt.Errorf("创建解压会话失败: %v", err)
+			log.Printf("激活发布版本失败: %v", err)
+			return "", err
 		}
 
-		unzipCommand := fmt.Sprintf("cd %s && unzip -o release.zip", server.DefaultPath)
-		log.Printf("Executing unzip command: %s", unzipCommand)
-		unzipOutput, err := unzipSession.CombinedOutput(unzipCommand)
-		unzipSession.Close()
+		log.Printf("Release %s activated successfully", name)
+	} else {
+		log.Printf("No release.zip found, skipping extraction")
+	}
+
+	// 3. 上传配置文件
+	return name, a.uploadConfigFile(server, filename, content)
+}
+
+// uploadConfigFile 只上传配置文件（SFTP写入临时文件、校验后重命名），不处理 release.zip
+func (a *App) uploadConfigFile(server *services.ServerData, filename, content string) error {
+	log.Printf("Uploading config file: %s", filename)
+	targetPath := path.Join(server.DefaultPath, filename)
+	if _, err := a.transferService.UploadBytes(server, []byte(content), targetPath, services.TransferOptions{}); err != nil {
+		return fmt.Errorf("上传配置文件失败: %w", err)
+	}
+
+	log.Printf("Config file uploaded successfully to: %s", targetPath)
+	return nil
+}
 
+// planProjectConfig 下载服务器上现有的 project_config.json 并与将要上传的配置比较
+// configJSON 为空时按服务器的项目列表生成，返回计划和实际要上传的内容
+func (a *App) planProjectConfig(server *services.ServerData, configJSON string) (*services.ProjectConfigPlan, string, error) {
+	var proposed map[string]map[string]string
+	if strings.TrimSpace(configJSON) == "" {
+		var err error
+		proposed, configJSON, err = buildServerProjectConfig(server)
+		if err != nil {
+			return nil, "", fmt.Errorf("生成配置文件失败: %v", err)
+		}
+	} else {
+		var err error
+		proposed, err = services.ParseProjectConfig([]byte(configJSON))
 		if err != nil {
-			log.Printf("解压 release.zip 失败: %v, output: %s", err, string(unzipOutput))
-			return fmt.Errorf("解压 release.zip 失败: %v", err)
+			return nil, "", err
 		}
+	}
 
-		log.Printf("Unzip successful, output: %s", string(unzipOutput))
+	remote, err := a.transferService.ReadFile(server, path.Join(server.DefaultPath, services.ProjectConfigFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, "", fmt.Errorf("读取远程配置文件失败: %w", err)
+	}
 
-		// 删除 release.zip
-		deleteSession, err := client.NewSession()
-		if err != nil {
-			return fmt.Errorf("创建删除会话失败: %v", err)
+	plan, err := services.PlanProjectConfig(server, remote, proposed)
+	if err != nil {
+		return nil, "", err
+	}
+	return plan, configJSON, nil
+}
+
+// projectConfigPlanResponse 生成配置变更计划的响应
+func (a *App) projectConfigPlanResponse(server *services.ServerData, configJSON string) string {
+	plan, _, err := a.planProjectConfig(server, configJSON)
+	if err != nil {
+		log.Printf("Failed to plan project config for %s: %v", server.ServerID, err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
 		}
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
 
-		deleteCommand := fmt.Sprintf("rm -f %s", releaseZipPath)
-		log.Printf("Executing delete command: %s", deleteCommand)
-		deleteOutput, err := deleteSession.CombinedOutput(deleteCommand)
-		deleteSession.Close()
+	response := ApiResponse{Code: 200, Msg: "success", Data: plan}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
 
-		if err != nil {
-			log.Printf("删除 release.zip 失败: %v, output: %s", err, string(deleteOutput))
-			return fmt.Errorf("删除 release.zip 失败: %v", err)
+// projectConfigApplyResponse 确认远程配置自生成计划后未被修改，再上传配置（不处理 release.zip）
+func (a *App) projectConfigApplyResponse(server *services.ServerData, configJSON, expectedRemoteSHA256 string) string {
+	plan, configJSON, err := a.planProjectConfig(server, configJSON)
+	if err != nil {
+		log.Printf("Failed to plan project config for %s: %v", server.ServerID, err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if plan.RemoteSHA256 != expectedRemoteSHA256 {
+		log.Printf("Remote project config on %s changed since plan (expected %q, got %q)", server.ServerID, expectedRemoteSHA256, plan.RemoteSHA256)
+		response := ApiResponse{Code: 409, Msg: "远程配置文件在生成计划后已被修改，请重新确认变更", Data: plan}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 只上传计划中的配置文件，服务器上的 release.zip 由发布流程处理
+	if err := a.uploadConfigFile(server, services.ProjectConfigFile, configJSON); err != nil {
+		log.Printf("Failed to upload project config: %v", err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
 		}
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
 
-		log.Printf("Release.zip deleted successfully")
-	} else {
-		log.Printf("No release.zip found, skipping extraction")
+	response := ApiResponse{
+		Code: 200,
+		Msg:  "项目配置文件上传成功",
+		Data: map[string]interface{}{
+			"config": plan.Config,
+			"path":   plan.Path,
+			"plan":   plan,
+		},
 	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
 
-	// 3. 上传配置文件
-	log.Printf("Uploading config file: %s", filename)
-	uploadSession, err := client.NewSession()
+// ProjectConfigPlan 预览上传 project_config.json 会产生的变更（不修改服务器）
+// projectConfigJson 为空时按服务器保存的项目列表生成配置
+func (a *App) ProjectConfigPlan(serverID, projectConfigJson, authorization, clientJson string) string {
+	log.Printf("ProjectConfigPlan called with serverID: %s", serverID)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
 	if err != nil {
-		return fmt.Errorf("创建上传会话失败: %v", err)
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return string(result)
 	}
-	defer uploadSession.Close()
 
-	// 创建目标文件路径
-	targetPath := fmt.Sprintf("%s/%s", server.DefaultPath, filename)
+	return a.projectConfigPlanResponse(server, projectConfigJson)
+}
+
+// ProjectConfigApply 确认计划后上传 project_config.json
+// expectedRemoteSHA256 为计划中的 remote_sha256，远程文件已变化时返回409和新的计划
+func (a *App) ProjectConfigApply(serverID, projectConfigJson, expectedRemoteSHA256, authorization, clientJson string) string {
+	return a.audited("ProjectConfigApply", authorization, map[string]interface{}{
+		"server_id":              serverID,
+		"project_config_json":    projectConfigJson,
+		"expected_remote_sha256": expectedRemoteSHA256,
+	}, func() string {
+		return a.projectConfigApply(serverID, projectConfigJson, expectedRemoteSHA256, authorization, clientJson)
+	})
+}
+
+// projectConfigApply ProjectConfigApply 的实现
+func (a *App) projectConfigApply(serverID, projectConfigJson, expectedRemoteSHA256, authorization, clientJson string) string {
+	log.Printf("ProjectConfigApply called with serverID: %s", serverID)
 
-	// 使用cat命令写入文件内容
-	command := fmt.Sprintf("cat > %s", targetPath)
-	uploadSession.Stdin = strings.NewReader(content)
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
 
-	err = uploadSession.Run(command)
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
 	if err != nil {
-		return fmt.Errorf("上传配置文件失败: %v", err)
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
 	}
 
-	log.Printf("Config file uploaded successfully to: %s", targetPath)
-	return nil
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	return a.projectConfigApplyResponse(server, projectConfigJson, expectedRemoteSHA256)
+}
+
+// ProjectConfigPlanWithData 使用前端传入的服务器数据预览 project_config.json 变更
+func (a *App) ProjectConfigPlanWithData(serverDataJson, projectConfigJson, authorization string) string {
+	log.Printf("ProjectConfigPlanWithData called")
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var server services.ServerData
+	if err := json.Unmarshal([]byte(serverDataJson), &server); err != nil {
+		log.Printf("Failed to unmarshal server data: %v", err)
+		response := ApiResponse{Code: 400, Msg: "服务器数据格式错误"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	return a.projectConfigPlanResponse(&server, projectConfigJson)
+}
+
+// ProjectConfigApplyWithData 使用前端传入的服务器数据确认并上传 project_config.json
+func (a *App) ProjectConfigApplyWithData(serverDataJson, projectConfigJson, expectedRemoteSHA256, authorization string) string {
+	return a.audited("ProjectConfigApplyWithData", authorization, map[string]interface{}{
+		"server_data_json":       serverDataJson,
+		"project_config_json":    projectConfigJson,
+		"expected_remote_sha256": expectedRemoteSHA256,
+	}, func() string {
+		return a.projectConfigApplyWithData(serverDataJson, projectConfigJson, expectedRemoteSHA256, authorization)
+	})
+}
+
+// projectConfigApplyWithData ProjectConfigApplyWithData 的实现
+func (a *App) projectConfigApplyWithData(serverDataJson, projectConfigJson, expectedRemoteSHA256, authorization string) string {
+	log.Printf("ProjectConfigApplyWithData called")
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var server services.ServerData
+	if err := json.Unmarshal([]byte(serverDataJson), &server); err != nil {
+		log.Printf("Failed to unmarshal server data: %v", err)
+		response := ApiResponse{Code: 400, Msg: "服务器数据格式错误"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	return a.projectConfigApplyResponse(&server, projectConfigJson, expectedRemoteSHA256)
+}
+
+// JobCancel 取消正在执行的远程命令任务
+func (a *App) JobCancel(jobID string) string {
+	return a.audited("JobCancel", "", map[string]interface{}{
+		"job_id": jobID,
+	}, func() string {
+		return a.jobCancel(jobID)
+	})
+}
+
+// jobCancel JobCancel 的实现
+func (a *App) jobCancel(jobID string) string {
+	log.Printf("JobCancel called with jobID: %s", jobID)
+
+	if err := a.jobService.Cancel(jobID); err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "已发送取消请求"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// JobStatus 查询远程命令任务的状态和已输出内容
+func (a *App) JobStatus(jobID string) string {
+	status, output, err := a.jobService.Status(jobID)
+	if err != nil {
+		response := ApiResponse{Code: 404, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{
+		Code: 200,
+		Msg:  "success",
+		Data: map[string]interface{}{
+			"job":    status,
+			"output": output,
+		},
+	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// DeployResult 查询项目初始化/更新任务的最终结果，任务仍在执行时返回 202
+func (a *App) DeployResult(jobID string) string {
+	result, ok, err := a.jobService.Result(jobID)
+	if err != nil {
+		response := ApiResponse{Code: 404, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+	if !ok {
+		response := ApiResponse{Code: 202, Msg: "任务仍在执行", Data: map[string]interface{}{"job_id": jobID}}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+	response, _ := json.Marshal(result)
+	return string(response)
+}
+
+// JobList 列出最近的远程命令任务
+func (a *App) JobList() string {
+	response := ApiResponse{Code: 200, Msg: "success", Data: a.jobService.List()}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ReleaseProgressEvent 发布包部署进度事件名称
+const ReleaseProgressEvent = "release_progress"
+
+// SelectReleaseArchive 选择本地发布包（release.zip），返回路径、大小和SHA-256
+func (a *App) SelectReleaseArchive() string {
+	log.Printf("SelectReleaseArchive called")
+
+	selectedFile, err := wailsruntime.OpenFileDialog(a.ctx, wailsruntime.OpenDialogOptions{
+		Title: "选择发布包",
+		Filters: []wailsruntime.FileFilter{
+			{DisplayName: "发布包 (*.zip)", Pattern: "*.zip"},
+		},
+	})
+
+	if err != nil {
+		log.Printf("Failed to open file dialog: %v", err)
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("打开文件选择对话框失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if selectedFile == "" {
+		// 用户取消选择
+		response := ApiResponse{Code: 400, Msg: "用户取消选择文件"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	sum, size, err := services.FileSHA256(selectedFile)
+	if err != nil {
+		log.Printf("Failed to read release archive: %v", err)
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("读取发布包失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	log.Printf("Release archive selected: %s (%d bytes, sha256 %s)", selectedFile, size, sum)
+	response := ApiResponse{
+		Code: 200,
+		Msg:  "文件选择成功",
+		Data: map[string]interface{}{
+			"path":   selectedFile,
+			"name":   filepath.Base(selectedFile),
+			"size":   size,
+			"sha256": sum,
+		},
+	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// releaseDeployResult 单台服务器的发布包部署结果
+type releaseDeployResult struct {
+	ServerID string `json:"server_id"`
+	Success  bool   `json:"success"`
+	Step     string `json:"step"` // "uploading", "extracting", "done", "failed"
+	SHA256   string `json:"sha256,omitempty"`
+	Release  string `json:"release,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// emitReleaseProgress 推送发布包部署进度
+func (a *App) emitReleaseProgress(result releaseDeployResult) {
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, ReleaseProgressEvent, result)
+	}
+}
+
+// deployReleaseArchive 上传发布包到服务器、校验SHA-256后解压并上传配置文件
+func (a *App) deployReleaseArchive(server *services.ServerData, localPath, expectedSum string) releaseDeployResult {
+	result := releaseDeployResult{ServerID: server.ServerID}
+	name, err := a.uploadReleaseArchive(server, localPath, expectedSum, func(step string) {
+		result.Step = step
+		a.emitReleaseProgress(result)
+	})
+	if err != nil {
+		log.Printf("Release deploy to %s failed at %s: %v", server.ServerID, result.Step, err)
+		result.Error = err.Error()
+		result.Step = "failed"
+		a.emitReleaseProgress(result)
+		return result
+	}
+
+	result.SHA256 = expectedSum
+	result.Release = name
+	result.Step = "done"
+	result.Success = true
+	a.emitReleaseProgress(result)
+	return result
+}
+
+// uploadReleaseArchive 上传发布包（uploading）、校验后解压并上传配置文件（extracting），每个步骤开始时调用 onStep
+// 返回激活的发布版本名
+func (a *App) uploadReleaseArchive(server *services.ServerData, localPath, expectedSum string, onStep func(step string)) (string, error) {
+	// 1. 上传发布包（传输层会在重命名前比对远程SHA-256）
+	onStep("uploading")
+	remotePath := path.Join(server.DefaultPath, "release.zip")
+	uploaded, err := a.transferService.UploadFile(server, localPath, remotePath, services.TransferOptions{
+		TransferID: "release-" + server.ServerID,
+	})
+	if err != nil {
+		return "", err
+	}
+	// 上传期间本地文件被修改时，远程内容与选择时的发布包不一致
+	if uploaded.SHA256 != expectedSum {
+		return "", fmt.Errorf("发布包校验失败: 期望 %s，实际上传 %s", expectedSum, uploaded.SHA256)
+	}
+
+	// 2. 解压发布包并上传配置文件
+	onStep("extracting")
+	_, configJSON, err := buildServerProjectConfig(server)
+	if err != nil {
+		return "", err
+	}
+	return a.processReleaseAndUploadConfig(server, "project_config.json", configJSON)
+}
+
+// ReleaseDeploy 上传本地发布包到一台或多台已保存的服务器并解压部署（serverIDsJson 为服务器ID数组）
+func (a *App) ReleaseDeploy(serverIDsJson, localPath, authorization, clientJson string) string {
+	return a.audited("ReleaseDeploy", authorization, map[string]interface{}{
+		"server_ids_json": serverIDsJson,
+		"local_path":      localPath,
+	}, func() string {
+		return a.releaseDeploy(serverIDsJson, localPath, authorization, clientJson)
+	})
+}
+
+// releaseDeploy ReleaseDeploy 的实现
+func (a *App) releaseDeploy(serverIDsJson, localPath, authorization, clientJson string) string {
+	log.Printf("ReleaseDeploy called with servers: %s, localPath: %s", serverIDsJson, localPath)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var serverIDs []string
+	if err := json.Unmarshal([]byte(serverIDsJson), &serverIDs); err != nil || len(serverIDs) == 0 {
+		response := ApiResponse{Code: 400, Msg: "请选择要部署的服务器"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 先计算本地发布包的SHA-256，作为各服务器校验的基准
+	expectedSum, size, err := services.FileSHA256(localPath)
+	if err != nil {
+		response := ApiResponse{Code: 400, Msg: fmt.Sprintf("读取发布包失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+	log.Printf("Deploying release %s (%d bytes, sha256 %s) to %d server(s)", localPath, size, expectedSum, len(serverIDs))
+
+	results := make([]releaseDeployResult, 0, len(serverIDs))
+	failed := 0
+	for _, serverID := range serverIDs {
+		server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+		if err != nil || server == nil {
+			if err == nil {
+				err = fmt.Errorf("服务器不存在")
+			}
+			item := releaseDeployResult{ServerID: serverID, Step: "failed", Error: err.Error()}
+			a.emitReleaseProgress(item)
+			results = append(results, item)
+			failed++
+			continue
+		}
+
+		item := a.deployReleaseArchive(server, localPath, expectedSum)
+		if !item.Success {
+			failed++
+		}
+		results = append(results, item)
+	}
+
+	data := map[string]interface{}{
+		"sha256":  expectedSum,
+		"size":    size,
+		"results": results,
+	}
+	if failed > 0 {
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("发布包部署完成，%d 台成功，%d 台失败", len(results)-failed, failed), Data: data}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: fmt.Sprintf("发布包已部署到 %d 台服务器", len(results)), Data: data}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ReleaseList 列出服务器上的发布版本
+func (a *App) ReleaseList(serverID, authorization, clientJson string) string {
+	log.Printf("ReleaseList called with serverID: %s", serverID)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	releases, err := a.releaseService.List(server)
+	if err != nil {
+		log.Printf("Failed to list releases: %v", err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "success", Data: releases}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ReleaseRollback 回滚到指定发布版本（releaseName 为空时回滚到上一个版本），并对服务器上的项目重新执行更新
+func (a *App) ReleaseRollback(serverID, releaseName, authorization, clientJson string) string {
+	return a.audited("ReleaseRollback", authorization, map[string]interface{}{
+		"server_id":    serverID,
+		"release_name": releaseName,
+	}, func() string {
+		return a.releaseRollback(serverID, releaseName, authorization, clientJson)
+	})
+}
+
+// releaseRollback ReleaseRollback 的实现
+func (a *App) releaseRollback(serverID, releaseName, authorization, clientJson string) string {
+	log.Printf("ReleaseRollback called with serverID: %s, release: %s", serverID, releaseName)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	name, err := a.releaseService.Rollback(server, releaseName)
+	if err != nil {
+		log.Printf("Failed to roll back: %v", err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 对服务器上的项目重新执行 codedeploy.sh update，输出通过 command_output 事件推送
+	projects, failed := a.rerunProjectUpdates(server)
+
+	data := map[string]interface{}{
+		"release":  name,
+		"projects": projects,
+	}
+	if failed > 0 {
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("已回滚到版本 %s，但有 %d 个项目更新失败", name, failed), Data: data}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: fmt.Sprintf("已回滚到版本 %s", name), Data: data}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// projectUpdateResult 单个项目的更新结果
+type projectUpdateResult struct {
+	ProjectID string `json:"project_id"`
+	JobID     string `json:"job_id"`
+	ExitCode  int    `json:"exit_code"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// rerunProjectUpdates 对服务器上的所有项目依次执行 codedeploy.sh update，返回结果和失败数量
+func (a *App) rerunProjectUpdates(server *services.ServerData) ([]projectUpdateResult, int) {
+	results := make([]projectUpdateResult, 0, len(server.ProjectList))
+	failed := 0
+	for _, project := range server.ProjectList {
+		command := projectUpdateCommand(server, project.ProjectID)
+		job, _, err := a.runProjectCommand(server, project.ProjectID, "update", command)
+		item := projectUpdateResult{ProjectID: project.ProjectID, JobID: job.JobID, ExitCode: job.ExitCode, Success: err == nil}
+		if err != nil {
+			item.Error = err.Error()
+			failed++
+		}
+		results = append(results, item)
+	}
+	return results, failed
+}
+
+// rolloutRunner 单台服务器的发布步骤：上传发布包 → 上传配置 → 依次执行 codedeploy.sh update
+func (a *App) rolloutRunner(servers map[string]*services.ServerData, localPath, expectedSum string) services.RolloutRunner {
+	return func(ctx context.Context, target services.RolloutTarget, report *services.RolloutReport) error {
+		server := servers[target.ServerID]
+
+		// 1. 上传并解压发布包、上传配置文件（未选择发布包时只上传配置文件）
+		var release string
+		if localPath != "" {
+			name, err := a.uploadReleaseArchive(server, localPath, expectedSum, report.Step)
+			if err != nil {
+				return err
+			}
+			release = name
+		} else {
+			report.Step("config")
+			_, configJSON, err := buildServerProjectConfig(server)
+			if err != nil {
+				return err
+			}
+			if err := a.uploadConfigFile(server, services.ProjectConfigFile, configJSON); err != nil {
+				return err
+			}
+		}
+
+		// 2. 依次更新项目，某个项目失败后跳过该服务器上剩余的项目
+		report.Step("updating")
+		for i, projectID := range target.ProjectIDs {
+			if ctx.Err() == nil {
+				report.Project(services.RolloutProjectStatus{ProjectID: projectID, Status: services.RolloutRunning, ExitCode: -1})
+				// 本次没有激活新版本时，从更新前的当前版本回滚
+				from := release
+				if from == "" {
+					from = a.rollbackRelease(server, projectID)
+				}
+				job, _, err := a.runProjectCommandContext(ctx, server, projectID, "update", projectUpdateCommand(server, projectID))
+				item := services.RolloutProjectStatus{ProjectID: projectID, Status: services.RolloutSucceeded, JobID: job.JobID, ExitCode: job.ExitCode}
+				if err == nil {
+					health, rollback := a.verifyDeployment(server, projectID, from)
+					if health == nil || health.Healthy {
+						report.Project(item)
+						continue
+					}
+					err = fmt.Errorf("健康检查失败: %s", health.Error)
+					if rollback != nil && rollback.Success {
+						err = fmt.Errorf("%v，已自动回滚到版本 %s", err, rollback.Release)
+					} else if rollback != nil {
+						err = fmt.Errorf("%v，自动回滚失败: %s", err, rollback.Error)
+					}
+				}
+				item.Status = services.RolloutFailed
+				item.Error = err.Error()
+				report.Project(item)
+				if ctx.Err() == nil {
+					err = fmt.Errorf("项目 %s 更新失败: %v", projectID, err)
+				}
+				for _, rest := range target.ProjectIDs[i+1:] {
+					report.Project(services.RolloutProjectStatus{ProjectID: rest, Status: services.RolloutSkipped, ExitCode: -1})
+				}
+				return err
+			}
+			for _, rest := range target.ProjectIDs[i:] {
+				report.Project(services.RolloutProjectStatus{ProjectID: rest, Status: services.RolloutCanceled, ExitCode: -1})
+			}
+			return ctx.Err()
+		}
+		return nil
+	}
+}
+
+// RolloutStart 分批向多台服务器发布：上传发布包（localPath 可为空）→ 上传配置 → 更新项目
+// targetsJson 为 [{"server_id": "...", "project_ids": [...]}]（project_ids 为空表示全部项目）
+// optionsJson 为 {"parallelism": 2, "batch_size": 5, "stop_on_failure": true}，进度通过 rollout_target/rollout_status 事件推送
+func (a *App) RolloutStart(targetsJson, localPath, optionsJson, authorization, clientJson string) string {
+	return a.audited("RolloutStart", authorization, map[string]interface{}{
+		"targets_json": targetsJson,
+		"local_path":   localPath,
+		"options_json": optionsJson,
+	}, func() string {
+		return a.rolloutStart(targetsJson, localPath, optionsJson, authorization, clientJson)
+	})
+}
+
+// rolloutStart RolloutStart 的实现
+func (a *App) rolloutStart(targetsJson, localPath, optionsJson, authorization, clientJson string) string {
+	log.Printf("RolloutStart called with targets: %s, localPath: %s, options: %s", targetsJson, localPath, optionsJson)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var targets []services.RolloutTarget
+	if err := json.Unmarshal([]byte(targetsJson), &targets); err != nil || len(targets) == 0 {
+		response := ApiResponse{Code: 400, Msg: "请选择要发布的服务器"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var options services.RolloutOptions
+	if strings.TrimSpace(optionsJson) != "" {
+		if err := json.Unmarshal([]byte(optionsJson), &options); err != nil {
+			response := ApiResponse{Code: 400, Msg: "发布选项格式错误"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+	}
+
+	// 先计算本地发布包的SHA-256，作为各服务器校验的基准
+	expectedSum := ""
+	if localPath != "" {
+		sum, _, err := services.FileSHA256(localPath)
+		if err != nil {
+			response := ApiResponse{Code: 400, Msg: fmt.Sprintf("读取发布包失败: %v", err)}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+		expectedSum = sum
+	}
+
+	// 开始前确认所有服务器和项目都存在，避免发布到一半才发现
+	list, err := a.jsonService.LoadJsonFile(authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to load servers: %v", err)
+		if result, ok := vaultErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+	servers := make(map[string]*services.ServerData, len(list))
+	for i := range list {
+		servers[list[i].ServerID] = &list[i]
+	}
+
+	seen := make(map[string]bool, len(targets))
+	for i, target := range targets {
+		server := servers[target.ServerID]
+		if server == nil || seen[target.ServerID] {
+			msg := fmt.Sprintf("服务器 %s 不存在", target.ServerID)
+			if server != nil {
+				msg = fmt.Sprintf("服务器 %s 重复", target.ServerID)
+			}
+			response := ApiResponse{Code: 400, Msg: msg}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+		seen[target.ServerID] = true
+
+		if len(target.ProjectIDs) == 0 {
+			for _, project := range server.ProjectList {
+				targets[i].ProjectIDs = append(targets[i].ProjectIDs, project.ProjectID)
+			}
+			continue
+		}
+		for _, projectID := range target.ProjectIDs {
+			found := false
+			for _, project := range server.ProjectList {
+				if project.ProjectID == projectID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				response := ApiResponse{Code: 400, Msg: fmt.Sprintf("服务器 %s 上不存在项目 %s", target.ServerID, projectID)}
+				result, _ := json.Marshal(response)
+				return string(result)
+			}
+		}
+	}
+
+	status, err := a.rolloutService.Start(targets, options, a.rolloutRunner(servers, localPath, expectedSum))
+	if err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "发布已开始", Data: status}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// RolloutStatus 查询发布的状态矩阵
+func (a *App) RolloutStatus(rolloutID string) string {
+	status, err := a.rolloutService.Status(rolloutID)
+	if err != nil {
+		response := ApiResponse{Code: 404, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "success", Data: status}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// RolloutCancel 取消发布：停止运行中的更新任务，不再开始新的服务器
+func (a *App) RolloutCancel(rolloutID string) string {
+	return a.audited("RolloutCancel", "", map[string]interface{}{
+		"rollout_id": rolloutID,
+	}, func() string {
+		return a.rolloutCancel(rolloutID)
+	})
+}
+
+// rolloutCancel RolloutCancel 的实现
+func (a *App) rolloutCancel(rolloutID string) string {
+	log.Printf("RolloutCancel called with rolloutID: %s", rolloutID)
+
+	if err := a.rolloutService.Cancel(rolloutID); err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "已发送取消请求"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// RolloutList 列出最近的发布
+func (a *App) RolloutList() string {
+	response := ApiResponse{Code: 200, Msg: "success", Data: a.rolloutService.List()}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// parseTransferOptions 解析传输选项（mode 为八进制字符串，如 "0644"，为空保留原有权限）
+func parseTransferOptions(mode, owner string) (services.TransferOptions, error) {
+	opts := services.TransferOptions{Owner: strings.TrimSpace(owner)}
+	if mode = strings.TrimSpace(mode); mode != "" {
+		value, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || value > 0777 {
+			return opts, fmt.Errorf("文件权限格式错误: %s", mode)
+		}
+		opts.Mode = os.FileMode(value)
+	}
+	return opts, nil
+}
+
+// TransferUpload 通过SFTP上传本地文件或目录到已保存的服务器，进度通过 transfer_progress 事件推送
+func (a *App) TransferUpload(serverID, localPath, remotePath, mode, owner, authorization, clientJson string) string {
+	return a.audited("TransferUpload", authorization, map[string]interface{}{
+		"server_id":   serverID,
+		"local_path":  localPath,
+		"remote_path": remotePath,
+		"mode":        mode,
+		"owner":       owner,
+	}, func() string {
+		return a.transferUpload(serverID, localPath, remotePath, mode, owner, authorization, clientJson)
+	})
+}
+
+// transferUpload TransferUpload 的实现
+func (a *App) transferUpload(serverID, localPath, remotePath, mode, owner, authorization, clientJson string) string {
+	log.Printf("TransferUpload called with serverID: %s, localPath: %s, remotePath: %s", serverID, localPath, remotePath)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if localPath == "" || remotePath == "" {
+		response := ApiResponse{Code: 400, Msg: "本地路径和远程路径不能为空"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	opts, err := parseTransferOptions(mode, owner)
+	if err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	results, err := a.transferService.Upload(server, localPath, remotePath, opts)
+	if err != nil {
+		log.Printf("Failed to upload: %v", err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("上传失败: %v", err), Data: results}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: fmt.Sprintf("上传成功，共 %d 个文件", len(results)), Data: results}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// TransferDownload 通过SFTP从已保存的服务器下载文件或目录到本地，进度通过 transfer_progress 事件推送
+func (a *App) TransferDownload(serverID, remotePath, localPath, authorization, clientJson string) string {
+	return a.audited("TransferDownload", authorization, map[string]interface{}{
+		"server_id":   serverID,
+		"remote_path": remotePath,
+		"local_path":  localPath,
+	}, func() string {
+		return a.transferDownload(serverID, remotePath, localPath, authorization, clientJson)
+	})
+}
+
+// transferDownload TransferDownload 的实现
+func (a *App) transferDownload(serverID, remotePath, localPath, authorization, clientJson string) string {
+	log.Printf("TransferDownload called with serverID: %s, remotePath: %s, localPath: %s", serverID, remotePath, localPath)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if localPath == "" || remotePath == "" {
+		response := ApiResponse{Code: 400, Msg: "本地路径和远程路径不能为空"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	results, err := a.transferService.Download(server, remotePath, localPath, services.TransferOptions{})
+	if err != nil {
+		log.Printf("Failed to download: %v", err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("下载失败: %v", err), Data: results}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: fmt.Sprintf("下载成功，共 %d 个文件", len(results)), Data: results}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// TerminalOpen 在已保存的服务器上打开交互式终端，输出通过 terminal_output 事件推送
+func (a *App) TerminalOpen(serverID string, cols, rows int, authorization, clientJson string) string {
+	return a.audited("TerminalOpen", authorization, map[string]interface{}{
+		"server_id": serverID,
+		"cols":      cols,
+		"rows":      rows,
+	}, func() string {
+		return a.terminalOpen(serverID, cols, rows, authorization, clientJson)
+	})
+}
+
+// terminalOpen TerminalOpen 的实现
+func (a *App) terminalOpen(serverID string, cols, rows int, authorization, clientJson string) string {
+	log.Printf("TerminalOpen called with serverID: %s, size: %dx%d", serverID, cols, rows)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	// 获取服务器信息
+	server, err := a.jsonService.GetServerByID(serverID, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to get server info: %v", err)
+		response := ApiResponse{Code: 500, Msg: "获取服务器信息失败"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if server == nil {
+		response := ApiResponse{Code: 404, Msg: "服务器不存在"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	terminal, err := a.terminalService.Open(server, cols, rows)
+	if err != nil {
+		log.Printf("Failed to open terminal: %v", err)
+		if result, ok := hostKeyErrorResponse(err); ok {
+			return result
+		}
+		if result, ok := vaultErrorResponse(err); ok {
+			return result
+		}
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("打开终端失败: %v", err)}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "终端已打开", Data: terminal}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// TerminalWrite 向终端写入输入（按键、粘贴内容等）
+func (a *App) TerminalWrite(terminalID, data string) string {
+	if err := a.terminalService.Write(terminalID, data); err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "success"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// TerminalResize 调整终端窗口大小
+func (a *App) TerminalResize(terminalID string, cols, rows int) string {
+	if err := a.terminalService.Resize(terminalID, cols, rows); err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "success"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// TerminalClose 关闭终端
+func (a *App) TerminalClose(terminalID string) string {
+	log.Printf("TerminalClose called with terminalID: %s", terminalID)
+
+	if err := a.terminalService.Close(terminalID); err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "终端已关闭"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// TerminalList 列出打开的终端
+func (a *App) TerminalList() string {
+	response := ApiResponse{Code: 200, Msg: "success", Data: a.terminalService.List()}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// AuditList 查询审计日志（queryJson 为过滤条件，按时间倒序分页）
+func (a *App) AuditList(queryJson, authorization string) string {
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var query services.AuditQuery
+	if strings.TrimSpace(queryJson) != "" {
+		if err := json.Unmarshal([]byte(queryJson), &query); err != nil {
+			response := ApiResponse{Code: 400, Msg: "查询条件格式错误"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+	}
+
+	entries, total, err := a.auditService.Query(query)
+	if err != nil {
+		log.Printf("Failed to query audit log: %v", err)
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{
+		Code: 200,
+		Msg:  "success",
+		Data: map[string]interface{}{
+			"entries": entries,
+			"total":   total,
+		},
+	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// AuditExport 按条件导出审计日志为 JSONL 文件（targetPath 为空时弹出保存对话框）
+func (a *App) AuditExport(queryJson, targetPath, authorization string) string {
+	log.Printf("AuditExport called with targetPath: %s", targetPath)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var query services.AuditQuery
+	if strings.TrimSpace(queryJson) != "" {
+		if err := json.Unmarshal([]byte(queryJson), &query); err != nil {
+			response := ApiResponse{Code: 400, Msg: "查询条件格式错误"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+	}
+
+	if targetPath == "" {
+		selected, err := wailsruntime.SaveFileDialog(a.ctx, wailsruntime.SaveDialogOptions{
+			Title:           "导出审计日志",
+			DefaultFilename: fmt.Sprintf("audit-%s.jsonl", time.Now().Format("20060102150405")),
+			Filters: []wailsruntime.FileFilter{
+				{DisplayName: "JSON Lines (*.jsonl)", Pattern: "*.jsonl"},
+			},
+		})
+		if err != nil {
+			response := ApiResponse{Code: 500, Msg: fmt.Sprintf("打开保存对话框失败: %v", err)}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+		if selected == "" {
+			response := ApiResponse{Code: 400, Msg: "未选择导出文件"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+		targetPath = selected
+	}
+
+	count, err := a.auditService.Export(query, targetPath)
+	if err != nil {
+		log.Printf("Failed to export audit log: %v", err)
+		response := ApiResponse{Code: 500, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{
+		Code: 200,
+		Msg:  fmt.Sprintf("已导出 %d 条审计记录", count),
+		Data: map[string]interface{}{
+			"path":  targetPath,
+			"count": count,
+		},
+	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// loadServers 按ID列表获取服务器（列表为空时返回全部服务器）
+func (a *App) loadServers(serverIDs []string, authorization, clientJson string) ([]services.ServerData, error) {
+	servers, err := a.jsonService.LoadJsonFile(authorization, clientJson)
+	if err != nil {
+		return nil, err
+	}
+	if len(serverIDs) == 0 {
+		return servers, nil
+	}
+
+	byID := make(map[string]services.ServerData, len(servers))
+	for _, server := range servers {
+		byID[server.ServerID] = server
+	}
+	selected := make([]services.ServerData, 0, len(serverIDs))
+	for _, serverID := range serverIDs {
+		server, ok := byID[serverID]
+		if !ok {
+			return nil, fmt.Errorf("服务器 %s 不存在", serverID)
+		}
+		selected = append(selected, server)
+	}
+	return selected, nil
+}
+
+// ServerMetricsCollect 立即采集服务器资源指标（serverIDsJson 为空数组时采集全部服务器）
+func (a *App) ServerMetricsCollect(serverIDsJson, authorization, clientJson string) string {
+	log.Printf("ServerMetricsCollect called with servers: %s", serverIDsJson)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var serverIDs []string
+	if strings.TrimSpace(serverIDsJson) != "" {
+		if err := json.Unmarshal([]byte(serverIDsJson), &serverIDs); err != nil {
+			response := ApiResponse{Code: 400, Msg: "Invalid server list"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+	}
+
+	servers, err := a.loadServers(serverIDs, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to load servers: %v", err)
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	samples := a.metricsService.CollectAll(servers)
+	response := ApiResponse{Code: 200, Msg: "success", Data: samples}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ServerMetricsHistory 查询服务器资源指标的时间序列（since 格式 2006-01-02 15:04:05，为空表示全部）
+func (a *App) ServerMetricsHistory(serverID, since, authorization string) string {
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "success", Data: a.metricsService.History(serverID, since)}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ServerMetricsLatest 每台服务器最近一次采样（用于仪表盘）
+func (a *App) ServerMetricsLatest(authorization string) string {
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{
+		Code: 200,
+		Msg:  "success",
+		Data: map[string]interface{}{
+			"samples":  a.metricsService.Latest(),
+			"schedule": a.metricsService.Schedule(),
+		},
+	}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ServerMetricsScheduleStart 开始定时采集全部服务器的资源指标，采样通过 metrics_sample 事件推送
+func (a *App) ServerMetricsScheduleStart(intervalSeconds int, authorization, clientJson string) string {
+	return a.audited("ServerMetricsScheduleStart", authorization, map[string]interface{}{
+		"interval_seconds": intervalSeconds,
+	}, func() string {
+		return a.serverMetricsScheduleStart(intervalSeconds, authorization, clientJson)
+	})
+}
+
+// serverMetricsScheduleStart ServerMetricsScheduleStart 的实现
+func (a *App) serverMetricsScheduleStart(intervalSeconds int, authorization, clientJson string) string {
+	log.Printf("ServerMetricsScheduleStart called with interval: %ds", intervalSeconds)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	schedule := a.metricsService.StartSchedule(intervalSeconds, func() ([]services.ServerData, error) {
+		return a.loadServers(nil, authorization, clientJson)
+	})
+	response := ApiResponse{Code: 200, Msg: "定时采集已启动", Data: schedule}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// ServerMetricsScheduleStop 停止定时采集
+func (a *App) ServerMetricsScheduleStop() string {
+	return a.audited("ServerMetricsScheduleStop", "", nil, func() string {
+		return a.serverMetricsScheduleStop()
+	})
+}
+
+// serverMetricsScheduleStop ServerMetricsScheduleStop 的实现
+func (a *App) serverMetricsScheduleStop() string {
+	log.Printf("ServerMetricsScheduleStop called")
+
+	a.metricsService.StopSchedule()
+	response := ApiResponse{Code: 200, Msg: "定时采集已停止", Data: a.metricsService.Schedule()}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// CommandTemplateList 列出命令模板库
+func (a *App) CommandTemplateList(authorization string) string {
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "success", Data: a.commandService.List()}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// CommandTemplateSave 新增或更新命令模板
+func (a *App) CommandTemplateSave(templateJson, authorization string) string {
+	return a.audited("CommandTemplateSave", authorization, map[string]interface{}{
+		"template_json": templateJson,
+	}, func() string {
+		return a.commandTemplateSave(templateJson, authorization)
+	})
+}
+
+// commandTemplateSave CommandTemplateSave 的实现
+func (a *App) commandTemplateSave(templateJson, authorization string) string {
+	log.Printf("CommandTemplateSave called")
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var template services.CommandTemplate
+	if err := json.Unmarshal([]byte(templateJson), &template); err != nil {
+		log.Printf("Failed to unmarshal command template: %v", err)
+		response := ApiResponse{Code: 400, Msg: "Invalid command template"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	saved, err := a.commandService.Save(template)
+	if err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "命令模板已保存", Data: saved}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// CommandTemplateDelete 删除命令模板
+func (a *App) CommandTemplateDelete(templateID, authorization string) string {
+	return a.audited("CommandTemplateDelete", authorization, map[string]interface{}{
+		"template_id": templateID,
+	}, func() string {
+		return a.commandTemplateDelete(templateID, authorization)
+	})
+}
+
+// commandTemplateDelete CommandTemplateDelete 的实现
+func (a *App) commandTemplateDelete(templateID, authorization string) string {
+	log.Printf("CommandTemplateDelete called with templateID: %s", templateID)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	if err := a.commandService.Delete(templateID); err != nil {
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: "命令模板已删除"}
+	result, _ := json.Marshal(response)
+	return string(result)
+}
+
+// CommandRun 在一台或多台服务器上执行命令模板（paramsJson 为参数名到值的映射），返回每台服务器的执行结果；
+// 执行过程中的输出通过 command_output 事件推送，可用 JobCancel 取消
+func (a *App) CommandRun(templateID, serverIDsJson, paramsJson string, parallelism int, authorization, clientJson string) string {
+	return a.audited("CommandRun", authorization, map[string]interface{}{
+		"template_id": templateID,
+		"server_ids":  serverIDsJson,
+		"params":      paramsJson,
+		"parallelism": parallelism,
+	}, func() string {
+		return a.commandRun(templateID, serverIDsJson, paramsJson, parallelism, authorization, clientJson)
+	})
+}
+
+// commandRun CommandRun 的实现
+func (a *App) commandRun(templateID, serverIDsJson, paramsJson string, parallelism int, authorization, clientJson string) string {
+	log.Printf("CommandRun called with templateID: %s, servers: %s", templateID, serverIDsJson)
+
+	// 检查授权
+	if authorization == "" || strings.TrimSpace(authorization) == "" {
+		response := ApiResponse{Code: 401, Msg: "Authorization required"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	template, err := a.commandService.Get(templateID)
+	if err != nil {
+		response := ApiResponse{Code: 404, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	var serverIDs []string
+	if err := json.Unmarshal([]byte(serverIDsJson), &serverIDs); err != nil || len(serverIDs) == 0 {
+		response := ApiResponse{Code: 400, Msg: "请选择要执行命令的服务器"}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	values := map[string]string{}
+	if strings.TrimSpace(paramsJson) != "" {
+		if err := json.Unmarshal([]byte(paramsJson), &values); err != nil {
+			response := ApiResponse{Code: 400, Msg: "Invalid command parameters"}
+			result, _ := json.Marshal(response)
+			return string(result)
+		}
+	}
+
+	servers, err := a.loadServers(serverIDs, authorization, clientJson)
+	if err != nil {
+		log.Printf("Failed to load servers: %v", err)
+		response := ApiResponse{Code: 400, Msg: err.Error()}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	action := "command:" + template.ID
+	results := a.commandService.RunAll(template, servers, values, parallelism,
+		func(server *services.ServerData, projectID, command string) (services.JobStatus, string, error) {
+			return a.runProjectCommand(server, projectID, action, command)
+		})
+
+	failed := 0
+	output := strings.Builder{}
+	for _, item := range results {
+		if !item.Success {
+			failed++
+		}
+		fmt.Fprintf(&output, "[%s] %s\n%s\n", item.ServerID, item.Command, item.Output)
+	}
+
+	data := map[string]interface{}{
+		"template_id": template.ID,
+		"results":     results,
+		// output 汇总各服务器输出，供审计日志记录
+		"output": output.String(),
+	}
+	if failed > 0 {
+		response := ApiResponse{Code: 500, Msg: fmt.Sprintf("命令执行完成，%d 台成功，%d 台失败", len(results)-failed, failed), Data: data}
+		result, _ := json.Marshal(response)
+		return string(result)
+	}
+
+	response := ApiResponse{Code: 200, Msg: fmt.Sprintf("命令已在 %d 台服务器上执行", len(results)), Data: data}
+	result, _ := json.Marshal(response)
+	return string(result)
 }
 
 // CapturePage 抓取页面内容
@@ -21,6 +21,7 @@ declare global {
                 App?: any;
             };
         };
+        runtime?: any;
     }
 }
 
@@ -54,16 +55,25 @@ const apiMap: Record<string, (data: any) => Promise<string>> = {
     'list': (data: any) => window.go!.main!.App!.List(data.authorization, data.client_json),
     'server_list': (data: any) => window.go!.main!.App!.List(data.authorization, data.client_json),
     'server_info': (data: any) => window.go!.main!.App!.ServerInfo(data.serverId, data.authorization, data.client_json),
-    'server_add': (data: any) => window.go!.main!.App!.ServerAdd(data.server_id, data.server_name, data.server_ip, data.server_port, data.server_user, data.server_password, data.default_path || '/adplace', data.authorization, data.client_json),
-    'server_update': (data: any) => window.go!.main!.App!.ServerUpdate(data.old_server_id || data.server_id, data.server_id, data.server_name, data.server_ip, data.server_port, data.server_user, data.server_password, data.default_path || '/adplace', data.authorization, data.client_json),
+    'server_add': (data: any) => window.go!.main!.App!.ServerAdd(data.server_id, data.server_name, data.server_ip, data.server_port, data.server_user, data.server_password, data.default_path || '/adplace', data.auth_type || 'password', data.private_key || '', data.key_passphrase || '', data.authorization, data.client_json),
+    'server_update': (data: any) => window.go!.main!.App!.ServerUpdate(data.old_server_id || data.server_id, data.server_id, data.server_name, data.server_ip, data.server_port, data.server_user, data.server_password, data.default_path || '/adplace', data.auth_type || 'password', data.private_key || '', data.key_passphrase || '', data.authorization, data.client_json, Number(data.revision) || 0),
     'server_delete': (data: any) => window.go!.main!.App!.ServerDelete(data.server_id, data.authorization, data.client_json),
+    'inventory_history_list': (data: any) => window.go!.main!.App!.InventoryHistoryList(data.authorization, data.client_json),
+    'inventory_history_diff': (data: any) => window.go!.main!.App!.InventoryHistoryDiff(Number(data.from_version) || 0, Number(data.to_version) || 0, data.authorization, data.client_json),
+    'inventory_history_restore': (data: any) => window.go!.main!.App!.InventoryHistoryRestore(Number(data.version) || 0, data.authorization, data.client_json),
+    'vault_status': (data: any) => window.go!.main!.App!.VaultStatus(data.authorization, data.client_json),
+    'vault_unlock': (data: any) => window.go!.main!.App!.VaultUnlock(data.passphrase, data.authorization, data.client_json),
+    'vault_lock': (data: any) => window.go!.main!.App!.VaultLock(),
     'test_ssh': (data: any) => window.go!.main!.App!.TestSSHConnection(data.server_ip, data.server_port, data.server_user, data.server_password),
+    'test_ssh_with_data': (data: any) => window.go!.main!.App!.TestSSHConnectionWithData(JSON.stringify(data.server_data || data)),
+    'server_accept_host_key': (data: any) => window.go!.main!.App!.ServerAcceptHostKey(data.server_id, data.fingerprint, data.authorization, data.client_json),
     'test_stored_ssh': (data: any) => window.go!.main!.App!.TestStoredServerSSH(data.server_id, data.authorization, data.client_json),
     'project_info': (data: any) => window.go!.main!.App!.ProjectInfo(data.projectId, data.authorization, data.client_json),
     'project_form': (data: any) => window.go!.main!.App!.ProjectForm(data.serverId, data.projectInfo, data.authorization, data.client_json),
     'project_delete': (data: any) => window.go!.main!.App!.ProjectDelete(data.serverId, data.projectId, data.authorization, data.client_json),
     'exec': (data: any) => window.go!.main!.App!.Exec(data.projectId, data.sql, data.sqlType, data.authorization, data.client_json),
     'exec_with_project_url': (data: any) => window.go!.main!.App!.ExecWithProjectURL(data.projectApiUrl, data.sql, data.sqlType, data.authorization),
+    'exec_with_project_url_params': (data: any) => window.go!.main!.App!.ExecWithProjectURLParams(data.projectApiUrl, data.sql, JSON.stringify(data.params ?? []), data.sqlType, data.dialect ?? '', data.strict ?? true, data.authorization),
     'test_401': () => window.go!.main!.App!.TestUnauthorized(),
     'cloudflare_get_dns': (data: any) => window.go!.main!.App!.CloudflareGetDNSRecords(data.api_token, data.zone_id, data.name || '', data.type || ''),
     'cloudflare_configure_dns': (data: any) => window.go!.main!.App!.CloudflareConfigureDNSRecord(data.api_token, data.zone_id, data.name, data.type, data.content, data.proxied || true),
@@ -72,12 +82,57 @@ const apiMap: Record<string, (data: any) => Promise<string>> = {
     'cloudflare_pages_add_domain': (data: any) => window.go!.main!.App!.CloudflarePagesAddDomain(data.api_token, data.zone_id, data.project_name, data.domain),
     'cloudflare_pages_get_domains': (data: any) => window.go!.main!.App!.CloudflarePagesGetDomains(data.api_token, data.zone_id, data.project_name),
     'cloudflare_pages_delete_domain': (data: any) => window.go!.main!.App!.CloudflarePagesDeleteDomain(data.api_token, data.zone_id, data.project_name, data.domain),
-    'generate_project_config': (data: any) => window.go!.main!.App!.GenerateProjectConfig(data.server_id, data.authorization, data.client_json),
-    'upload_project_config': (data: any) => window.go!.main!.App!.UploadProjectConfig(data.server_data_json, data.project_config_json, data.authorization),
     'project_init': (data: any) => window.go!.main!.App!.ProjectInit(data.server_id, data.project_id, data.authorization, data.client_json),
     'project_init_with_data': (data: any) => window.go!.main!.App!.ProjectInitWithData(data.server_id, data.project_id, data.server_data_json, data.authorization),
     'project_update': (data: any) => window.go!.main!.App!.ProjectUpdate(data.server_id, data.project_id, data.authorization, data.client_json),
     'project_update_with_data': (data: any) => window.go!.main!.App!.ProjectUpdateWithData(data.server_id, data.project_id, data.server_data_json, data.authorization),
+    'deploy_result': (data: any) => window.go!.main!.App!.DeployResult(data.job_id),
+    'job_cancel': (data: any) => window.go!.main!.App!.JobCancel(data.job_id),
+    'job_status': (data: any) => window.go!.main!.App!.JobStatus(data.job_id),
+    'job_list': (data: any) => window.go!.main!.App!.JobList(),
+    'select_release_archive': (data: any) => window.go!.main!.App!.SelectReleaseArchive(),
+    'release_deploy': (data: any) => window.go!.main!.App!.ReleaseDeploy(JSON.stringify(data.server_ids || []), data.local_path, data.authorization, data.client_json),
+    'release_list': (data: any) => window.go!.main!.App!.ReleaseList(data.server_id, data.authorization, data.client_json),
+    'release_rollback': (data: any) => window.go!.main!.App!.ReleaseRollback(data.server_id, data.release_name || '', data.authorization, data.client_json),
+    'project_health_check': (data: any) => window.go!.main!.App!.ProjectHealthCheck(data.server_id, data.project_id, data.authorization, data.client_json),
+    'server_jump_update': (data: any) => window.go!.main!.App!.ServerJumpUpdate(data.server_id, data.jump_server_id || '', data.jump_host ? JSON.stringify(data.jump_host) : '', data.authorization, data.client_json),
+    'project_status': (data: any) => window.go!.main!.App!.ProjectStatus(data.server_id, data.project_id, data.authorization, data.client_json),
+    'project_log_tail': (data: any) => window.go!.main!.App!.ProjectLogTail(data.server_id, data.project_id, Number(data.lines) || 0, data.authorization, data.client_json),
+    'project_log_follow': (data: any) => window.go!.main!.App!.ProjectLogFollow(data.server_id, data.project_id, Number(data.lines) || 0, data.authorization, data.client_json),
+    'project_log_stop': (data: any) => window.go!.main!.App!.ProjectLogStop(data.stream_id),
+    'project_log_list': (data: any) => window.go!.main!.App!.ProjectLogList(),
+    'server_log_source_update': (data: any) => window.go!.main!.App!.ServerLogSourceUpdate(data.server_id, data.log_source || '', data.authorization, data.client_json),
+    'project_port_check': (data: any) => window.go!.main!.App!.ProjectPortCheck(data.server_id, data.project_info ? JSON.stringify(data.project_info) : '', !!data.probe, data.authorization, data.client_json),
+    'project_config_plan': (data: any) => window.go!.main!.App!.ProjectConfigPlan(data.server_id, data.project_config_json || '', data.authorization, data.client_json),
+    'project_config_apply': (data: any) => window.go!.main!.App!.ProjectConfigApply(data.server_id, data.project_config_json || '', data.remote_sha256 || '', data.authorization, data.client_json),
+    'project_config_plan_with_data': (data: any) => window.go!.main!.App!.ProjectConfigPlanWithData(data.server_data_json, data.project_config_json || '', data.authorization),
+    'project_config_apply_with_data': (data: any) => window.go!.main!.App!.ProjectConfigApplyWithData(data.server_data_json, data.project_config_json || '', data.remote_sha256 || '', data.authorization),
+    'rollout_start': (data: any) => window.go!.main!.App!.RolloutStart(JSON.stringify(data.targets || []), data.local_path || '', JSON.stringify(data.options || {}), data.authorization, data.client_json),
+    'rollout_status': (data: any) => window.go!.main!.App!.RolloutStatus(data.rollout_id),
+    'rollout_cancel': (data: any) => window.go!.main!.App!.RolloutCancel(data.rollout_id),
+    'rollout_list': (data: any) => window.go!.main!.App!.RolloutList(),
+    'transfer_upload': (data: any) => window.go!.main!.App!.TransferUpload(data.server_id, data.local_path, data.remote_path, data.mode || '', data.owner || '', data.authorization, data.client_json),
+    'transfer_download': (data: any) => window.go!.main!.App!.TransferDownload(data.server_id, data.remote_path, data.local_path, data.authorization, data.client_json),
+    'terminal_open': (data: any) => window.go!.main!.App!.TerminalOpen(data.server_id, Number(data.cols) || 80, Number(data.rows) || 24, data.authorization, data.client_json),
+    'terminal_write': (data: any) => window.go!.main!.App!.TerminalWrite(data.terminal_id, data.data),
+    'terminal_resize': (data: any) => window.go!.main!.App!.TerminalResize(data.terminal_id, Number(data.cols), Number(data.rows)),
+    'terminal_close': (data: any) => window.go!.main!.App!.TerminalClose(data.terminal_id),
+    'terminal_list': (data: any) => window.go!.main!.App!.TerminalList(),
+    'server_connectivity_check': (data: any) => window.go!.main!.App!.ServerConnectivityCheck(JSON.stringify(data.server_ids || []), Number(data.parallelism) || 0, data.authorization, data.client_json),
+    'server_connectivity_status': (data: any) => window.go!.main!.App!.ServerConnectivityStatus(),
+    'server_connectivity_schedule_start': (data: any) => window.go!.main!.App!.ServerConnectivityScheduleStart(Number(data.interval_seconds) || 0, Number(data.parallelism) || 0, data.authorization, data.client_json),
+    'server_connectivity_schedule_stop': (data: any) => window.go!.main!.App!.ServerConnectivityScheduleStop(),
+    'server_metrics_collect': (data: any) => window.go!.main!.App!.ServerMetricsCollect(JSON.stringify(data.server_ids || []), data.authorization, data.client_json),
+    'server_metrics_history': (data: any) => window.go!.main!.App!.ServerMetricsHistory(data.server_id, data.since || '', data.authorization),
+    'server_metrics_latest': (data: any) => window.go!.main!.App!.ServerMetricsLatest(data.authorization),
+    'server_metrics_schedule_start': (data: any) => window.go!.main!.App!.ServerMetricsScheduleStart(Number(data.interval_seconds) || 0, data.authorization, data.client_json),
+    'server_metrics_schedule_stop': (data: any) => window.go!.main!.App!.ServerMetricsScheduleStop(),
+    'command_template_list': (data: any) => window.go!.main!.App!.CommandTemplateList(data.authorization),
+    'command_template_save': (data: any) => window.go!.main!.App!.CommandTemplateSave(JSON.stringify(data.template || {}), data.authorization),
+    'command_template_delete': (data: any) => window.go!.main!.App!.CommandTemplateDelete(data.template_id, data.authorization),
+    'command_run': (data: any) => window.go!.main!.App!.CommandRun(data.template_id, JSON.stringify(data.server_ids || []), JSON.stringify(data.params || {}), Number(data.parallelism) || 0, data.authorization, data.client_json),
+    'audit_list': (data: any) => window.go!.main!.App!.AuditList(JSON.stringify(data.query || {}), data.authorization),
+    'audit_export': (data: any) => window.go!.main!.App!.AuditExport(JSON.stringify(data.query || {}), data.target_path || '', data.authorization),
     'capture_page': (data: any) => window.go!.main!.App!.CapturePage(data.url, data.options || '{}'),
     'get_capture_progress': (data: any) => window.go!.main!.App!.GetCaptureProgress(),
     'download_file': (data: any) => window.go!.main!.App!.DownloadFile(data.filePath),
@@ -202,6 +257,37 @@ const api = async (uri: string, data: any) => {
 }
 
 
+// 项目初始化/更新在后台执行：等待 deploy_result 事件返回最终结果（含健康检查），接口返回的是任务已开始
+export const waitDeployResult = async (start: Promise<any>): Promise<any> => {
+    const started = await start;
+    const jobId = started?.data?.job_id;
+    if (started?.code !== 200 || !jobId) {
+        return started;
+    }
+
+    return new Promise((resolve) => {
+        let done = false;
+        let off: (() => void) | undefined;
+        const finish = (result: any) => {
+            if (done) return;
+            done = true;
+            off?.();
+            resolve(result);
+        };
+        off = window.runtime?.EventsOn('deploy_result', (event: any) => {
+            if (event?.job_id === jobId) {
+                finish(event.response);
+            }
+        });
+        // 注册监听前任务可能已经结束，主动查询一次
+        api('deploy_result', { job_id: jobId }).then((result: any) => {
+            if (result?.code !== 202) {
+                finish(result);
+            }
+        });
+    });
+};
+
 // 导出未授权处理函数，供其他地方使用
 export { handleUnauthorized };
 
@@ -18,41 +18,52 @@ class Base {
         }, {});
     }
 
+    // insert/update/delete 返回 { sql, params }，值通过 ? 占位符绑定，由后端按方言转义
     insert() {
-        const columns = this.fields.filter(f => f !== this.primaryKey).join(', ');
-        const values = this.fields.filter(f => f !== this.primaryKey)
-            .map(f => this.escapeValue(this.formData[f], f)).join(', ');
-        return `INSERT INTO ${this.tableName} (${columns}) VALUES (${values})`;
+        const fields = this.fields.filter(f => f !== this.primaryKey);
+        const placeholders = fields.map(() => '?').join(', ');
+        return {
+            sql: `INSERT INTO ${this.tableName} (${fields.join(', ')}) VALUES (${placeholders})`,
+            params: fields.map(f => this.paramValue(this.formData[f], f)),
+        };
     }
 
     update() {
-        const setClause = this.fields.filter(f => f !== this.primaryKey)
-            .map(f => `${f} = ${this.escapeValue(this.formData[f], f)}`).join(', ');
-        const whereClause = `${this.primaryKey} = ${this.escapeValue(this.formData[this.primaryKey], this.primaryKey)}`;
-        return `UPDATE ${this.tableName} SET ${setClause} WHERE ${whereClause}`;
+        const fields = this.fields.filter(f => f !== this.primaryKey);
+        const setClause = fields.map(f => `${f} = ?`).join(', ');
+        return {
+            sql: `UPDATE ${this.tableName} SET ${setClause} WHERE ${this.primaryKey} = ?`,
+            params: [
+                ...fields.map(f => this.paramValue(this.formData[f], f)),
+                this.paramValue(this.formData[this.primaryKey], this.primaryKey),
+            ],
+        };
     }
 
     delete() {
-        return `DELETE FROM ${this.tableName} WHERE ${this.primaryKey} = ${this.escapeValue(this.formData[this.primaryKey], this.primaryKey)}`;
+        return {
+            sql: `DELETE FROM ${this.tableName} WHERE ${this.primaryKey} = ?`,
+            params: [this.paramValue(this.formData[this.primaryKey], this.primaryKey)],
+        };
     }
 
     selects() {
         return `SELECT ${this.fields.join(', ')} FROM ${this.tableName}`;
     }
 
-    escapeValue(value, field) {
+    paramValue(value, field) {
         const type = this.fieldsType[field]?.type;
         if (type === 'string' || type === 'enum' || type === 'datetime') {
             // null or undefined
             if (value === null || value === undefined) {
-                return 'NULL';
+                return null;
             }
-            return `'${String(value).replace(/'/g, "''")}'`;
+            return String(value);
         }
         if (type === 'int') {
             return Number.parseInt(value, 10) || 0; // Default to 0 if NaN
         }
-        return value;
+        return value ?? null;
     }
 }
 
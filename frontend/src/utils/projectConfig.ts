@@ -0,0 +1,58 @@
+import { h } from 'vue';
+import api from '@/api';
+
+// 变更计划的文字说明（每行一项）
+export const describeProjectConfigPlan = (plan: any): string => {
+    const lines: string[] = [];
+    if (!plan?.remote_exists) {
+        lines.push(`服务器上还没有 ${plan?.path}，将新建该文件`);
+    }
+    (plan?.added_projects || []).forEach((id: string) => lines.push(`新增项目 ${id}`));
+    (plan?.removed_projects || []).forEach((id: string) => lines.push(`移除项目 ${id}`));
+    (plan?.changes || []).forEach((change: any) => {
+        lines.push(`${change.project_id} 的 ${change.field}: ${change.from || '(空)'} → ${change.to || '(空)'}`);
+    });
+    return lines.join('\n');
+};
+
+// 弹框确认配置变更，确认返回 true
+export const confirmProjectConfigPlan = (dialog: any, plan: any): Promise<boolean> => {
+    return new Promise((resolve) => {
+        dialog.warning({
+            title: '确认配置文件变更',
+            content: () => h('div', { style: 'white-space: pre-line' }, describeProjectConfigPlan(plan)),
+            positiveText: '确认上传',
+            negativeText: '取消',
+            onPositiveClick: () => resolve(true),
+            onNegativeClick: () => resolve(false),
+            onClose: () => resolve(false),
+            onMaskClick: () => resolve(false)
+        });
+    });
+};
+
+// 上传 project_config.json：先生成变更计划，有变更时经 confirm 确认后再上传
+// projectConfigJson 为空时按服务器数据中的全部项目生成；远程文件在确认期间被修改时返回 409
+export const applyProjectConfig = async (
+    serverData: any,
+    projectConfigJson: string,
+    confirm: (plan: any) => Promise<boolean>
+): Promise<any> => {
+    const serverDataJson = JSON.stringify(serverData);
+    const plan = await api('project_config_plan_with_data', {
+        server_data_json: serverDataJson,
+        project_config_json: projectConfigJson
+    });
+    if (plan?.code !== 200) {
+        return plan;
+    }
+    if (!plan.data?.unchanged && !(await confirm(plan.data))) {
+        return { code: 499, msg: '已取消上传配置文件' };
+    }
+
+    return api('project_config_apply_with_data', {
+        server_data_json: serverDataJson,
+        project_config_json: projectConfigJson,
+        remote_sha256: plan.data?.remote_sha256 || ''
+    });
+};
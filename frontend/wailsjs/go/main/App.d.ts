@@ -1,6 +1,10 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AuditExport(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function AuditList(arg1:string,arg2:string):Promise<string>;
+
 export function Base64Md5(arg1:string):Promise<string>;
 
 export function CapturePage(arg1:string,arg2:string):Promise<string>;
@@ -19,60 +23,162 @@ export function CloudflarePagesDeleteDomain(arg1:string,arg2:string,arg3:string,
 
 export function CloudflarePagesGetDomains(arg1:string,arg2:string,arg3:string):Promise<string>;
 
+export function CommandRun(arg1:string,arg2:string,arg3:string,arg4:number,arg5:string,arg6:string):Promise<string>;
+
+export function CommandTemplateDelete(arg1:string,arg2:string):Promise<string>;
+
+export function CommandTemplateList(arg1:string):Promise<string>;
+
+export function CommandTemplateSave(arg1:string,arg2:string):Promise<string>;
+
+export function DeployResult(arg1:string):Promise<string>;
+
 export function DownloadFile(arg1:string):Promise<string>;
 
 export function ExecWithProjectURL(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
 
-export function GenerateProjectConfig(arg1:string,arg2:string,arg3:string):Promise<string>;
-
-export function GenerateProjectConfigForSingleProject(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+export function ExecWithProjectURLParams(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:boolean,arg7:string):Promise<string>;
 
 export function GetCaptureProgress():Promise<string>;
 
 export function Greet(arg1:string):Promise<string>;
 
+export function InventoryHistoryDiff(arg1:number,arg2:number,arg3:string,arg4:string):Promise<string>;
+
+export function InventoryHistoryList(arg1:string,arg2:string):Promise<string>;
+
+export function InventoryHistoryRestore(arg1:number,arg2:string,arg3:string):Promise<string>;
+
+export function JobCancel(arg1:string):Promise<string>;
+
+export function JobList():Promise<string>;
+
+export function JobStatus(arg1:string):Promise<string>;
+
 export function List(arg1:string,arg2:string):Promise<string>;
 
 export function OpenDirectory(arg1:string):Promise<string>;
 
 export function OpenUrl(arg1:string):Promise<string>;
 
+export function ProjectConfigApply(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<string>;
+
+export function ProjectConfigApplyWithData(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function ProjectConfigPlan(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function ProjectConfigPlanWithData(arg1:string,arg2:string,arg3:string):Promise<string>;
+
 export function ProjectDelete(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
 
 export function ProjectForm(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
 
+export function ProjectHealthCheck(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
 export function ProjectInfo(arg1:string,arg2:string,arg3:string):Promise<string>;
 
 export function ProjectInit(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
 
 export function ProjectInitWithData(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
 
+export function ProjectLogFollow(arg1:string,arg2:string,arg3:number,arg4:string,arg5:string):Promise<string>;
+
+export function ProjectLogList():Promise<string>;
+
+export function ProjectLogStop(arg1:string):Promise<string>;
+
+export function ProjectLogTail(arg1:string,arg2:string,arg3:number,arg4:string,arg5:string):Promise<string>;
+
+export function ProjectPortCheck(arg1:string,arg2:string,arg3:boolean,arg4:string,arg5:string):Promise<string>;
+
 export function ProjectPortUpdate(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<string>;
 
+export function ProjectStatus(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
 export function ProjectUpdate(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
 
 export function ProjectUpdateWithData(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
 
+export function ReleaseDeploy(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function ReleaseList(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function ReleaseRollback(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function RolloutCancel(arg1:string):Promise<string>;
+
+export function RolloutList():Promise<string>;
+
+export function RolloutStart(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<string>;
+
+export function RolloutStatus(arg1:string):Promise<string>;
+
 export function SaveZipToDirectory(arg1:string,arg2:string,arg3:string):Promise<string>;
 
 export function SelectDirectory():Promise<string>;
 
-export function ServerAdd(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string,arg8:string,arg9:string):Promise<string>;
+export function SelectReleaseArchive():Promise<string>;
+
+export function ServerAcceptHostKey(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function ServerAdd(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string,arg8:string,arg9:string,arg10:string,arg11:string,arg12:string):Promise<string>;
+
+export function ServerConnectivityCheck(arg1:string,arg2:number,arg3:string,arg4:string):Promise<string>;
+
+export function ServerConnectivityScheduleStart(arg1:number,arg2:number,arg3:string,arg4:string):Promise<string>;
+
+export function ServerConnectivityScheduleStop():Promise<string>;
+
+export function ServerConnectivityStatus():Promise<string>;
 
 export function ServerDelete(arg1:string,arg2:string,arg3:string):Promise<string>;
 
 export function ServerInfo(arg1:string,arg2:string,arg3:string):Promise<string>;
 
-export function ServerUpdate(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string,arg8:string,arg9:string,arg10:string):Promise<string>;
+export function ServerJumpUpdate(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<string>;
+
+export function ServerLogSourceUpdate(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function ServerMetricsCollect(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function ServerMetricsHistory(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function ServerMetricsLatest(arg1:string):Promise<string>;
+
+export function ServerMetricsScheduleStart(arg1:number,arg2:string,arg3:string):Promise<string>;
+
+export function ServerMetricsScheduleStop():Promise<string>;
+
+export function ServerUpdate(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string,arg8:string,arg9:string,arg10:string,arg11:string,arg12:string,arg13:string,arg14:number):Promise<string>;
 
 export function ShowMessage(arg1:string,arg2:string):Promise<void>;
 
 export function StopCapture():Promise<string>;
 
+export function TerminalClose(arg1:string):Promise<string>;
+
+export function TerminalList():Promise<string>;
+
+export function TerminalOpen(arg1:string,arg2:number,arg3:number,arg4:string,arg5:string):Promise<string>;
+
+export function TerminalResize(arg1:string,arg2:number,arg3:number):Promise<string>;
+
+export function TerminalWrite(arg1:string,arg2:string):Promise<string>;
+
 export function TestSSHConnection(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
 
+export function TestSSHConnectionWithData(arg1:string):Promise<string>;
+
 export function TestStoredServerSSH(arg1:string,arg2:string,arg3:string):Promise<string>;
 
 export function TestUnauthorized():Promise<string>;
 
-export function UploadProjectConfig(arg1:string,arg2:string,arg3:string):Promise<string>;
+export function TransferDownload(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<string>;
+
+export function TransferUpload(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:string,arg7:string):Promise<string>;
+
+export function VaultLock():Promise<string>;
+
+export function VaultStatus(arg1:string,arg2:string):Promise<string>;
+
+export function VaultUnlock(arg1:string,arg2:string,arg3:string):Promise<string>;
@@ -2,6 +2,14 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AuditExport(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AuditExport'](arg1, arg2, arg3);
+}
+
+export function AuditList(arg1, arg2) {
+  return window['go']['main']['App']['AuditList'](arg1, arg2);
+}
+
 export function Base64Md5(arg1) {
   return window['go']['main']['App']['Base64Md5'](arg1);
 }
@@ -38,6 +46,26 @@ export function CloudflarePagesGetDomains(arg1, arg2, arg3) {
   return window['go']['main']['App']['CloudflarePagesGetDomains'](arg1, arg2, arg3);
 }
 
+export function CommandRun(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['CommandRun'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function CommandTemplateDelete(arg1, arg2) {
+  return window['go']['main']['App']['CommandTemplateDelete'](arg1, arg2);
+}
+
+export function CommandTemplateList(arg1) {
+  return window['go']['main']['App']['CommandTemplateList'](arg1);
+}
+
+export function CommandTemplateSave(arg1, arg2) {
+  return window['go']['main']['App']['CommandTemplateSave'](arg1, arg2);
+}
+
+export function DeployResult(arg1) {
+  return window['go']['main']['App']['DeployResult'](arg1);
+}
+
 export function DownloadFile(arg1) {
   return window['go']['main']['App']['DownloadFile'](arg1);
 }
@@ -46,12 +74,8 @@ export function ExecWithProjectURL(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['ExecWithProjectURL'](arg1, arg2, arg3, arg4);
 }
 
-export function GenerateProjectConfig(arg1, arg2, arg3) {
-  return window['go']['main']['App']['GenerateProjectConfig'](arg1, arg2, arg3);
-}
-
-export function GenerateProjectConfigForSingleProject(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['GenerateProjectConfigForSingleProject'](arg1, arg2, arg3, arg4);
+export function ExecWithProjectURLParams(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['ExecWithProjectURLParams'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
 }
 
 export function GetCaptureProgress() {
@@ -62,6 +86,30 @@ export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
 
+export function InventoryHistoryDiff(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['InventoryHistoryDiff'](arg1, arg2, arg3, arg4);
+}
+
+export function InventoryHistoryList(arg1, arg2) {
+  return window['go']['main']['App']['InventoryHistoryList'](arg1, arg2);
+}
+
+export function InventoryHistoryRestore(arg1, arg2, arg3) {
+  return window['go']['main']['App']['InventoryHistoryRestore'](arg1, arg2, arg3);
+}
+
+export function JobCancel(arg1) {
+  return window['go']['main']['App']['JobCancel'](arg1);
+}
+
+export function JobList() {
+  return window['go']['main']['App']['JobList']();
+}
+
+export function JobStatus(arg1) {
+  return window['go']['main']['App']['JobStatus'](arg1);
+}
+
 export function List(arg1, arg2) {
   return window['go']['main']['App']['List'](arg1, arg2);
 }
@@ -74,6 +122,22 @@ export function OpenUrl(arg1) {
   return window['go']['main']['App']['OpenUrl'](arg1);
 }
 
+export function ProjectConfigApply(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ProjectConfigApply'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ProjectConfigApplyWithData(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ProjectConfigApplyWithData'](arg1, arg2, arg3, arg4);
+}
+
+export function ProjectConfigPlan(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ProjectConfigPlan'](arg1, arg2, arg3, arg4);
+}
+
+export function ProjectConfigPlanWithData(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ProjectConfigPlanWithData'](arg1, arg2, arg3);
+}
+
 export function ProjectDelete(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['ProjectDelete'](arg1, arg2, arg3, arg4);
 }
@@ -82,6 +146,10 @@ export function ProjectForm(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['ProjectForm'](arg1, arg2, arg3, arg4);
 }
 
+export function ProjectHealthCheck(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ProjectHealthCheck'](arg1, arg2, arg3, arg4);
+}
+
 export function ProjectInfo(arg1, arg2, arg3) {
   return window['go']['main']['App']['ProjectInfo'](arg1, arg2, arg3);
 }
@@ -94,10 +162,34 @@ export function ProjectInitWithData(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['ProjectInitWithData'](arg1, arg2, arg3, arg4);
 }
 
+export function ProjectLogFollow(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ProjectLogFollow'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ProjectLogList() {
+  return window['go']['main']['App']['ProjectLogList']();
+}
+
+export function ProjectLogStop(arg1) {
+  return window['go']['main']['App']['ProjectLogStop'](arg1);
+}
+
+export function ProjectLogTail(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ProjectLogTail'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ProjectPortCheck(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ProjectPortCheck'](arg1, arg2, arg3, arg4, arg5);
+}
+
 export function ProjectPortUpdate(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['ProjectPortUpdate'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function ProjectStatus(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ProjectStatus'](arg1, arg2, arg3, arg4);
+}
+
 export function ProjectUpdate(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['ProjectUpdate'](arg1, arg2, arg3, arg4);
 }
@@ -106,6 +198,34 @@ export function ProjectUpdateWithData(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['ProjectUpdateWithData'](arg1, arg2, arg3, arg4);
 }
 
+export function ReleaseDeploy(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ReleaseDeploy'](arg1, arg2, arg3, arg4);
+}
+
+export function ReleaseList(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ReleaseList'](arg1, arg2, arg3);
+}
+
+export function ReleaseRollback(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ReleaseRollback'](arg1, arg2, arg3, arg4);
+}
+
+export function RolloutCancel(arg1) {
+  return window['go']['main']['App']['RolloutCancel'](arg1);
+}
+
+export function RolloutList() {
+  return window['go']['main']['App']['RolloutList']();
+}
+
+export function RolloutStart(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['RolloutStart'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function RolloutStatus(arg1) {
+  return window['go']['main']['App']['RolloutStatus'](arg1);
+}
+
 export function SaveZipToDirectory(arg1, arg2, arg3) {
   return window['go']['main']['App']['SaveZipToDirectory'](arg1, arg2, arg3);
 }
@@ -114,8 +234,32 @@ export function SelectDirectory() {
   return window['go']['main']['App']['SelectDirectory']();
 }
 
-export function ServerAdd(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9) {
-  return window['go']['main']['App']['ServerAdd'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9);
+export function SelectReleaseArchive() {
+  return window['go']['main']['App']['SelectReleaseArchive']();
+}
+
+export function ServerAcceptHostKey(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ServerAcceptHostKey'](arg1, arg2, arg3, arg4);
+}
+
+export function ServerAdd(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12) {
+  return window['go']['main']['App']['ServerAdd'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12);
+}
+
+export function ServerConnectivityCheck(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ServerConnectivityCheck'](arg1, arg2, arg3, arg4);
+}
+
+export function ServerConnectivityScheduleStart(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ServerConnectivityScheduleStart'](arg1, arg2, arg3, arg4);
+}
+
+export function ServerConnectivityScheduleStop() {
+  return window['go']['main']['App']['ServerConnectivityScheduleStop']();
+}
+
+export function ServerConnectivityStatus() {
+  return window['go']['main']['App']['ServerConnectivityStatus']();
 }
 
 export function ServerDelete(arg1, arg2, arg3) {
@@ -126,8 +270,36 @@ export function ServerInfo(arg1, arg2, arg3) {
   return window['go']['main']['App']['ServerInfo'](arg1, arg2, arg3);
 }
 
-export function ServerUpdate(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10) {
-  return window['go']['main']['App']['ServerUpdate'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10);
+export function ServerJumpUpdate(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ServerJumpUpdate'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ServerLogSourceUpdate(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ServerLogSourceUpdate'](arg1, arg2, arg3, arg4);
+}
+
+export function ServerMetricsCollect(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ServerMetricsCollect'](arg1, arg2, arg3);
+}
+
+export function ServerMetricsHistory(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ServerMetricsHistory'](arg1, arg2, arg3);
+}
+
+export function ServerMetricsLatest(arg1) {
+  return window['go']['main']['App']['ServerMetricsLatest'](arg1);
+}
+
+export function ServerMetricsScheduleStart(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ServerMetricsScheduleStart'](arg1, arg2, arg3);
+}
+
+export function ServerMetricsScheduleStop() {
+  return window['go']['main']['App']['ServerMetricsScheduleStop']();
+}
+
+export function ServerUpdate(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12, arg13, arg14) {
+  return window['go']['main']['App']['ServerUpdate'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12, arg13, arg14);
 }
 
 export function ShowMessage(arg1, arg2) {
@@ -138,10 +310,34 @@ export function StopCapture() {
   return window['go']['main']['App']['StopCapture']();
 }
 
+export function TerminalClose(arg1) {
+  return window['go']['main']['App']['TerminalClose'](arg1);
+}
+
+export function TerminalList() {
+  return window['go']['main']['App']['TerminalList']();
+}
+
+export function TerminalOpen(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['TerminalOpen'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function TerminalResize(arg1, arg2, arg3) {
+  return window['go']['main']['App']['TerminalResize'](arg1, arg2, arg3);
+}
+
+export function TerminalWrite(arg1, arg2) {
+  return window['go']['main']['App']['TerminalWrite'](arg1, arg2);
+}
+
 export function TestSSHConnection(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['TestSSHConnection'](arg1, arg2, arg3, arg4);
 }
 
+export function TestSSHConnectionWithData(arg1) {
+  return window['go']['main']['App']['TestSSHConnectionWithData'](arg1);
+}
+
 export function TestStoredServerSSH(arg1, arg2, arg3) {
   return window['go']['main']['App']['TestStoredServerSSH'](arg1, arg2, arg3);
 }
@@ -150,6 +346,22 @@ export function TestUnauthorized() {
   return window['go']['main']['App']['TestUnauthorized']();
 }
 
-export function UploadProjectConfig(arg1, arg2, arg3) {
-  return window['go']['main']['App']['UploadProjectConfig'](arg1, arg2, arg3);
+export function TransferDownload(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['TransferDownload'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function TransferUpload(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['TransferUpload'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function VaultLock() {
+  return window['go']['main']['App']['VaultLock']();
+}
+
+export function VaultStatus(arg1, arg2) {
+  return window['go']['main']['App']['VaultStatus'](arg1, arg2);
+}
+
+export function VaultUnlock(arg1, arg2, arg3) {
+  return window['go']['main']['App']['VaultUnlock'](arg1, arg2, arg3);
 }
@@ -13,13 +13,28 @@ import (
 const KEY = "3HXV4P8dizvATG5EjLIsUKxSreyghDMB" // 32字节密钥
 
 // AesService AES加密服务
-type AesService struct{}
+type AesService struct {
+	key []byte // 为空时使用与项目接口约定的 KEY
+}
 
 // NewAesService 创建AES服务实例
 func NewAesService() *AesService {
 	return &AesService{}
 }
 
+// NewAesServiceWithKey 使用指定的32字节密钥创建AES服务实例
+func NewAesServiceWithKey(key []byte) *AesService {
+	return &AesService{key: key}
+}
+
+// cipherKey 当前使用的密钥
+func (s *AesService) cipherKey() []byte {
+	if len(s.key) > 0 {
+		return s.key
+	}
+	return []byte(KEY)
+}
+
 // Encrypt AES加密
 func (s *AesService) Encrypt(data string) (string, error) {
 	// 生成随机IV
@@ -32,7 +47,7 @@ func (s *AesService) Encrypt(data string) (string, error) {
 	timestamp := time.Now().Unix()
 
 	// 创建AES加密器
-	block, err := aes.NewCipher([]byte(KEY))
+	block, err := aes.NewCipher(s.cipherKey())
 	if err != nil {
 		return "", err
 	}
@@ -77,9 +92,12 @@ func (s *AesService) Decrypt(encryptedData string) (string, error) {
 	// timestamp := binary.BigEndian.Uint64(data[0:8])
 	iv := data[8:24]
 	ciphertext := data[24:]
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("invalid ciphertext length")
+	}
 
 	// 创建AES解密器
-	block, err := aes.NewCipher([]byte(KEY))
+	block, err := aes.NewCipher(s.cipherKey())
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditFile 审计日志文件名（JSONL，每行一条记录）
+const auditFile = "audit.jsonl"
+
+// maxAuditOutput 每条记录保留的命令输出字节数
+const maxAuditOutput = 64 * 1024
+
+// maxAuditLine 每条记录编码后的最大字节数，超出时依次截短输出、省略参数和消息
+const maxAuditLine = 256 * 1024
+
+// auditRedacted 脱敏后的参数值
+const auditRedacted = "******"
+
+// auditSecretKeys 参数名包含这些词时视为敏感信息
+var auditSecretKeys = []string{"password", "passphrase", "private_key", "privatekey", "secret", "token", "authorization"}
+
+// AuditEntry 一条审计记录
+type AuditEntry struct {
+	ID         string                 `json:"id"`
+	Time       string                 `json:"time"`
+	Actor      string                 `json:"actor"`    // 本机用户@主机名
+	TokenID    string                 `json:"token_id"` // 授权token的摘要（不保存token本身）
+	Action     string                 `json:"action"`   // 绑定方法名
+	ServerID   string                 `json:"server_id,omitempty"`
+	ProjectID  string                 `json:"project_id,omitempty"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Code       int                    `json:"code"`
+	Success    bool                   `json:"success"`
+	Msg        string                 `json:"msg,omitempty"`
+	Output     string                 `json:"output,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
+// AuditQuery 审计记录查询条件（字段为空表示不过滤）
+type AuditQuery struct {
+	Action    string `json:"action"`
+	ServerID  string `json:"server_id"`
+	ProjectID string `json:"project_id"`
+	Actor     string `json:"actor"`
+	Success   *bool  `json:"success"`
+	Since     string `json:"since"` // 格式 2006-01-02 15:04:05，可只写日期
+	Until     string `json:"until"`
+	Keyword   string `json:"keyword"` // 匹配消息、参数和输出
+	Offset    int    `json:"offset"`
+	Limit     int    `json:"limit"` // 默认100
+}
+
+// AuditService 本地审计日志服务，记录所有修改类操作
+type AuditService struct {
+	mutex sync.Mutex
+	path  string
+	actor string
+}
+
+// NewAuditService 创建审计日志服务实例
+func NewAuditService() *AuditService {
+	service := &AuditService{actor: currentActor()}
+	if dir, err := AppDataDir(); err == nil {
+		service.path = filepath.Join(dir, auditFile)
+	} else {
+		log.Printf("Audit log disabled: %v", err)
+	}
+	return service
+}
+
+// AuditTokenID 授权token的短摘要，用于区分操作者而不泄露token
+func AuditTokenID(authorization string) string {
+	authorization = strings.TrimSpace(authorization)
+	if authorization == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// isSecretKey 参数名是否为敏感信息
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range auditSecretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactParams 参数脱敏：敏感字段替换为 ******，JSON字符串参数解析后逐字段脱敏
+func RedactParams(params map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		redacted[key] = redactValue(key, value)
+	}
+	return redacted
+}
+
+// redactValue 按字段名脱敏单个值
+func redactValue(key string, value interface{}) interface{} {
+	if isSecretKey(key) {
+		if value == nil || value == "" {
+			return value
+		}
+		return auditRedacted
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return RedactParams(v)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = redactValue(key, item)
+		}
+		return items
+	case string:
+		// 前端常以JSON字符串传递服务器数据，解析后脱敏其中的密码和密钥
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(trimmed), &parsed); err == nil {
+				return redactValue(key, parsed)
+			}
+		}
+	}
+	return value
+}
+
+// Record 追加一条审计记录（自动填充ID、时间和操作者，参数脱敏、输出截断）
+func (s *AuditService) Record(entry AuditEntry) {
+	if s.path == "" {
+		return
+	}
+	entry.ID = newID("audit")
+	if entry.Time == "" {
+		entry.Time = time.Now().Format("2006-01-02 15:04:05")
+	}
+	if entry.Actor == "" {
+		entry.Actor = s.actor
+	}
+	entry.Params = RedactParams(entry.Params)
+	if len(entry.Output) > maxAuditOutput {
+		entry.Output = entry.Output[len(entry.Output)-maxAuditOutput:]
+	}
+
+	line, err := encodeAuditEntry(entry)
+	if err != nil {
+		log.Printf("Failed to encode audit entry: %v", err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("Failed to open audit log: %v", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to write audit log: %v", err)
+	}
+}
+
+// encodeAuditEntry 编码审计记录，超过 maxAuditLine 时依次截短输出、省略参数、截短消息
+func encodeAuditEntry(entry AuditEntry) ([]byte, error) {
+	for {
+		line, err := json.Marshal(entry)
+		if err != nil || len(line) <= maxAuditLine {
+			return line, err
+		}
+		// 转义只会让编码变长，去掉超出的字节数即可（截断多字节字符时再循环一次）
+		excess := len(line) - maxAuditLine
+		switch {
+		case entry.Output != "":
+			entry.Output = entry.Output[min(excess, len(entry.Output)):]
+		case entry.Params != nil && entry.Params["truncated"] == nil:
+			entry.Params = map[string]interface{}{"truncated": fmt.Sprintf("参数过大（%d 字节），未记录", len(line))}
+		case entry.Msg != "":
+			entry.Msg = entry.Msg[:len(entry.Msg)-min(excess, len(entry.Msg))]
+		default:
+			return nil, fmt.Errorf("审计记录过大: %d 字节", len(line))
+		}
+	}
+}
+
+// matches 记录是否满足查询条件
+func (q AuditQuery) matches(entry AuditEntry, line string) bool {
+	if q.Action != "" && !strings.EqualFold(entry.Action, q.Action) {
+		return false
+	}
+	if q.ServerID != "" && entry.ServerID != q.ServerID {
+		return false
+	}
+	if q.ProjectID != "" && entry.ProjectID != q.ProjectID {
+		return false
+	}
+	if q.Actor != "" && !strings.Contains(entry.Actor, q.Actor) && entry.TokenID != q.Actor {
+		return false
+	}
+	if q.Success != nil && entry.Success != *q.Success {
+		return false
+	}
+	// 时间格式固定，可直接按字符串比较
+	if q.Since != "" && entry.Time < q.Since {
+		return false
+	}
+	if q.Until != "" && entry.Time > q.Until && !strings.HasPrefix(entry.Time, q.Until) {
+		return false
+	}
+	if q.Keyword != "" && !strings.Contains(line, q.Keyword) {
+		return false
+	}
+	return true
+}
+
+// scan 按时间顺序遍历满足条件的记录
+func (s *AuditService) scan(query AuditQuery, visit func(entry AuditEntry, line []byte)) error {
+	if s.path == "" {
+		return fmt.Errorf("审计日志不可用")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// 逐行读取不限制行长度，旧版本写入的超长行或损坏的行直接跳过
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimRight(line, "\r\n")
+			var entry AuditEntry
+			if json.Unmarshal(line, &entry) == nil && query.matches(entry, string(line)) {
+				visit(entry, line)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Query 查询审计记录（按时间倒序分页），返回记录和满足条件的总数
+func (s *AuditService) Query(query AuditQuery) ([]AuditEntry, int, error) {
+	if query.Limit <= 0 {
+		query.Limit = 100
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	var all []AuditEntry
+	err := s.scan(query, func(entry AuditEntry, line []byte) {
+		// 列表中不返回完整输出，避免响应过大
+		if len(entry.Output) > 2048 {
+			entry.Output = entry.Output[len(entry.Output)-2048:]
+		}
+		all = append(all, entry)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := []AuditEntry{}
+	for i := len(all) - 1 - query.Offset; i >= 0 && len(entries) < query.Limit; i-- {
+		entries = append(entries, all[i])
+	}
+	return entries, len(all), nil
+}
+
+// Export 把满足条件的记录（按时间顺序、包含完整输出）导出为JSONL文件，返回导出的条数
+func (s *AuditService) Export(query AuditQuery, targetPath string) (int, error) {
+	var buffer strings.Builder
+	count := 0
+	err := s.scan(query, func(entry AuditEntry, line []byte) {
+		buffer.Write(line)
+		buffer.WriteByte('\n')
+		count++
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := writeFileAtomic(targetPath, []byte(buffer.String()), 0600); err != nil {
+		return 0, fmt.Errorf("写入导出文件失败: %v", err)
+	}
+	return count, nil
+}
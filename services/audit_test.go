@@ -0,0 +1,79 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestAuditService(t *testing.T) *AuditService {
+	t.Helper()
+	return &AuditService{path: filepath.Join(t.TempDir(), auditFile), actor: "tester@host"}
+}
+
+func TestAuditRecordCapsLineSize(t *testing.T) {
+	huge := strings.Repeat("x", 2*maxAuditLine)
+	tests := []struct {
+		name      string
+		entry     AuditEntry
+		truncated bool // 参数是否被省略
+	}{
+		{"small", AuditEntry{Action: "A", Params: map[string]interface{}{"sql": "select 1"}, Output: "ok"}, false},
+		{"huge output", AuditEntry{Action: "A", Params: map[string]interface{}{"sql": "select 1"}, Output: huge}, false},
+		{"escaped output", AuditEntry{Action: "A", Output: strings.Repeat("<\"中", maxAuditLine)}, false},
+		{"huge params", AuditEntry{Action: "A", Params: map[string]interface{}{"server_data_json": huge}}, true},
+		{"huge message", AuditEntry{Action: "A", Params: map[string]interface{}{"sql": "select 1"}, Msg: huge}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestAuditService(t)
+			service.Record(tt.entry)
+
+			data, err := os.ReadFile(service.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) > maxAuditLine+1 {
+				t.Fatalf("line size = %d; want at most %d", len(data), maxAuditLine)
+			}
+			entries, total, err := service.Query(AuditQuery{})
+			if err != nil || total != 1 {
+				t.Fatalf("Query = %d entries, %v; want 1", total, err)
+			}
+			if _, got := entries[0].Params["truncated"]; got != tt.truncated {
+				t.Fatalf("params = %v; want truncated %v", entries[0].Params, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestAuditScanSkipsBadLines(t *testing.T) {
+	service := newTestAuditService(t)
+	service.Record(AuditEntry{Action: "First"})
+
+	// 旧版本写入的超长行和损坏的行
+	file, err := os.OpenFile(service.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"action":"Legacy","output":"` + strings.Repeat("y", 2*maxAuditLine) + "\"}\n")
+	file.WriteString("not json\n")
+	file.WriteString(`{"action":"Trunc`)
+	file.Close()
+
+	service.Record(AuditEntry{Action: "Last"})
+
+	entries, total, err := service.Query(AuditQuery{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	actions := []string{}
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	// 截断的行与下一条记录拼在一起，整体无法解析而被跳过
+	if total != 2 || strings.Join(actions, ",") != "Legacy,First" {
+		t.Fatalf("entries = %v (total %d); want Legacy,First", actions, total)
+	}
+}
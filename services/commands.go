@@ -0,0 +1,380 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandsFile 命令模板库文件名
+const commandsFile = "commands.json"
+
+// 命令模板参数类型
+const (
+	ParamString  = "string"
+	ParamInt     = "int"
+	ParamPath    = "path"
+	ParamProject = "project" // 项目ID，必须是目标服务器上已有的项目
+)
+
+// 命令执行参数
+const (
+	defaultCommandParallelism = 4
+	maxCommandParallelism     = 16
+	maxCommandOutput          = 64 * 1024 // 每个目标返回的输出字节数
+)
+
+// commandPlaceholder 模板中的占位符 {name}；未声明的名称（如 awk '{print}'）原样保留
+var commandPlaceholder = regexp.MustCompile(`\{([a-z][a-z0-9_]*)\}`)
+
+// commandParamName 参数名格式
+var commandParamName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// builtinCommandParams 内置参数，按目标服务器自动填充
+var builtinCommandParams = map[string]func(server *ServerData) string{
+	"server_id":    func(server *ServerData) string { return server.ServerID },
+	"server_ip":    func(server *ServerData) string { return server.ServerIP },
+	"default_path": func(server *ServerData) string { return server.DefaultPath },
+}
+
+// CommandParam 命令模板参数
+type CommandParam struct {
+	Name     string `json:"name"`
+	Label    string `json:"label,omitempty"`
+	Type     string `json:"type"` // string、int、path、project
+	Required bool   `json:"required,omitempty"`
+	Default  string `json:"default,omitempty"`
+}
+
+// CommandTemplate 命令模板，命令中的 {name} 在执行时替换为加引号的参数值
+type CommandTemplate struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Command     string         `json:"command"`
+	Params      []CommandParam `json:"params,omitempty"`
+	CreatedAt   string         `json:"created_at,omitempty"`
+	UpdatedAt   string         `json:"updated_at,omitempty"`
+}
+
+// CommandRunResult 命令在单台服务器上的执行结果
+type CommandRunResult struct {
+	ServerID string `json:"server_id"`
+	Command  string `json:"command,omitempty"`
+	JobID    string `json:"job_id,omitempty"`
+	Success  bool   `json:"success"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CommandRunner 在服务器上执行渲染后的命令（由调用方提供，通常以任务方式执行以便推送输出和取消）
+type CommandRunner func(server *ServerData, projectID, command string) (JobStatus, string, error)
+
+// defaultCommandTemplates 模板库不存在时的初始模板
+var defaultCommandTemplates = []CommandTemplate{
+	{
+		ID:          "disk_usage",
+		Name:        "磁盘使用",
+		Description: "查看部署目录所在磁盘的使用情况",
+		Command:     "df -h {default_path}",
+	},
+	{
+		ID:          "project_size",
+		Name:        "项目目录大小",
+		Description: "查看项目目录及其子目录占用的空间",
+		Command:     "du -sh {default_path}/{project}/* 2>/dev/null | sort -h",
+		Params:      []CommandParam{{Name: "project", Label: "项目", Type: ParamProject, Required: true}},
+	},
+	{
+		ID:          "tail_file",
+		Name:        "查看文件末尾",
+		Description: "输出文件最后若干行",
+		Command:     "tail -n {lines} {file}",
+		Params: []CommandParam{
+			{Name: "file", Label: "文件", Type: ParamPath, Required: true},
+			{Name: "lines", Label: "行数", Type: ParamInt, Default: "100"},
+		},
+	},
+}
+
+// CommandService 远程命令模板库，保存在本地数据目录
+type CommandService struct {
+	mutex     sync.Mutex
+	path      string
+	templates []CommandTemplate
+}
+
+// NewCommandService 创建命令模板库实例并加载本地保存的模板
+func NewCommandService() *CommandService {
+	service := &CommandService{}
+	dir, err := AppDataDir()
+	if err != nil {
+		log.Printf("Command library is not persisted: %v", err)
+		service.templates = append([]CommandTemplate(nil), defaultCommandTemplates...)
+		return service
+	}
+	service.path = filepath.Join(dir, commandsFile)
+
+	data, err := os.ReadFile(service.path)
+	if os.IsNotExist(err) {
+		service.templates = append([]CommandTemplate(nil), defaultCommandTemplates...)
+		return service
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &service.templates)
+	}
+	if err != nil {
+		log.Printf("Failed to load command library: %v", err)
+	}
+	return service
+}
+
+// save 写入本地文件（调用方持有锁）
+func (s *CommandService) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.templates, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0600)
+}
+
+// List 列出全部模板（按名称排序）
+func (s *CommandService) List() []CommandTemplate {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	templates := append([]CommandTemplate{}, s.templates...)
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// Get 按ID获取模板
+func (s *CommandService) Get(templateID string) (CommandTemplate, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, template := range s.templates {
+		if template.ID == templateID {
+			return template, nil
+		}
+	}
+	return CommandTemplate{}, fmt.Errorf("命令模板 %s 不存在", templateID)
+}
+
+// Validate 校验模板：参数名合法、不重复且不与内置参数同名，类型和默认值有效
+func (t *CommandTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("命令模板名称不能为空")
+	}
+	if strings.TrimSpace(t.Command) == "" {
+		return fmt.Errorf("命令不能为空")
+	}
+
+	declared := make(map[string]bool, len(t.Params))
+	for _, param := range t.Params {
+		if !commandParamName.MatchString(param.Name) {
+			return fmt.Errorf("参数名 %q 只能包含小写字母、数字和下划线，且以字母开头", param.Name)
+		}
+		if _, ok := builtinCommandParams[param.Name]; ok {
+			return fmt.Errorf("参数名 %s 为内置参数", param.Name)
+		}
+		if declared[param.Name] {
+			return fmt.Errorf("参数名 %s 重复", param.Name)
+		}
+		switch param.Type {
+		case ParamString, ParamInt, ParamPath, ParamProject:
+		default:
+			return fmt.Errorf("参数 %s 的类型 %q 无效", param.Name, param.Type)
+		}
+		if param.Default != "" && param.Type != ParamProject {
+			if err := checkParamValue(param, param.Default, nil); err != nil {
+				return fmt.Errorf("参数 %s 的默认值无效: %v", param.Name, err)
+			}
+		}
+		declared[param.Name] = true
+	}
+	return nil
+}
+
+// Save 新增或更新模板（ID为空时生成新ID），返回保存后的模板
+func (s *CommandService) Save(template CommandTemplate) (CommandTemplate, error) {
+	template.Name = strings.TrimSpace(template.Name)
+	if err := template.Validate(); err != nil {
+		return CommandTemplate{}, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	index := -1
+	for i, existing := range s.templates {
+		if template.ID != "" && existing.ID == template.ID {
+			index = i
+		} else if existing.Name == template.Name {
+			return CommandTemplate{}, fmt.Errorf("命令模板 %s 已存在", template.Name)
+		}
+	}
+
+	if index >= 0 {
+		template.CreatedAt = s.templates[index].CreatedAt
+		template.UpdatedAt = now
+		s.templates[index] = template
+	} else {
+		if template.ID == "" {
+			template.ID = newID("cmd")
+		}
+		template.CreatedAt = now
+		template.UpdatedAt = now
+		s.templates = append(s.templates, template)
+	}
+
+	if err := s.save(); err != nil {
+		return CommandTemplate{}, fmt.Errorf("保存命令模板失败: %v", err)
+	}
+	return template, nil
+}
+
+// Delete 删除模板
+func (s *CommandService) Delete(templateID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, template := range s.templates {
+		if template.ID == templateID {
+			s.templates = append(s.templates[:i], s.templates[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("命令模板 %s 不存在", templateID)
+}
+
+// checkParamValue 按参数类型校验取值（server 为空时不校验项目是否存在）
+func checkParamValue(param CommandParam, value string, server *ServerData) error {
+	if strings.ContainsAny(value, "\x00\n\r") {
+		return fmt.Errorf("不能包含换行或空字符")
+	}
+
+	switch param.Type {
+	case ParamInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%q 不是整数", value)
+		}
+	case ParamPath:
+		if strings.HasPrefix(value, "-") {
+			return fmt.Errorf("路径不能以 - 开头")
+		}
+	case ParamProject:
+		if server == nil {
+			return nil
+		}
+		for _, project := range server.ProjectList {
+			if project.ProjectID == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("服务器 %s 上没有项目 %s", server.ServerID, value)
+	}
+	return nil
+}
+
+// Render 按目标服务器渲染命令：参数按类型校验后加引号替换，返回命令和其中的项目ID（若有项目参数）
+func (t *CommandTemplate) Render(server *ServerData, values map[string]string) (string, string, error) {
+	resolved := make(map[string]string, len(t.Params)+len(builtinCommandParams))
+	for name, builtin := range builtinCommandParams {
+		resolved[name] = builtin(server)
+	}
+
+	projectID := ""
+	for _, param := range t.Params {
+		value, ok := values[param.Name]
+		if !ok || value == "" {
+			value = param.Default
+		}
+		if value == "" {
+			if param.Required {
+				return "", "", fmt.Errorf("缺少参数 %s", param.Name)
+			}
+			resolved[param.Name] = ""
+			continue
+		}
+		if err := checkParamValue(param, value, server); err != nil {
+			return "", "", fmt.Errorf("参数 %s 无效: %v", param.Name, err)
+		}
+		if param.Type == ParamProject && projectID == "" {
+			projectID = value
+		}
+		resolved[param.Name] = value
+	}
+
+	command := commandPlaceholder.ReplaceAllStringFunc(t.Command, func(match string) string {
+		value, ok := resolved[match[1:len(match)-1]]
+		if !ok {
+			return match
+		}
+		return ShellQuote(value)
+	})
+	return command, projectID, nil
+}
+
+// RunAll 在多台服务器上并发执行模板（并发数有限），按输入顺序返回每台服务器的结果
+func (s *CommandService) RunAll(template CommandTemplate, servers []ServerData, values map[string]string, parallelism int, run CommandRunner) []CommandRunResult {
+	if parallelism <= 0 {
+		parallelism = defaultCommandParallelism
+	}
+	if parallelism > maxCommandParallelism {
+		parallelism = maxCommandParallelism
+	}
+
+	results := make([]CommandRunResult, len(servers))
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := range servers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = runTemplate(template, &servers[i], values, run)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// runTemplate 在单台服务器上渲染并执行模板
+func runTemplate(template CommandTemplate, server *ServerData, values map[string]string, run CommandRunner) CommandRunResult {
+	result := CommandRunResult{ServerID: server.ServerID, ExitCode: -1}
+	command, projectID, err := template.Render(server, values)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Command = command
+
+	status, output, err := run(server, projectID, command)
+	result.JobID = status.JobID
+	result.ExitCode = status.ExitCode
+	if len(output) > maxCommandOutput {
+		output = output[len(output)-maxCommandOutput:]
+	}
+	result.Output = output
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Success = true
+	return result
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCommandTemplateRender(t *testing.T) {
+	server := &ServerData{ServerID: "a", ServerIP: "10.0.0.1", DefaultPath: "/srv/my app", ProjectList: []ProjectData{
+		{ProjectID: "p1"},
+	}}
+	template := CommandTemplate{
+		Name:    "test",
+		Command: "cmd {text} {file} {lines} {project} {default_path} awk '{print}' {undeclared}",
+		Params: []CommandParam{
+			{Name: "text", Type: ParamString},
+			{Name: "file", Type: ParamPath, Required: true},
+			{Name: "lines", Type: ParamInt, Default: "100"},
+			{Name: "project", Type: ParamProject},
+		},
+	}
+	tests := []struct {
+		name        string
+		values      map[string]string
+		want        string
+		wantProject string
+		wantErr     string // 错误信息包含的内容，为空表示渲染成功
+	}{
+		{"plain values", map[string]string{"text": "hello", "file": "/var/log/app.log", "lines": "20", "project": "p1"},
+			"cmd hello /var/log/app.log 20 p1 '/srv/my app' awk '{print}' {undeclared}", "p1", ""},
+		{"single quote", map[string]string{"text": "it's", "file": "/tmp/a"},
+			`cmd 'it'"'"'s' /tmp/a 100 '' '/srv/my app' awk '{print}' {undeclared}`, "", ""},
+		{"command substitution", map[string]string{"text": "$(rm -rf /)", "file": "/tmp/`id`"},
+			"cmd '$(rm -rf /)' '/tmp/`id`' 100 '' '/srv/my app' awk '{print}' {undeclared}", "", ""},
+		{"spaces and separators", map[string]string{"text": "a b; echo c && d", "file": "/tmp/my file"},
+			"cmd 'a b; echo c && d' '/tmp/my file' 100 '' '/srv/my app' awk '{print}' {undeclared}", "", ""},
+		{"leading dash in string", map[string]string{"text": "-rf", "file": "/tmp/a"},
+			"cmd -rf /tmp/a 100 '' '/srv/my app' awk '{print}' {undeclared}", "", ""},
+		{"leading dash in path", map[string]string{"file": "-rf"}, "", "", "不能以 - 开头"},
+		{"newline", map[string]string{"text": "a\nrm -rf /", "file": "/tmp/a"}, "", "", "换行"},
+		{"missing required", map[string]string{"text": "x"}, "", "", "缺少参数 file"},
+		{"empty required", map[string]string{"file": ""}, "", "", "缺少参数 file"},
+		{"default used", map[string]string{"file": "/tmp/a", "lines": ""},
+			"cmd '' /tmp/a 100 '' '/srv/my app' awk '{print}' {undeclared}", "", ""},
+		{"invalid int", map[string]string{"file": "/tmp/a", "lines": "1; reboot"}, "", "", "不是整数"},
+		{"unknown project", map[string]string{"file": "/tmp/a", "project": "p2"}, "", "", "没有项目 p2"},
+		{"builtin cannot be overridden", map[string]string{"file": "/tmp/a", "default_path": "/etc"},
+			"cmd '' /tmp/a 100 '' '/srv/my app' awk '{print}' {undeclared}", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, projectID, err := template.Render(server, tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Render = %q, %v; want error containing %q", command, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || command != tt.want || projectID != tt.wantProject {
+				t.Fatalf("Render = %q, %q, %v; want %q, %q", command, projectID, err, tt.want, tt.wantProject)
+			}
+		})
+	}
+}
+
+func TestCommandTemplateRenderShellRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	template := CommandTemplate{Name: "echo", Command: "printf '%s|' {value}", Params: []CommandParam{{Name: "value", Type: ParamString}}}
+	for _, value := range []string{"it's", "$(echo injected)", "`echo injected`", "a b\tc", "-n", "x; echo injected", `\'"`, "*"} {
+		command, _, err := template.Render(&ServerData{}, map[string]string{"value": value})
+		if err != nil {
+			t.Fatalf("Render(%q): %v", value, err)
+		}
+		output, err := exec.Command("sh", "-c", command).Output()
+		if err != nil || string(output) != value+"|" {
+			t.Errorf("sh -c %s printed %q, %v; want %q", command, output, err, value+"|")
+		}
+	}
+}
+
+func TestCommandTemplateValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  []CommandParam
+		wantErr string
+	}{
+		{"valid", []CommandParam{{Name: "file", Type: ParamPath}, {Name: "lines", Type: ParamInt, Default: "10"}}, ""},
+		{"project default not checked", []CommandParam{{Name: "project", Type: ParamProject, Default: "anything"}}, ""},
+		{"invalid name", []CommandParam{{Name: "File", Type: ParamPath}}, "只能包含"},
+		{"builtin name", []CommandParam{{Name: "server_ip", Type: ParamString}}, "内置参数"},
+		{"duplicate", []CommandParam{{Name: "a", Type: ParamString}, {Name: "a", Type: ParamInt}}, "重复"},
+		{"invalid type", []CommandParam{{Name: "a", Type: "shell"}}, "类型"},
+		{"invalid int default", []CommandParam{{Name: "a", Type: ParamInt, Default: "ten"}}, "默认值无效"},
+		{"path default with dash", []CommandParam{{Name: "a", Type: ParamPath, Default: "-rf"}}, "默认值无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := CommandTemplate{Name: "t", Command: "echo", Params: tt.params}
+			err := template.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate = %v; want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate = %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 连接失败分类
+const (
+	FailureDNS         = "dns"         // 域名解析失败
+	FailureRefused     = "refused"     // 端口拒绝连接
+	FailureTimeout     = "timeout"     // 连接或握手超时
+	FailureUnreachable = "unreachable" // 网络或主机不可达
+	FailureAuth        = "auth"        // 认证失败
+	FailureHostKey     = "host_key"    // 主机密钥已变化
+	FailureCredentials = "credentials" // 凭据不可用（保险库未解锁、私钥无法读取等）
+	FailureOther       = "other"
+)
+
+// 连通性检测事件名称（通过 Wails 事件发送到前端）
+const (
+	ConnectivityResultEvent  = "connectivity_result"  // 每台服务器检测完成
+	ConnectivityChangedEvent = "connectivity_changed" // 服务器连通状态或失败分类与上次不同
+)
+
+// 连通性检测参数
+const (
+	connectivityTimeout            = 10 * time.Second
+	defaultConnectivityParallelism = 8
+	maxConnectivityParallelism     = 32
+	defaultConnectivityInterval    = 300 // 秒
+	minConnectivityInterval        = 30
+)
+
+// ConnectivityResult 单台服务器的连通性检测结果
+type ConnectivityResult struct {
+	ServerID           string `json:"server_id"`
+	Connected          bool   `json:"connected"`
+	LatencyMs          int64  `json:"latency_ms"` // 建立连接并完成认证的耗时
+	Category           string `json:"category,omitempty"`
+	Error              string `json:"error,omitempty"`
+	HostKeyFingerprint string `json:"host_key_fingerprint,omitempty"`
+	TestTime           string `json:"test_time"`
+}
+
+// ConnectivityChange 连通状态变化
+type ConnectivityChange struct {
+	ServerID string             `json:"server_id"`
+	Previous ConnectivityResult `json:"previous"`
+	Current  ConnectivityResult `json:"current"`
+}
+
+// ClassifySSHError 按错误类型给SSH连接失败分类
+func ClassifySSHError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var mismatch *HostKeyMismatchError
+	if errors.As(err, &mismatch) {
+		return FailureHostKey
+	}
+	if errors.Is(err, ErrVaultLocked) {
+		return FailureCredentials
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return FailureTimeout
+		}
+		return FailureDNS
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return FailureRefused
+	}
+	if errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH) {
+		return FailureUnreachable
+	}
+	var netErr net.Error
+	if errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return FailureTimeout
+	}
+
+	// golang.org/x/crypto/ssh 的认证失败没有专门的错误类型，只能按消息判断
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "unable to authenticate"), strings.Contains(message, "no supported methods remain"):
+		return FailureAuth
+	case strings.Contains(message, "私钥"), strings.Contains(message, "ssh-agent"):
+		return FailureCredentials
+	case strings.Contains(message, "i/o timeout"):
+		return FailureTimeout
+	}
+	return FailureOther
+}
+
+// ConnectivityService 服务器连通性检测服务：并发检测、记录延迟和失败分类，可定时在后台重新检测
+type ConnectivityService struct {
+	ssh      *SSHService
+	mutex    sync.Mutex
+	last     map[string]ConnectivityResult
+	schedule scheduler
+	callback func(event string, data interface{})
+}
+
+// NewConnectivityService 创建连通性检测服务实例
+func NewConnectivityService(sshService *SSHService) *ConnectivityService {
+	return &ConnectivityService{
+		ssh:  sshService,
+		last: make(map[string]ConnectivityResult),
+	}
+}
+
+// SetEventCallback 设置事件回调（用于向前端推送检测结果）
+func (s *ConnectivityService) SetEventCallback(callback func(event string, data interface{})) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callback = callback
+}
+
+// emit 发送事件
+func (s *ConnectivityService) emit(event string, data interface{}) {
+	s.mutex.Lock()
+	callback := s.callback
+	s.mutex.Unlock()
+
+	if callback != nil {
+		callback(event, data)
+	}
+}
+
+// Check 检测一台服务器：建立新连接（不使用连接池）并执行一条空命令
+func (s *ConnectivityService) Check(server *ServerData) ConnectivityResult {
+	result := ConnectivityResult{ServerID: server.ServerID}
+
+	start := time.Now()
+	var err error
+	if server.ServerIP == "" {
+		err = fmt.Errorf("服务器IP不能为空")
+	} else {
+		var client *ssh.Client
+		client, result.HostKeyFingerprint, err = s.ssh.DialWithFingerprint(server, connectivityTimeout)
+		result.LatencyMs = time.Since(start).Milliseconds()
+		if err == nil {
+			var session *ssh.Session
+			if session, err = client.NewSession(); err == nil {
+				err = session.Run("true")
+				session.Close()
+			}
+			client.Close()
+		}
+	}
+
+	result.Connected = err == nil
+	if err != nil {
+		result.Category = ClassifySSHError(err)
+		result.Error = err.Error()
+	}
+	result.TestTime = time.Now().Format("2006-01-02 15:04:05")
+	return result
+}
+
+// CheckAll 并发检测多台服务器（parallelism 为0时使用默认并发数），按输入顺序返回结果；
+// 每台服务器检测完成后发送 connectivity_result 事件，状态变化时另外发送 connectivity_changed 事件
+func (s *ConnectivityService) CheckAll(servers []ServerData, parallelism int) []ConnectivityResult {
+	if parallelism <= 0 {
+		parallelism = defaultConnectivityParallelism
+	}
+	if parallelism > maxConnectivityParallelism {
+		parallelism = maxConnectivityParallelism
+	}
+
+	results := make([]ConnectivityResult, len(servers))
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := range servers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = s.Check(&servers[i])
+			s.record(results[i])
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// record 保存最近一次结果并推送事件
+func (s *ConnectivityService) record(result ConnectivityResult) {
+	s.mutex.Lock()
+	previous, known := s.last[result.ServerID]
+	s.last[result.ServerID] = result
+	s.mutex.Unlock()
+
+	s.emit(ConnectivityResultEvent, result)
+	if !known || previous.Connected != result.Connected || previous.Category != result.Category {
+		log.Printf("Connectivity of server %s changed: connected=%v category=%s", result.ServerID, result.Connected, result.Category)
+		s.emit(ConnectivityChangedEvent, ConnectivityChange{ServerID: result.ServerID, Previous: previous, Current: result})
+	}
+}
+
+// Latest 每台服务器最近一次检测结果
+func (s *ConnectivityService) Latest() map[string]ConnectivityResult {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	latest := make(map[string]ConnectivityResult, len(s.last))
+	for serverID, result := range s.last {
+		latest[serverID] = result
+	}
+	return latest
+}
+
+// StartSchedule 按间隔在后台重新检测，load 获取服务器列表，save 保存检测结果；已在运行时按新间隔重新开始
+func (s *ConnectivityService) StartSchedule(intervalSeconds, parallelism int, load func() ([]ServerData, error), save func([]ConnectivityResult) error) ScheduleStatus {
+	intervalSeconds = clampInterval(intervalSeconds, defaultConnectivityInterval, minConnectivityInterval)
+	log.Printf("Connectivity check scheduled every %ds", intervalSeconds)
+	return s.schedule.Start(intervalSeconds, func() {
+		servers, err := load()
+		if err != nil {
+			log.Printf("Connectivity schedule failed to load servers: %v", err)
+			return
+		}
+		if err := save(s.CheckAll(servers, parallelism)); err != nil {
+			log.Printf("Connectivity schedule failed to save statuses: %v", err)
+		}
+	})
+}
+
+// StopSchedule 停止定时检测
+func (s *ConnectivityService) StopSchedule() {
+	if s.schedule.Stop() {
+		log.Printf("Connectivity check schedule stopped")
+	}
+}
+
+// Schedule 定时检测状态
+func (s *ConnectivityService) Schedule() ScheduleStatus {
+	return s.schedule.Status()
+}
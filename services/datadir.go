@@ -0,0 +1,48 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// AppDataDir 获取本地数据目录（可通过 ADSPLAT_DATA_DIR 覆盖），不存在时自动创建
+func AppDataDir(sub ...string) (string, error) {
+	base := os.Getenv("ADSPLAT_DATA_DIR")
+	if base == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			configDir = os.TempDir()
+		}
+		base = filepath.Join(configDir, "adsplat")
+	}
+
+	dir := filepath.Join(append([]string{base}, sub...)...)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免写入中断导致文件损坏
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
@@ -0,0 +1,500 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 远程 SQL 类型（/dbexec 接口的 sql_type 参数）
+const (
+	SQLSelect = "selects"
+	SQLInsert = "insert"
+	SQLUpdate = "update"
+	SQLDelete = "delete"
+)
+
+// 远程数据库错误码
+const (
+	DBErrInvalid      = "invalid"      // 请求无效（地址或SQL为空、类型不支持），未发送请求
+	DBErrBadRequest   = "bad_request"  // 远端拒绝请求（HTTP 400）
+	DBErrUnauthorized = "unauthorized" // 签名校验失败（HTTP 401/403）
+	DBErrNotFound     = "not_found"    // 接口不存在（HTTP 404），通常是项目地址错误或未部署
+	DBErrServer       = "server"       // 远端内部错误（HTTP 5xx 或其他非200状态）
+	DBErrSQL          = "sql"          // 远端返回非200业务码，通常是SQL执行失败
+	DBErrTimeout      = "timeout"      // 请求超时
+	DBErrCanceled     = "canceled"     // 请求被取消
+	DBErrNetwork      = "network"      // 网络错误（域名解析、连接失败等）
+	DBErrResponse     = "response"     // 响应无法解析
+)
+
+// 结果集列类型（按列中的值推断）
+const (
+	DBTypeInt    = "int"
+	DBTypeFloat  = "float"
+	DBTypeString = "string"
+	DBTypeBool   = "bool"
+	DBTypeJSON   = "json"  // 对象或数组
+	DBTypeNull   = "null"  // 全部为 NULL
+	DBTypeMixed  = "mixed" // 不同行的值类型不一致
+)
+
+// 请求参数
+const (
+	defaultDBTimeout = 30 * time.Second
+	maxDBResponse    = 32 * 1024 * 1024
+)
+
+// DBError 远程数据库请求错误
+type DBError struct {
+	Code    string `json:"error_code"`
+	Status  int    `json:"status,omitempty"` // HTTP 状态码，或远端返回的业务码（Code 为 sql 时）
+	Message string `json:"message"`
+	Body    string `json:"body,omitempty"` // 非JSON或非200响应的原始内容
+	Err     error  `json:"-"`
+}
+
+func (e *DBError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("远程数据库请求失败(%s, %d): %s", e.Code, e.Status, e.Message)
+	}
+	return fmt.Sprintf("远程数据库请求失败(%s): %s", e.Code, e.Message)
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// DBErrorCode 错误的远程数据库错误码，不是 DBError 时返回空字符串
+func DBErrorCode(err error) string {
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return dbErr.Code
+	}
+	return ""
+}
+
+// DBResponse /dbexec 接口的响应
+type DBResponse struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// DBColumn 结果集的列
+type DBColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// RowSet 查询结果集，列按远端返回的顺序排列；值为 int64、float64、string、bool、nil 或嵌套的 map/slice
+type RowSet struct {
+	Columns []DBColumn                 `json:"columns"`
+	Rows    [][]interface{}            `json:"rows"`
+	Meta    map[string]json.RawMessage `json:"meta,omitempty"` // data 为对象时 result（results）以外的字段，原样保留
+}
+
+// ColumnIndex 按列名查找列的位置，不存在时返回 -1
+func (r *RowSet) ColumnIndex(name string) int {
+	for i, column := range r.Columns {
+		if column.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Maps 以 列名->值 的形式返回每一行
+func (r *RowSet) Maps() []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(r.Rows))
+	for _, values := range r.Rows {
+		row := make(map[string]interface{}, len(r.Columns))
+		for i, column := range r.Columns {
+			row[column.Name] = values[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// ExecResult 写操作的结果；远端未返回影响行数或自增ID时对应字段为 -1
+type ExecResult struct {
+	RowsAffected int64           `json:"rows_affected"`
+	LastInsertID int64           `json:"last_insert_id"`
+	Data         json.RawMessage `json:"data,omitempty"` // 远端返回的原始 data
+}
+
+// ProjectDBClient 项目数据库客户端：SQL 加密后提交到项目的 /dbexec 接口
+type ProjectDBClient struct {
+	BaseURL    string
+	Timeout    time.Duration // 单次请求超时，0 表示只受 ctx 约束
+	Dialect    string        // 远端数据库方言，用于参数转义，默认 sqlite
+	Strict     bool          // 严格模式：语句中不允许出现字符串或数字字面量，值必须通过参数传入
+	HTTPClient *http.Client
+	encrypt    func(string) (string, error)
+}
+
+// NewProjectDBClient 创建项目数据库客户端，baseURL 为项目的 API 地址
+func NewProjectDBClient(baseURL string, aesService *AesService) *ProjectDBClient {
+	return &ProjectDBClient{
+		BaseURL:    strings.TrimRight(strings.TrimSpace(baseURL), "/"),
+		Timeout:    defaultDBTimeout,
+		Dialect:    DialectSQLite,
+		HTTPClient: http.DefaultClient,
+		encrypt:    aesService.Encrypt,
+	}
+}
+
+// Query 执行查询语句并解码结果集，args 为 ? 占位符的参数或 Named 命名参数
+func (c *ProjectDBClient) Query(ctx context.Context, sql string, args ...interface{}) (*RowSet, error) {
+	sql, err := c.bind(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.Do(ctx, SQLSelect, sql)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := decodeRowSet(response.Data)
+	if err != nil {
+		return nil, &DBError{Code: DBErrResponse, Message: fmt.Sprintf("解析查询结果失败: %v", err), Err: err}
+	}
+	return rows, nil
+}
+
+// Exec 执行写操作（insert、update、delete），args 与 Query 相同
+func (c *ProjectDBClient) Exec(ctx context.Context, sqlType, sql string, args ...interface{}) (*ExecResult, error) {
+	switch sqlType {
+	case SQLInsert, SQLUpdate, SQLDelete:
+	default:
+		return nil, &DBError{Code: DBErrInvalid, Message: fmt.Sprintf("不支持的写操作类型 %q", sqlType)}
+	}
+	sql, err := c.bind(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.Do(ctx, sqlType, sql)
+	if err != nil {
+		return nil, err
+	}
+	return decodeExecResult(response.Data), nil
+}
+
+// bind 按客户端的方言和严格模式填入参数
+func (c *ProjectDBClient) bind(sql string, args []interface{}) (string, error) {
+	bound, err := BindSQL(sql, c.Dialect, c.Strict, args...)
+	if err != nil {
+		return "", &DBError{Code: DBErrInvalid, Message: err.Error(), Err: err}
+	}
+	return bound, nil
+}
+
+// Do 发送一条已完成参数填充的SQL（不经过严格模式检查），返回远端的原始响应；HTTP 状态或业务码不是200时返回 DBError
+func (c *ProjectDBClient) Do(ctx context.Context, sqlType, sql string) (*DBResponse, error) {
+	if c.BaseURL == "" {
+		return nil, &DBError{Code: DBErrInvalid, Message: "项目API地址不能为空"}
+	}
+	if strings.TrimSpace(sql) == "" {
+		return nil, &DBError{Code: DBErrInvalid, Message: "SQL不能为空"}
+	}
+	switch sqlType {
+	case SQLSelect, SQLInsert, SQLUpdate, SQLDelete:
+	default:
+		return nil, &DBError{Code: DBErrInvalid, Message: fmt.Sprintf("不支持的SQL类型 %q", sqlType)}
+	}
+
+	signature, err := c.encrypt(sql)
+	if err != nil {
+		return nil, &DBError{Code: DBErrInvalid, Message: fmt.Sprintf("加密SQL失败: %v", err), Err: err}
+	}
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	form := url.Values{}
+	form.Set("sql_type", sqlType)
+	form.Set("signature", signature)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/dbexec", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, &DBError{Code: DBErrInvalid, Message: err.Error(), Err: err}
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return nil, transportError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDBResponse+1))
+	if err != nil {
+		return nil, transportError(ctx, err)
+	}
+	if len(body) > maxDBResponse {
+		return nil, &DBError{Code: DBErrResponse, Status: resp.StatusCode, Message: fmt.Sprintf("响应超过 %d 字节", maxDBResponse)}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &DBError{
+			Code:    statusErrorCode(resp.StatusCode),
+			Status:  resp.StatusCode,
+			Message: fmt.Sprintf("API请求失败，状态码: %d", resp.StatusCode),
+			Body:    string(body),
+		}
+	}
+	return decodeDBResponse(body)
+}
+
+// decodeDBResponse 解析响应信封；非JSON或没有 code 字段的响应视为成功，整体作为 data
+func decodeDBResponse(body []byte) (*DBResponse, error) {
+	var envelope struct {
+		Code *int            `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Code == nil {
+		data := json.RawMessage(body)
+		if !json.Valid(body) {
+			data, _ = json.Marshal(string(body))
+		}
+		return &DBResponse{Code: http.StatusOK, Msg: "Success", Data: data}, nil
+	}
+
+	if *envelope.Code != http.StatusOK {
+		message := envelope.Msg
+		if message == "" {
+			message = fmt.Sprintf("远端返回错误码 %d", *envelope.Code)
+		}
+		return nil, &DBError{Code: DBErrSQL, Status: *envelope.Code, Message: message, Body: string(body)}
+	}
+	return &DBResponse{Code: *envelope.Code, Msg: envelope.Msg, Data: envelope.Data}, nil
+}
+
+// statusErrorCode 按HTTP状态码分类
+func statusErrorCode(status int) string {
+	switch {
+	case status == http.StatusBadRequest:
+		return DBErrBadRequest
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return DBErrUnauthorized
+	case status == http.StatusNotFound:
+		return DBErrNotFound
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return DBErrTimeout
+	}
+	return DBErrServer
+}
+
+// transportError 按上下文状态和网络错误类型分类请求失败
+func transportError(ctx context.Context, err error) *DBError {
+	code := DBErrNetwork
+	var netErr net.Error
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		code = DBErrCanceled
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		code = DBErrTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		code = DBErrTimeout
+	}
+	return &DBError{Code: code, Message: err.Error(), Err: err}
+}
+
+// decodeRowSet 解码查询结果：data 为行数组，或包含 result（results）行数组的对象，对象的其他字段放入 Meta；
+// 每行为 列名->值 的对象
+func decodeRowSet(data json.RawMessage) (*RowSet, error) {
+	set := &RowSet{Columns: []DBColumn{}, Rows: [][]interface{}{}}
+	rows := bytes.TrimSpace(data)
+	if len(rows) > 0 && rows[0] == '{' {
+		var wrapper map[string]json.RawMessage
+		if err := json.Unmarshal(rows, &wrapper); err != nil {
+			return nil, err
+		}
+		key := "result"
+		if _, ok := wrapper[key]; !ok {
+			key = "results"
+		}
+		rows = wrapper[key]
+		delete(wrapper, key)
+		if len(wrapper) > 0 {
+			set.Meta = wrapper
+		}
+	}
+
+	if len(rows) == 0 || string(rows) == "null" {
+		return set, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(rows))
+	decoder.UseNumber()
+	if err := expectDelim(decoder, '['); err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	for decoder.More() {
+		if err := expectDelim(decoder, '{'); err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", len(set.Rows)+1, err)
+		}
+		row := make([]interface{}, len(set.Columns))
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			name, _ := token.(string)
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+
+			// 列按首次出现的顺序排列，后续行新增的列在之前的行中为 NULL
+			i, ok := index[name]
+			if !ok {
+				i = len(set.Columns)
+				index[name] = i
+				set.Columns = append(set.Columns, DBColumn{Name: name})
+			}
+			for len(row) <= i {
+				row = append(row, nil)
+			}
+			row[i] = typedValue(value)
+		}
+		if err := expectDelim(decoder, '}'); err != nil {
+			return nil, err
+		}
+		set.Rows = append(set.Rows, row)
+	}
+	if err := expectDelim(decoder, ']'); err != nil {
+		return nil, err
+	}
+
+	for r := range set.Rows {
+		for len(set.Rows[r]) < len(set.Columns) {
+			set.Rows[r] = append(set.Rows[r], nil)
+		}
+	}
+	for i := range set.Columns {
+		set.Columns[i].Type = columnType(set.Rows, i)
+	}
+	return set, nil
+}
+
+// expectDelim 读取下一个JSON分隔符并校验
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("应为 %q，实际为 %v", delim, token)
+	}
+	return nil
+}
+
+// typedValue 把 json.Number 转换为 int64 或 float64（包括嵌套的值）
+func typedValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = typedValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = typedValue(item)
+		}
+	}
+	return value
+}
+
+// valueType 值对应的列类型
+func valueType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return DBTypeNull
+	case int64:
+		return DBTypeInt
+	case float64:
+		return DBTypeFloat
+	case string:
+		return DBTypeString
+	case bool:
+		return DBTypeBool
+	}
+	return DBTypeJSON
+}
+
+// columnType 推断列类型：忽略 NULL，整数和小数混合时为 float
+func columnType(rows [][]interface{}, column int) string {
+	result := DBTypeNull
+	for _, row := range rows {
+		current := valueType(row[column])
+		switch {
+		case current == DBTypeNull || current == result:
+		case result == DBTypeNull:
+			result = current
+		case (result == DBTypeInt && current == DBTypeFloat) || (result == DBTypeFloat && current == DBTypeInt):
+			result = DBTypeFloat
+		default:
+			return DBTypeMixed
+		}
+	}
+	return result
+}
+
+// decodeExecResult 从写操作的 data 中读取影响行数和自增ID（兼容常见字段名，包括 D1 的 meta）
+func decodeExecResult(data json.RawMessage) *ExecResult {
+	result := &ExecResult{RowsAffected: -1, LastInsertID: -1, Data: data}
+
+	type counters struct {
+		RowsAffected *int64 `json:"rows_affected"`
+		AffectedRows *int64 `json:"affected_rows"`
+		Changes      *int64 `json:"changes"`
+		LastInsertID *int64 `json:"last_insert_id"`
+		LastRowID    *int64 `json:"last_row_id"`
+	}
+	var fields struct {
+		counters
+		Meta *counters `json:"meta"`
+	}
+	if json.Unmarshal(data, &fields) != nil {
+		return result
+	}
+
+	for _, c := range []*counters{&fields.counters, fields.Meta} {
+		if c == nil {
+			continue
+		}
+		for _, value := range []*int64{c.RowsAffected, c.AffectedRows, c.Changes} {
+			if value != nil && result.RowsAffected < 0 {
+				result.RowsAffected = *value
+			}
+		}
+		for _, value := range []*int64{c.LastInsertID, c.LastRowID} {
+			if value != nil && result.LastInsertID < 0 {
+				result.LastInsertID = *value
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeRowSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		columns int
+		rows    int
+		meta    string // Meta 的 JSON，为空表示没有 Meta
+	}{
+		{"array", `[{"a":1},{"a":2,"b":"x"}]`, 2, 2, ""},
+		{"null", `null`, 0, 0, ""},
+		{"result", `{"result":[{"a":1}]}`, 1, 1, ""},
+		{"results", `{"results":[{"a":1}]}`, 1, 1, ""},
+		{"extra fields", `{"result":[{"a":1}],"total":10,"page":{"size":1}}`, 1, 1, `{"page":{"size":1},"total":10}`},
+		{"result and results", `{"result":[],"results":[{"a":1}]}`, 0, 0, `{"results":[{"a":1}]}`},
+		{"only extra fields", `{"affected":0}`, 0, 0, `{"affected":0}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := decodeRowSet(json.RawMessage(tt.data))
+			if err != nil {
+				t.Fatalf("decodeRowSet: %v", err)
+			}
+			if len(set.Columns) != tt.columns || len(set.Rows) != tt.rows {
+				t.Fatalf("got %d columns, %d rows; want %d, %d", len(set.Columns), len(set.Rows), tt.columns, tt.rows)
+			}
+			meta := ""
+			if set.Meta != nil {
+				data, _ := json.Marshal(set.Meta)
+				meta = string(data)
+			}
+			if meta != tt.meta {
+				t.Fatalf("meta = %s; want %s", meta, tt.meta)
+			}
+		})
+	}
+}
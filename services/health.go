@@ -0,0 +1,208 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 健康检查类型
+const (
+	HealthCheckHTTP = "http"
+	HealthCheckTCP  = "tcp"
+)
+
+// 健康检查默认参数
+const (
+	defaultHealthTimeout  = 10 // 秒
+	defaultHealthRetries  = 3
+	defaultHealthInterval = 5 // 秒
+)
+
+// HealthCheckConfig 项目健康检查配置（保存在 ProjectData.HealthCheck，为空或 Type 为空表示不检查）
+type HealthCheckConfig struct {
+	Type            string `json:"type"`                       // http 或 tcp
+	Target          string `json:"target"`                     // http：api、manage 或完整URL；tcp：api 或 front
+	Path            string `json:"path,omitempty"`             // http 检查时追加到URL后的路径，如 /health
+	ExpectStatus    int    `json:"expect_status,omitempty"`    // 期望的HTTP状态码，0 表示 2xx/3xx 均视为健康
+	TimeoutSeconds  int    `json:"timeout_seconds,omitempty"`  // 单次检查超时
+	Retries         int    `json:"retries,omitempty"`          // 失败后重试次数（部署后服务启动需要时间）
+	IntervalSeconds int    `json:"interval_seconds,omitempty"` // 重试间隔
+	AutoRollback    bool   `json:"auto_rollback,omitempty"`    // 部署后检查失败时从部署的发布版本回滚到上一个版本
+}
+
+// HealthResult 健康检查结果
+type HealthResult struct {
+	ServerID   string `json:"server_id"`
+	ProjectID  string `json:"project_id"`
+	Type       string `json:"type"`
+	Target     string `json:"target"` // 实际检查的URL或端口
+	Healthy    bool   `json:"healthy"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Attempts   int    `json:"attempts"`
+	Error      string `json:"error,omitempty"`
+	CheckedAt  string `json:"checked_at"`
+}
+
+// Enabled 是否配置了健康检查
+func (c *HealthCheckConfig) Enabled() bool {
+	return c != nil && c.Type != ""
+}
+
+// HealthService 项目健康检查服务：HTTP 检查从本机发起，TCP 检查通过SSH在服务器上连接本地端口
+type HealthService struct {
+	ssh *SSHService
+}
+
+// NewHealthService 创建健康检查服务实例
+func NewHealthService(sshService *SSHService) *HealthService {
+	return &HealthService{ssh: sshService}
+}
+
+// httpTarget 解析HTTP检查的URL
+func httpTarget(project ProjectData, config *HealthCheckConfig) (string, error) {
+	base := config.Target
+	switch strings.ToLower(config.Target) {
+	case "", "api":
+		base = project.ProjectAPIURL
+	case "manage":
+		base = project.ProjectManageURL
+	}
+	if base == "" {
+		return "", fmt.Errorf("项目 %s 未配置健康检查地址", project.ProjectID)
+	}
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = "http://" + base
+	}
+	if config.Path != "" {
+		base = strings.TrimRight(base, "/") + "/" + strings.TrimLeft(config.Path, "/")
+	}
+	return base, nil
+}
+
+// tcpTarget 解析TCP检查的端口
+func tcpTarget(project ProjectData, config *HealthCheckConfig) (int, error) {
+	apiPort, frontPort, err := ProjectPorts(project)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(config.Target) {
+	case "", "api":
+		return apiPort, nil
+	case "front":
+		return frontPort, nil
+	}
+	return parsePort(config.Target, apiPort)
+}
+
+// Check 按项目配置执行健康检查，失败时按配置重试
+func (s *HealthService) Check(server *ServerData, project ProjectData) HealthResult {
+	config := project.HealthCheck
+	result := HealthResult{ServerID: server.ServerID, ProjectID: project.ProjectID}
+	if !config.Enabled() {
+		result.Error = "项目未配置健康检查"
+		result.CheckedAt = time.Now().Format("2006-01-02 15:04:05")
+		return result
+	}
+	result.Type = config.Type
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout * time.Second
+	}
+	retries := config.Retries
+	if retries <= 0 {
+		retries = defaultHealthRetries
+	}
+	interval := time.Duration(config.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultHealthInterval * time.Second
+	}
+
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		result.Attempts = attempt
+		start := time.Now()
+		var err error
+		switch config.Type {
+		case HealthCheckHTTP:
+			err = s.checkHTTP(project, config, timeout, &result)
+		case HealthCheckTCP:
+			err = s.checkTCP(server, project, config, timeout, &result)
+		default:
+			err = fmt.Errorf("不支持的健康检查类型: %s", config.Type)
+			retries = 0
+		}
+		result.LatencyMs = time.Since(start).Milliseconds()
+
+		if err == nil {
+			result.Healthy = true
+			result.Error = ""
+			break
+		}
+		result.Error = err.Error()
+		if attempt <= retries {
+			time.Sleep(interval)
+		}
+	}
+
+	result.CheckedAt = time.Now().Format("2006-01-02 15:04:05")
+	log.Printf("Health check for project %s on %s (%s %s): healthy=%v after %d attempt(s) %s",
+		project.ProjectID, server.ServerID, result.Type, result.Target, result.Healthy, result.Attempts, result.Error)
+	return result
+}
+
+// checkHTTP 发送 HTTP GET 并检查状态码
+func (s *HealthService) checkHTTP(project ProjectData, config *HealthCheckConfig, timeout time.Duration, result *HealthResult) error {
+	target, err := httpTarget(project, config)
+	if err != nil {
+		return err
+	}
+	result.Target = target
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(target)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+
+	if config.ExpectStatus != 0 {
+		if resp.StatusCode != config.ExpectStatus {
+			return fmt.Errorf("状态码 %d，期望 %d", resp.StatusCode, config.ExpectStatus)
+		}
+		return nil
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// checkTCP 通过SSH在服务器上连接本地端口
+func (s *HealthService) checkTCP(server *ServerData, project ProjectData, config *HealthCheckConfig, timeout time.Duration, result *HealthResult) error {
+	port, err := tcpTarget(project, config)
+	if err != nil {
+		return err
+	}
+	result.Target = fmt.Sprintf("127.0.0.1:%d", port)
+
+	seconds := int(timeout.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	command := fmt.Sprintf(
+		"if command -v nc >/dev/null 2>&1; then nc -z -w %d 127.0.0.1 %d; else timeout %d bash -c 'exec 3<>/dev/tcp/127.0.0.1/%d'; fi",
+		seconds, port, seconds, port)
+	output, err := s.ssh.Run(server, command)
+	if err != nil {
+		if strings.TrimSpace(output) != "" {
+			return fmt.Errorf("端口 %d 无法连接: %w: %s", port, err, strings.TrimSpace(output))
+		}
+		return fmt.Errorf("端口 %d 无法连接: %w", port, err)
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxSnapshots 最多保留的快照数量，超出后循环覆盖最旧的快照
+const maxSnapshots = 50
+
+// SnapshotMeta 快照元信息
+type SnapshotMeta struct {
+	Version     int64         `json:"version"`
+	Actor       string        `json:"actor"`
+	CreatedAt   string        `json:"created_at"`
+	ServerCount int           `json:"server_count"`
+	Diff        InventoryDiff `json:"diff"`
+}
+
+// Snapshot 库存快照
+type Snapshot struct {
+	SnapshotMeta
+	Servers []ServerData `json:"servers"`
+}
+
+// historyIndex 快照索引
+type historyIndex struct {
+	Latest    int64          `json:"latest"`
+	Snapshots []SnapshotMeta `json:"snapshots"`
+	Redacted  bool           `json:"redacted,omitempty"` // 旧快照中的明文凭据已清除
+}
+
+// InventoryDiff 两个版本服务器列表之间的差异
+type InventoryDiff struct {
+	AddedServers   []string       `json:"added_servers,omitempty"`
+	RemovedServers []string       `json:"removed_servers,omitempty"`
+	ChangedServers []ServerChange `json:"changed_servers,omitempty"`
+}
+
+// ServerChange 单台服务器的变更
+type ServerChange struct {
+	ServerID        string   `json:"server_id"`
+	ChangedFields   []string `json:"changed_fields,omitempty"`
+	AddedProjects   []string `json:"added_projects,omitempty"`
+	RemovedProjects []string `json:"removed_projects,omitempty"`
+	ChangedProjects []string `json:"changed_projects,omitempty"`
+}
+
+// Empty 是否没有任何差异
+func (d InventoryDiff) Empty() bool {
+	return len(d.AddedServers) == 0 && len(d.RemovedServers) == 0 && len(d.ChangedServers) == 0
+}
+
+// HistoryService 库存版本历史服务，快照与索引保存在同一存储后端的独立key中
+type HistoryService struct {
+	store Store
+	mutex sync.Mutex
+}
+
+// NewHistoryService 创建版本历史服务实例
+func NewHistoryService(store Store) *HistoryService {
+	return &HistoryService{store: store}
+}
+
+// indexKey 快照索引的key
+func (s *HistoryService) indexKey(key string) string {
+	return key + "_history"
+}
+
+// snapshotKey 快照的key（按版本号循环使用槽位）
+func (s *HistoryService) snapshotKey(key string, version int64) string {
+	return fmt.Sprintf("%s_history_%d", key, version%maxSnapshots)
+}
+
+// loadIndex 读取快照索引
+func (s *HistoryService) loadIndex(key, authorization string) (*historyIndex, error) {
+	resp, err := s.store.Get(s.indexKey(key), authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &historyIndex{}
+	if resp.Code == 200 && resp.Data != nil && resp.Data.Value != "" {
+		if err := json.Unmarshal([]byte(resp.Data.Value), index); err != nil {
+			return nil, fmt.Errorf("解析历史索引失败: %v", err)
+		}
+	}
+	return index, nil
+}
+
+// List 列出快照（按版本倒序）
+func (s *HistoryService) List(key, authorization string) ([]SnapshotMeta, error) {
+	index, err := s.loadIndex(key, authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := append([]SnapshotMeta(nil), index.Snapshots...)
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Version > snapshots[j].Version
+	})
+	return snapshots, nil
+}
+
+// Get 读取指定版本的快照
+func (s *HistoryService) Get(key string, version int64, authorization string) (*Snapshot, error) {
+	resp, err := s.store.Get(s.snapshotKey(key, version), authorization)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 200 || resp.Data == nil {
+		return nil, fmt.Errorf("版本 %d 不存在", version)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal([]byte(resp.Data.Value), &snapshot); err != nil {
+		return nil, fmt.Errorf("解析快照失败: %v", err)
+	}
+	// 槽位已被更新的版本覆盖
+	if snapshot.Version != version {
+		return nil, fmt.Errorf("版本 %d 已过期被清理", version)
+	}
+	snapshot.Servers = RedactSecrets(snapshot.Servers)
+	return &snapshot, nil
+}
+
+// Record 记录一次保存的快照，与上一版本无差异时跳过；凭据只记录是否存在，不记录内容
+func (s *HistoryService) Record(key, authorization string, servers []ServerData) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	index, err := s.loadIndex(key, authorization)
+	if err != nil {
+		return err
+	}
+	if !index.Redacted {
+		s.scrub(key, index, authorization)
+	}
+	servers = RedactSecrets(servers)
+
+	var previous []ServerData
+	if index.Latest > 0 {
+		if last, err := s.Get(key, index.Latest, authorization); err == nil {
+			previous = last.Servers
+		} else {
+			log.Printf("Failed to load previous snapshot %d: %v", index.Latest, err)
+		}
+	}
+
+	diff := DiffServers(previous, servers)
+	if index.Latest > 0 && diff.Empty() {
+		if !index.Redacted {
+			index.Redacted = true
+			return s.saveIndex(key, index, authorization)
+		}
+		return nil
+	}
+
+	snapshot := Snapshot{
+		SnapshotMeta: SnapshotMeta{
+			Version:     index.Latest + 1,
+			Actor:       currentActor(),
+			CreatedAt:   time.Now().Format("2006-01-02 15:04:05"),
+			ServerCount: len(servers),
+			Diff:        diff,
+		},
+		Servers: servers,
+	}
+
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Put(s.snapshotKey(key, snapshot.Version), string(snapshotJSON), authorization); err != nil {
+		return fmt.Errorf("保存快照失败: %v", err)
+	}
+
+	index.Latest = snapshot.Version
+	index.Redacted = true
+	index.Snapshots = append(index.Snapshots, snapshot.SnapshotMeta)
+	if len(index.Snapshots) > maxSnapshots {
+		index.Snapshots = index.Snapshots[len(index.Snapshots)-maxSnapshots:]
+	}
+	if err := s.saveIndex(key, index, authorization); err != nil {
+		return err
+	}
+
+	log.Printf("Recorded inventory snapshot version %d by %s", snapshot.Version, snapshot.Actor)
+	return nil
+}
+
+// saveIndex 保存快照索引
+func (s *HistoryService) saveIndex(key string, index *historyIndex, authorization string) error {
+	indexJSON, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Put(s.indexKey(key), string(indexJSON), authorization); err != nil {
+		return fmt.Errorf("保存历史索引失败: %v", err)
+	}
+	return nil
+}
+
+// scrub 重写旧版本记录的快照，清除其中的明文凭据（失败的快照下次记录时再处理）
+func (s *HistoryService) scrub(key string, index *historyIndex, authorization string) {
+	for _, meta := range index.Snapshots {
+		snapshot, err := s.Get(key, meta.Version, authorization)
+		if err != nil {
+			continue
+		}
+		snapshotJSON, err := json.Marshal(snapshot)
+		if err != nil {
+			continue
+		}
+		if err := s.store.Put(s.snapshotKey(key, meta.Version), string(snapshotJSON), authorization); err != nil {
+			log.Printf("Failed to scrub snapshot %d: %v", meta.Version, err)
+			return
+		}
+	}
+	index.Redacted = true
+}
+
+// RedactSecrets 复制服务器列表并把非空凭据替换为掩码（返回给前端和记录快照时使用）
+func RedactSecrets(servers []ServerData) []ServerData {
+	redacted := cloneServers(servers)
+	for i := range redacted {
+		for _, name := range secretFieldNames {
+			if field := serverSecretField(&redacted[i], name); *field != "" {
+				*field = MaskedSecret
+			}
+		}
+	}
+	return redacted
+}
+
+// DiffServers 比较两个版本的服务器列表
+func DiffServers(from, to []ServerData) InventoryDiff {
+	diff := InventoryDiff{}
+	fromIndex := indexServers(from)
+	toIndex := indexServers(to)
+
+	for _, server := range from {
+		if toIndex[server.ServerID] == nil {
+			diff.RemovedServers = append(diff.RemovedServers, server.ServerID)
+		}
+	}
+
+	for _, server := range to {
+		old := fromIndex[server.ServerID]
+		if old == nil {
+			diff.AddedServers = append(diff.AddedServers, server.ServerID)
+			continue
+		}
+		if change := diffServer(*old, server); change != nil {
+			diff.ChangedServers = append(diff.ChangedServers, *change)
+		}
+	}
+
+	return diff
+}
+
+// diffServer 比较单台服务器，无变化时返回 nil（只记录字段名，不记录值，避免泄露密码）
+// 连接状态等运行时字段不参与比较，定时连通性测试不会产生新快照
+func diffServer(from, to ServerData) *ServerChange {
+	change := &ServerChange{ServerID: to.ServerID}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"server_name", from.ServerName, to.ServerName},
+		{"server_ip", from.ServerIP, to.ServerIP},
+		{"server_port", from.ServerPort, to.ServerPort},
+		{"server_user", from.ServerUser, to.ServerUser},
+		{"server_password", from.ServerPassword, to.ServerPassword},
+		{"auth_type", from.AuthType, to.AuthType},
+		{"private_key", from.PrivateKey, to.PrivateKey},
+		{"key_passphrase", from.KeyPassphrase, to.KeyPassphrase},
+		{"default_path", from.DefaultPath, to.DefaultPath},
+		{"log_source", from.LogSource, to.LogSource},
+		{"host_key_fingerprint", from.HostKeyFingerprint, to.HostKeyFingerprint},
+		{"jump_server_id", from.JumpServerID, to.JumpServerID},
+	}
+	for _, field := range fields {
+		if field.from != field.to {
+			change.ChangedFields = append(change.ChangedFields, field.name)
+		}
+	}
+	if !sameJSON(from.JumpHost, to.JumpHost) {
+		change.ChangedFields = append(change.ChangedFields, "jump_host")
+	}
+
+	fromProjects := indexProjects(from.ProjectList)
+	toProjects := indexProjects(to.ProjectList)
+	for _, project := range from.ProjectList {
+		if toProjects[project.ProjectID] == nil {
+			change.RemovedProjects = append(change.RemovedProjects, project.ProjectID)
+		}
+	}
+	for _, project := range to.ProjectList {
+		old := fromProjects[project.ProjectID]
+		if old == nil {
+			change.AddedProjects = append(change.AddedProjects, project.ProjectID)
+		} else if !sameJSON(*old, project) {
+			change.ChangedProjects = append(change.ChangedProjects, project.ProjectID)
+		}
+	}
+
+	if len(change.ChangedFields) == 0 && len(change.AddedProjects) == 0 &&
+		len(change.RemovedProjects) == 0 && len(change.ChangedProjects) == 0 {
+		return nil
+	}
+	return change
+}
+
+// currentActor 当前操作者（系统用户@主机名）
+func currentActor() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		name = fmt.Sprintf("%s@%s", name, host)
+	}
+	return name
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHistoryRecordRedactsSecrets(t *testing.T) {
+	tests := []struct {
+		name   string
+		server ServerData
+		want   ServerData
+	}{
+		{"plain password",
+			ServerData{ServerID: "a", ServerPassword: "hunter2"},
+			ServerData{ServerID: "a", ServerPassword: MaskedSecret}},
+		{"private key",
+			ServerData{ServerID: "a", PrivateKey: "-----BEGIN KEY-----", KeyPassphrase: "pass"},
+			ServerData{ServerID: "a", PrivateKey: MaskedSecret, KeyPassphrase: MaskedSecret}},
+		{"masked", ServerData{ServerID: "a", ServerPassword: MaskedSecret}, ServerData{ServerID: "a", ServerPassword: MaskedSecret}},
+		{"no credentials", ServerData{ServerID: "a"}, ServerData{ServerID: "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			history := NewHistoryService(store)
+			if err := history.Record(testNamespace, "", []ServerData{tt.server}); err != nil {
+				t.Fatal(err)
+			}
+
+			resp, _ := store.Get(history.snapshotKey(testNamespace, 1), "")
+			for _, secret := range []string{"hunter2", "BEGIN KEY", `"pass"`} {
+				if strings.Contains(resp.Data.Value, secret) {
+					t.Fatalf("snapshot contains %q: %s", secret, resp.Data.Value)
+				}
+			}
+			snapshot, err := history.Get(testNamespace, 1, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := snapshot.Servers[0]; !sameJSON(got, tt.want) {
+				t.Fatalf("snapshot server = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryScrubsLegacySnapshots(t *testing.T) {
+	store := NewMemoryStore()
+	history := NewHistoryService(store)
+
+	// 旧版本记录的快照和索引，凭据为明文
+	legacy := Snapshot{
+		SnapshotMeta: SnapshotMeta{Version: 1},
+		Servers:      []ServerData{{ServerID: "a", ServerPassword: "hunter2"}},
+	}
+	data, _ := json.Marshal(legacy)
+	store.Put(history.snapshotKey(testNamespace, 1), string(data), "")
+	index, _ := json.Marshal(historyIndex{Latest: 1, Snapshots: []SnapshotMeta{legacy.SnapshotMeta}})
+	store.Put(history.indexKey(testNamespace), string(index), "")
+
+	if err := history.Record(testNamespace, "", []ServerData{{ServerID: "a", ServerPassword: "hunter2", ServerName: "renamed"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for version := int64(1); version <= 2; version++ {
+		resp, _ := store.Get(history.snapshotKey(testNamespace, version), "")
+		if strings.Contains(resp.Data.Value, "hunter2") {
+			t.Errorf("snapshot %d still contains the password: %s", version, resp.Data.Value)
+		}
+	}
+	if snapshot, _ := history.Get(testNamespace, 2, ""); snapshot.Diff.ChangedServers[0].ChangedFields[0] != "server_name" {
+		t.Errorf("diff = %+v; want only server_name", snapshot.Diff)
+	}
+}
+
+func TestRestoreKeepsCurrentCredentials(t *testing.T) {
+	store := NewMemoryStore()
+	servers := testServers()
+	servers[1].ServerPassword = "old"
+	seedServers(t, store, servers)
+	service := NewJsonServiceWithStore(store)
+	service.LoadJsonFile("", testNamespace)
+	if err := service.SetServerLogSource("b", "/var/log/b", "", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+
+	// 凭据在记录快照之后更换
+	current, _ := service.LoadJsonFile("", testNamespace)
+	current[1].ServerPassword = "new"
+	data, _ := json.Marshal(current)
+	store.Put(testNamespace, string(data), "")
+
+	if _, err := service.RestoreSnapshot(1, "", testNamespace); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	restored, _ := service.LoadJsonFile("", testNamespace)
+	if restored[1].LogSource != "" {
+		t.Errorf("log source = %q; want restored to empty", restored[1].LogSource)
+	}
+	resp, _ := store.Get(testNamespace, "")
+	if !strings.Contains(resp.Data.Value, `"new"`) || strings.Contains(resp.Data.Value, MaskedSecret) {
+		t.Errorf("stored data after restore = %s; want current password kept", resp.Data.Value)
+	}
+}
+
+func TestRestoreDeletedServerReportsMissingCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		service func(t *testing.T) (*JsonService, *MemoryStore)
+	}{
+		{"vault", vaultServers},
+		{"legacy plaintext", func(t *testing.T) (*JsonService, *MemoryStore) {
+			store := NewMemoryStore()
+			servers := testServers()
+			servers[1].ServerPassword = "hunter2"
+			seedServers(t, store, servers)
+			service := NewJsonServiceWithStore(store)
+			service.LoadJsonFile("", testNamespace)
+			return service, store
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := tt.service(t)
+			if err := service.SetServerLogSource("a", "/var/log/a", "", testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			snapshots, _ := service.ListSnapshots("", testNamespace)
+			before := snapshots[0].Version
+			for _, snapshot := range snapshots {
+				before = max(before, snapshot.Version)
+			}
+			if err := service.DeleteServer("b", "", testNamespace); err != nil {
+				t.Fatal(err)
+			}
+
+			missing, err := service.RestoreSnapshot(before, "", testNamespace)
+			if err != nil {
+				t.Fatalf("RestoreSnapshot: %v", err)
+			}
+			if strings.Join(missing, ",") != "b" {
+				t.Fatalf("missing credentials = %v; want [b]", missing)
+			}
+			servers, _ := service.LoadJsonFile("", testNamespace)
+			if len(servers) != 2 || servers[1].ServerID != "b" || servers[1].ServerPassword != "" {
+				t.Fatalf("servers after restore = %+v", servers)
+			}
+		})
+	}
+}
+
+func TestConnectivityDoesNotRecordSnapshots(t *testing.T) {
+	store := NewMemoryStore()
+	seedServers(t, store, testServers())
+	service := NewJsonServiceWithStore(store)
+	service.LoadJsonFile("", testNamespace)
+	if err := service.SetServerLogSource("a", "/var/log/a", "", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := service.ListSnapshots("", testNamespace)
+
+	for _, connected := range []bool{false, true, false, true} {
+		result := ConnectivityResult{ServerID: "a", Connected: connected, TestTime: "2026-01-01 00:00:00"}
+		if err := service.UpdateConnectivity([]ConnectivityResult{result}, "", testNamespace); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after, _ := service.ListSnapshots("", testNamespace)
+	if len(after) != len(before) {
+		t.Fatalf("snapshots = %d after connectivity updates; want %d", len(after), len(before))
+	}
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 任务状态
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// 任务事件名称（通过 Wails 事件发送到前端）
+const (
+	JobOutputEvent = "command_output"
+	JobStatusEvent = "command_status"
+)
+
+// maxFinishedJobs 保留的已结束任务数量
+const maxFinishedJobs = 100
+
+// maxJobOutput 每个任务保留的输出字节数
+const maxJobOutput = 1024 * 1024
+
+// maxJobLine 单行输出保留的字节数，超出部分丢弃
+const maxJobLine = 64 * 1024
+
+// jobLineTruncated 超长行截断后追加的提示
+const jobLineTruncated = "…（行过长，已截断）"
+
+// jobPIDMarker 远程命令启动时输出的进程组号标记，用于取消时结束整个进程组
+const jobPIDMarker = "__adsplat_job_pid__:"
+
+// ErrJobCanceled 任务已被取消
+var ErrJobCanceled = errors.New("任务已取消")
+
+// JobStatus 远程命令任务状态
+type JobStatus struct {
+	JobID      string `json:"job_id"`
+	ServerID   string `json:"server_id"`
+	ProjectID  string `json:"project_id,omitempty"`
+	Action     string `json:"action"`
+	Command    string `json:"command"`
+	Status     string `json:"status"`
+	ExitCode   int    `json:"exit_code"` // -1 表示未获得退出码（连接中断或被取消）
+	Error      string `json:"error,omitempty"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at,omitempty"`
+}
+
+// JobOutput 任务输出的一行
+type JobOutput struct {
+	JobID  string `json:"job_id"`
+	Stream string `json:"stream"` // "stdout" 或 "stderr"
+	Line   string `json:"line"`
+	Time   string `json:"time"`
+}
+
+// job 运行中的任务
+type job struct {
+	status    JobStatus
+	output    strings.Builder
+	pid       string
+	session   *ssh.Session
+	server    *ServerData
+	canceled  bool
+	result    interface{}
+	hasResult bool
+}
+
+// JobService 远程命令任务服务，逐行推送输出并支持取消
+type JobService struct {
+	ssh      *SSHService
+	mutex    sync.Mutex
+	jobs     map[string]*job
+	finished []string
+	callback func(event string, data interface{})
+}
+
+// NewJobService 创建任务服务实例
+func NewJobService(sshService *SSHService) *JobService {
+	return &JobService{
+		ssh:  sshService,
+		jobs: make(map[string]*job),
+	}
+}
+
+// SetEventCallback 设置事件回调（用于向前端推送输出和状态）
+func (s *JobService) SetEventCallback(callback func(event string, data interface{})) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callback = callback
+}
+
+// emit 发送事件
+func (s *JobService) emit(event string, data interface{}) {
+	s.mutex.Lock()
+	callback := s.callback
+	s.mutex.Unlock()
+
+	if callback != nil {
+		callback(event, data)
+	}
+}
+
+// newID 生成带前缀的随机ID（任务、终端等）
+func newID(prefix string) string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+	}
+	return prefix + "-" + hex.EncodeToString(buf)
+}
+
+// Start 登记新任务并返回任务ID，随后调用 Run 执行
+func (s *JobService) Start(server *ServerData, projectID, action, command string) string {
+	j := &job{
+		status: JobStatus{
+			JobID:     newID("job"),
+			ServerID:  server.ServerID,
+			ProjectID: projectID,
+			Action:    action,
+			Command:   command,
+			Status:    JobRunning,
+			ExitCode:  -1,
+			StartedAt: time.Now().Format("2006-01-02 15:04:05"),
+		},
+		server: server,
+	}
+
+	s.mutex.Lock()
+	s.jobs[j.status.JobID] = j
+	s.mutex.Unlock()
+
+	s.emit(JobStatusEvent, j.status)
+	return j.status.JobID
+}
+
+// Run 在服务器上执行已登记的任务，逐行推送输出，返回最终状态和完整输出
+func (s *JobService) Run(jobID string) (JobStatus, string, error) {
+	s.mutex.Lock()
+	j := s.jobs[jobID]
+	s.mutex.Unlock()
+	if j == nil {
+		return JobStatus{}, "", fmt.Errorf("任务 %s 不存在", jobID)
+	}
+
+	err := s.execute(j)
+	return s.finish(j, err)
+}
+
+// execute 执行远程命令
+func (s *JobService) execute(j *job) error {
+	session, release, err := s.ssh.Session(j.server)
+	if err != nil {
+		return fmt.Errorf("SSH连接失败: %w", err)
+	}
+	defer release()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	if j.canceled {
+		s.mutex.Unlock()
+		return ErrJobCanceled
+	}
+	j.session = session
+	s.mutex.Unlock()
+
+	// 命令通过 setsid 在新的进程组中运行，先输出进程组号，取消时结束包括孙进程在内的整个进程组
+	wrapped := fmt.Sprintf(`setsid "${SHELL:-/bin/sh}" -c %s & echo %s$!; wait $!`, ShellQuote(j.status.Command), jobPIDMarker)
+	if err := session.Start(wrapped); err != nil {
+		return fmt.Errorf("启动命令失败: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go s.readLines(j, "stdout", stdout, &wg)
+	go s.readLines(j, "stderr", stderr, &wg)
+	wg.Wait()
+
+	return session.Wait()
+}
+
+// readLines 逐行读取输出并推送；超长的行截断，读取出错时记录错误并继续丢弃剩余输出，
+// 避免管道不再被读取导致远程命令阻塞、任务无法结束
+func (s *JobService) readLines(j *job, stream string, reader io.Reader, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	buffered := bufio.NewReaderSize(reader, 64*1024)
+	for {
+		line, err := readJobLine(buffered)
+		if err == nil || line != "" {
+			s.addLine(j, stream, line)
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			s.addLine(j, stream, fmt.Sprintf("读取%s失败: %v", stream, err))
+			io.Copy(io.Discard, buffered)
+			return
+		}
+	}
+}
+
+// readJobLine 读取一行输出（不含换行符），超过 maxJobLine 的部分丢弃并追加截断提示
+func readJobLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	truncated := false
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if room := maxJobLine - len(line); len(chunk) > room {
+			chunk = chunk[:room]
+			truncated = true
+		}
+		line = append(line, chunk...)
+		if err != nil || !isPrefix {
+			if truncated {
+				line = append([]byte(strings.ToValidUTF8(string(line), "")), jobLineTruncated...)
+			}
+			return string(line), err
+		}
+	}
+}
+
+// addLine 记录一行输出并推送
+func (s *JobService) addLine(j *job, stream, line string) {
+	s.mutex.Lock()
+	if j.pid == "" && stream == "stdout" && strings.HasPrefix(line, jobPIDMarker) {
+		j.pid = strings.TrimPrefix(line, jobPIDMarker)
+		s.mutex.Unlock()
+		return
+	}
+	if j.output.Len() < maxJobOutput {
+		j.output.WriteString(line)
+		j.output.WriteString("\n")
+	}
+	s.mutex.Unlock()
+
+	s.emit(JobOutputEvent, JobOutput{
+		JobID:  j.status.JobID,
+		Stream: stream,
+		Line:   line,
+		Time:   time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// finish 记录任务结果并推送最终状态
+func (s *JobService) finish(j *job, err error) (JobStatus, string, error) {
+	s.mutex.Lock()
+	j.session = nil
+	j.status.FinishedAt = time.Now().Format("2006-01-02 15:04:05")
+
+	var exitErr *ssh.ExitError
+	switch {
+	case j.canceled:
+		j.status.Status = JobCanceled
+		err = ErrJobCanceled
+	case err == nil:
+		j.status.Status = JobSucceeded
+		j.status.ExitCode = 0
+	case errors.As(err, &exitErr):
+		j.status.Status = JobFailed
+		j.status.ExitCode = exitErr.ExitStatus()
+		err = fmt.Errorf("命令执行失败，退出码: %d", j.status.ExitCode)
+	default:
+		j.status.Status = JobFailed
+	}
+	if err != nil {
+		j.status.Error = err.Error()
+	}
+
+	s.finished = append(s.finished, j.status.JobID)
+	if len(s.finished) > maxFinishedJobs {
+		delete(s.jobs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+
+	status := j.status
+	output := j.output.String()
+	s.mutex.Unlock()
+
+	log.Printf("Job %s (%s %s on %s) finished: %s, exit code %d", status.JobID, status.Action, status.ProjectID, status.ServerID, status.Status, status.ExitCode)
+	s.emit(JobStatusEvent, status)
+	return status, output, err
+}
+
+// Cancel 取消运行中的任务：向远程进程发送 SIGTERM 并关闭会话
+func (s *JobService) Cancel(jobID string) error {
+	s.mutex.Lock()
+	j := s.jobs[jobID]
+	if j == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("任务 %s 不存在", jobID)
+	}
+	if j.status.Status != JobRunning {
+		s.mutex.Unlock()
+		return fmt.Errorf("任务 %s 已结束", jobID)
+	}
+	j.canceled = true
+	session := j.session
+	pid := j.pid
+	server := j.server
+	s.mutex.Unlock()
+
+	log.Printf("Canceling job %s (pid %s)", jobID, pid)
+
+	// 部分SSH服务端不支持 signal 请求，另开会话按进程组结束命令
+	if _, err := strconv.Atoi(pid); err == nil {
+		kill := fmt.Sprintf("kill -TERM -- -%s", pid)
+		if killSession, release, err := s.ssh.Session(server); err == nil {
+			killSession.Run(kill)
+			release()
+		} else {
+			log.Printf("Failed to open session to kill job %s: %v", jobID, err)
+		}
+	}
+	if session != nil {
+		session.Signal(ssh.SIGTERM)
+		session.Close()
+	}
+	return nil
+}
+
+// SetResult 保存任务结束后由调用方补充的最终结果（如部署后的健康检查）
+func (s *JobService) SetResult(jobID string, result interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if j := s.jobs[jobID]; j != nil {
+		j.result = result
+		j.hasResult = true
+	}
+}
+
+// Result 查询 SetResult 保存的最终结果，尚未保存时 ok 为 false
+func (s *JobService) Result(jobID string) (result interface{}, ok bool, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j := s.jobs[jobID]
+	if j == nil {
+		return nil, false, fmt.Errorf("任务 %s 不存在", jobID)
+	}
+	return j.result, j.hasResult, nil
+}
+
+// Status 查询任务状态和已输出的内容
+func (s *JobService) Status(jobID string) (JobStatus, string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j := s.jobs[jobID]
+	if j == nil {
+		return JobStatus{}, "", fmt.Errorf("任务 %s 不存在", jobID)
+	}
+	return j.status, j.output.String(), nil
+}
+
+// List 列出任务（运行中的在前）
+func (s *JobService) List() []JobStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var running, done []JobStatus
+	for _, j := range s.jobs {
+		if j.status.Status == JobRunning {
+			running = append(running, j.status)
+		}
+	}
+	for i := len(s.finished) - 1; i >= 0; i-- {
+		if j := s.jobs[s.finished[i]]; j != nil {
+			done = append(done, j.status)
+		}
+	}
+	return append(running, done...)
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingReader 先返回 data，再返回读取错误
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestJobReadLines(t *testing.T) {
+	long := strings.Repeat("x", 3*1024*1024)
+	tests := []struct {
+		name   string
+		reader func() io.Reader
+		want   []string
+		pid    string
+	}{
+		{"lines", func() io.Reader { return strings.NewReader("a\r\n\nb") }, []string{"a", "", "b"}, ""},
+		{"pid marker", func() io.Reader { return strings.NewReader(jobPIDMarker + "123\nout\n") }, []string{"out"}, "123"},
+		{"long line", func() io.Reader {
+			// 通过管道写入，读取方停止读取时写入方会一直阻塞
+			reader, writer := io.Pipe()
+			go func() {
+				io.WriteString(writer, "head\n"+long+"\ntail\n")
+				writer.Close()
+			}()
+			return reader
+		}, []string{"head", strings.Repeat("x", maxJobLine) + jobLineTruncated, "tail"}, ""},
+		{"read error", func() io.Reader {
+			return &failingReader{data: "partial", err: errors.New("connection reset")}
+		}, []string{"partial", "读取stdout失败: connection reset"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewJobService(nil)
+			var lines []string
+			service.SetEventCallback(func(event string, data interface{}) {
+				lines = append(lines, data.(JobOutput).Line)
+			})
+			j := &job{}
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			done := make(chan struct{})
+			go func() {
+				service.readLines(j, "stdout", tt.reader(), &wg)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("readLines did not finish")
+			}
+
+			if strings.Join(lines, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("lines = %.200q; want %.200q", lines, tt.want)
+			}
+			if j.pid != tt.pid {
+				t.Fatalf("pid = %q; want %q", j.pid, tt.pid)
+			}
+		})
+	}
+}
@@ -2,64 +2,119 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"sync"
+
+	"adsplat/utils"
 )
 
 // ServerData 服务器数据结构
 type ServerData struct {
-	ServerID         string        `json:"server_id"`
-	ServerName       string        `json:"server_name"`
-	ServerIP         string        `json:"server_ip"`
-	ServerPort       string        `json:"server_port"`
-	ServerUser       string        `json:"server_user"`
-	ServerPassword   string        `json:"server_password"`
-	DefaultPath      string        `json:"default_path"`
-	ProjectList      []ProjectData `json:"project_list"`
-	ConnectionStatus string        `json:"connection_status,omitempty"` // "connected", "disconnected", "unknown"
-	LastTestTime     string        `json:"last_test_time,omitempty"`
-	LastTestResult   string        `json:"last_test_result,omitempty"`
+	ServerID           string        `json:"server_id"`
+	ServerName         string        `json:"server_name"`
+	ServerIP           string        `json:"server_ip"`
+	ServerPort         string        `json:"server_port"`
+	ServerUser         string        `json:"server_user"`
+	ServerPassword     string        `json:"server_password"`
+	AuthType           string        `json:"auth_type,omitempty"`      // "password"（默认）, "key", "agent", "keyboard-interactive"
+	PrivateKey         string        `json:"private_key,omitempty"`    // 私钥内容或本地私钥文件路径（保存在保险库中）
+	KeyPassphrase      string        `json:"key_passphrase,omitempty"` // 私钥密码（保存在保险库中）
+	DefaultPath        string        `json:"default_path"`
+	LogSource          string        `json:"log_source,omitempty"` // 项目日志位置模板，为空时使用默认位置（见 ProjectLogService）
+	ProjectList        []ProjectData `json:"project_list"`
+	ConnectionStatus   string        `json:"connection_status,omitempty"` // "connected", "disconnected", "unknown"
+	LastTestTime       string        `json:"last_test_time,omitempty"`
+	LastTestResult     string        `json:"last_test_result,omitempty"`
+	HostKeyFingerprint string        `json:"host_key_fingerprint,omitempty"` // 首次测试连接时记录的主机密钥指纹（SHA256）
+	JumpServerID       string        `json:"jump_server_id,omitempty"`       // 跳板机：引用服务器列表中的服务器
+	JumpHost           *JumpHost     `json:"jump_host,omitempty"`            // 跳板机：临时主机（与 JumpServerID 二选一）
+	Revision           int64         `json:"revision,omitempty"`             // 每次修改递增，用于乐观并发检查
+
+	namespace string // 加载时所在的KV命名空间，连接时据此查找保险库中的凭据
 }
 
+// maxMutateAttempts 条件写入失败（保存瞬间数据被修改）时重新合并的次数
+const maxMutateAttempts = 3
+
 // ProjectData 项目数据结构
 type ProjectData struct {
-	ProjectID        string `json:"project_id"`
-	ProjectName      string `json:"project_name"`
-	ProjectManageURL string `json:"project_manage_url"`
-	ProjectAPIURL    string `json:"project_api_url"`
-	APIPort          string `json:"api_port"`
-	FrontPort        string `json:"front_port"`
+	ProjectID        string             `json:"project_id"`
+	ProjectName      string             `json:"project_name"`
+	ProjectManageURL string             `json:"project_manage_url"`
+	ProjectAPIURL    string             `json:"project_api_url"`
+	APIPort          string             `json:"api_port"`
+	FrontPort        string             `json:"front_port"`
+	HealthCheck      *HealthCheckConfig `json:"health_check,omitempty"`
+	Revision         int64              `json:"revision,omitempty"` // 每次修改递增，编辑项目时作为期望版本号
 }
 
 // 移除固定的KV_KEY，改为使用传入的参数
 
 // JsonService JSON数据管理服务
 type JsonService struct {
-	kvService *KvService
+	store   Store
+	history *HistoryService
+	vault   *VaultService
+	onLoad  func(servers []ServerData)
+	locks   sync.Map // clientJson -> *sync.Mutex，串行化同一进程内对同一份数据的修改
 }
 
-// NewJsonService 创建JSON服务实例
+// NewJsonService 创建JSON服务实例（存储后端由环境变量选择，ADSPLAT_VAULT_PASSPHRASE 可在启动时解锁保险库）
 func NewJsonService() *JsonService {
+	service := NewJsonServiceWithStore(NewStoreFromEnv())
+	if passphrase := os.Getenv("ADSPLAT_VAULT_PASSPHRASE"); passphrase != "" {
+		service.vault.Unlock(passphrase)
+	}
+	return service
+}
+
+// NewJsonServiceWithStore 使用指定的存储后端创建JSON服务实例
+func NewJsonServiceWithStore(store Store) *JsonService {
 	return &JsonService{
-		kvService: NewKvService(),
+		store:   store,
+		history: NewHistoryService(store),
+		vault:   NewVaultService(),
 	}
 }
 
+// Vault 凭据保险库
+func (s *JsonService) Vault() *VaultService {
+	return s.vault
+}
+
+// SetLoadCallback 设置加载服务器列表后的回调（用于SSH服务按ID解析跳板机）
+func (s *JsonService) SetLoadCallback(callback func(servers []ServerData)) {
+	s.onLoad = callback
+}
+
+// StoreName 当前使用的存储后端名称
+func (s *JsonService) StoreName() string {
+	return s.store.Name()
+}
+
 // LoadJsonFile 加载JSON数据
 func (s *JsonService) LoadJsonFile(authorization, clientJson string) ([]ServerData, error) {
 	servers, _, err := s.LoadJsonFileWithResponse(authorization, clientJson)
 	return servers, err
 }
 
-// LoadJsonFileWithResponse 加载JSON数据并返回KV响应（无后端缓存，每次都从KV获取最新数据）
+// LoadJsonFileWithResponse 加载JSON数据并返回存储响应（无后端缓存，每次都从存储获取最新数据）
 func (s *JsonService) LoadJsonFileWithResponse(authorization, clientJson string) ([]ServerData, *KvResponse, error) {
 	log.Printf("LoadJsonFileWithResponse called with authorization: %s, clientJson: %s", authorization, clientJson)
+	servers, resp, _, err := s.load(authorization, clientJson)
+	return servers, resp, err
+}
 
-	log.Printf("Fetching data from KV store (no backend cache)")
-	resp, err := s.kvService.GetKey(clientJson, authorization)
+// load 加载JSON数据，同时返回存储内容的ETag（内容MD5）
+func (s *JsonService) load(authorization, clientJson string) ([]ServerData, *KvResponse, string, error) {
+	log.Printf("Fetching data from %s store (no backend cache)", s.store.Name())
+	resp, err := s.store.Get(clientJson, authorization)
 	if err != nil {
-		log.Printf("Failed to get KV data: %v", err)
-		return []ServerData{}, nil, err
+		log.Printf("Failed to get store data: %v", err)
+		return []ServerData{}, nil, "", err
 	}
 
 	log.Printf("KV response: Code=%d, Data=%v", resp.Code, resp.Data != nil)
@@ -67,15 +122,16 @@ func (s *JsonService) LoadJsonFileWithResponse(authorization, clientJson string)
 	// 如果是 401 错误，直接返回
 	if resp.Code == 401 {
 		log.Printf("KV service returned 401 Unauthorized")
-		return []ServerData{}, resp, nil
+		return []ServerData{}, resp, "", nil
 	}
 
 	if resp.Code == 200 && resp.Data != nil {
 		log.Printf("KV data value: %s", resp.Data.Value)
+		etag := utils.MD5(resp.Data.Value)
 		var servers []ServerData
 		if err := json.Unmarshal([]byte(resp.Data.Value), &servers); err != nil {
 			log.Printf("Failed to unmarshal JSON: %v", err)
-			return []ServerData{}, resp, nil
+			return []ServerData{}, resp, etag, nil
 		}
 
 		// 数据迁移：为缺少 default_path 的服务器添加默认值
@@ -87,8 +143,18 @@ func (s *JsonService) LoadJsonFileWithResponse(authorization, clientJson string)
 				log.Printf("Added default path '/adplace' to server: %s", servers[i].ServerID)
 			}
 
+			// 数据迁移：版本号从1开始，保证编辑时的版本检查生效
+			if servers[i].Revision == 0 {
+				servers[i].Revision = 1
+				needsSave = true
+			}
+
 			// 数据迁移：为项目添加默认端口值
 			for j := range servers[i].ProjectList {
+				if servers[i].ProjectList[j].Revision == 0 {
+					servers[i].ProjectList[j].Revision = 1
+					needsSave = true
+				}
 				if servers[i].ProjectList[j].APIPort == "" {
 					servers[i].ProjectList[j].APIPort = "9000"
 					needsSave = true
@@ -102,43 +168,485 @@ func (s *JsonService) LoadJsonFileWithResponse(authorization, clientJson string)
 			}
 		}
 
+		// 凭据：保险库中有记录的显示为掩码；仍为明文的在保险库解锁后迁移进保险库
+		doc, err := s.loadVault(authorization, clientJson)
+		if err != nil {
+			log.Printf("Failed to load vault: %v", err)
+		} else {
+			s.vault.Remember(clientJson, doc, servers)
+		}
+		for i := range servers {
+			if hasPlainSecrets(&servers[i]) {
+				if s.vault.Unlocked() {
+					needsSave = true
+					log.Printf("Migrating plaintext credentials of server %s into vault", servers[i].ServerID)
+				}
+			} else if doc != nil {
+				maskSecrets(&servers[i], doc.FieldsOf(servers[i].ServerID))
+			}
+		}
+
 		// 如果有数据被修改，保存回去
 		if needsSave {
 			log.Printf("Saving updated server data with default paths")
-			s.SaveJsonFile(servers, authorization, clientJson)
+			// 条件写入：期间数据已被他人修改时跳过迁移，下次加载再迁移
+			if savedETag, err := s.saveIfMatch(servers, etag, authorization, clientJson); err != nil {
+				log.Printf("Skipped saving migrated data: %v", err)
+			} else {
+				etag = savedETag
+				// 已迁移进保险库的凭据同样以掩码返回
+				if s.vault.Unlocked() {
+					for i := range servers {
+						maskSecrets(&servers[i], secretFieldNames)
+					}
+				}
+			}
+		}
+
+		for i := range servers {
+			servers[i].namespace = clientJson
+		}
+		if s.onLoad != nil {
+			s.onLoad(servers)
 		}
 
 		log.Printf("Successfully loaded %d servers (no backend cache)", len(servers))
-		return servers, resp, nil
+		return servers, resp, etag, nil
 	}
 
 	// 如果没有数据，创建空数据
 	log.Printf("No data found, creating empty data")
 	emptyData := []ServerData{}
-	s.SaveJsonFile(emptyData, authorization, clientJson)
+	etag, _ := s.saveIfMatch(emptyData, "", authorization, clientJson)
 
-	return emptyData, resp, nil
+	return emptyData, resp, etag, nil
 }
 
 // SaveJsonFile 保存JSON数据
 func (s *JsonService) SaveJsonFile(data []ServerData, authorization, clientJson string) error {
+	_, err := s.save(data, authorization, clientJson)
+	return err
+}
+
+// save 保存JSON数据并返回新内容的ETag
+func (s *JsonService) save(data []ServerData, authorization, clientJson string) (string, error) {
+	return s.put(data, "", false, authorization, clientJson)
+}
+
+// saveIfMatch 仅当存储内容的ETag仍为 etag 时保存，否则返回 ErrETagMismatch
+func (s *JsonService) saveIfMatch(data []ServerData, etag, authorization, clientJson string) (string, error) {
+	return s.put(data, etag, true, authorization, clientJson)
+}
+
+// put 写入库存数据（凭据先写入保险库，库存数据中不保存明文）
+func (s *JsonService) put(data []ServerData, etag string, conditional bool, authorization, clientJson string) (string, error) {
+	// 快照基于写入保险库前的数据记录，凭据以掩码表示是否存在
+	unsealed := data
+	data, err := s.sealSecrets(data, authorization, clientJson)
+	if err != nil {
+		return "", err
+	}
+
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// 调试：打印要保存的 JSON 数据
 	log.Printf("Saving JSON data: %s", string(jsonData))
 
-	_, err = s.kvService.UpdateKey(clientJson, string(jsonData), authorization)
+	if conditional {
+		err = s.store.PutIfMatch(clientJson, string(jsonData), etag, authorization)
+	} else {
+		err = s.store.Put(clientJson, string(jsonData), authorization)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Data saved successfully (no backend cache to clear)")
+
+	// 记录版本快照，失败不影响保存结果
+	if err := s.history.Record(clientJson, authorization, unsealed); err != nil {
+		log.Printf("Failed to record inventory snapshot: %v", err)
+	}
+
+	return utils.MD5(string(jsonData)), nil
+}
+
+// vaultKey 保险库文档的key
+func vaultKey(clientJson string) string {
+	return clientJson + "_vault"
+}
+
+// loadVault 读取保险库文档，不存在时返回 nil
+func (s *JsonService) loadVault(authorization, clientJson string) (*VaultDocument, error) {
+	resp, err := s.store.Get(vaultKey(clientJson), authorization)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 200 || resp.Data == nil || resp.Data.Value == "" {
+		return nil, nil
+	}
+
+	var doc VaultDocument
+	if err := json.Unmarshal([]byte(resp.Data.Value), &doc); err != nil {
+		return nil, fmt.Errorf("解析保险库失败: %v", err)
+	}
+	if doc.Entries == nil {
+		doc.Entries = make(map[string]string)
+	}
+	return &doc, nil
+}
+
+// hasPlainSecrets 服务器数据中是否含有明文凭据
+func hasPlainSecrets(server *ServerData) bool {
+	for _, name := range secretFieldNames {
+		if isPlainSecret(*serverSecretField(server, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskSecrets 将指定的非空凭据字段替换为掩码
+func maskSecrets(server *ServerData, fields []string) {
+	for _, name := range fields {
+		field := serverSecretField(server, name)
+		if *field == "" || isPlainSecret(*field) {
+			*field = MaskedSecret
+		}
+	}
+}
+
+// sealSecrets 将服务器凭据写入保险库，返回不含凭据的服务器列表
+// 凭据字段为掩码表示不修改，为空表示清除；保险库从未启用且未解锁时只保留旧的明文凭据，不接受新凭据
+func (s *JsonService) sealSecrets(servers []ServerData, authorization, clientJson string) ([]ServerData, error) {
+	doc, err := s.loadVault(authorization, clientJson)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc == nil {
+		if !s.vault.Unlocked() {
+			return s.keepLegacySecrets(servers, authorization, clientJson)
+		}
+		if doc, err = s.vault.NewDocument(); err != nil {
+			return nil, err
+		}
+		log.Printf("Initialized credentials vault for %s", clientJson)
+	}
+	if doc.Fields == nil {
+		doc.Fields = make(map[string][]string)
+	}
+
+	stored := cloneServers(servers)
+	changed := false
+	present := make(map[string]bool)
+	verified := false
+
+	for i := range stored {
+		server := &stored[i]
+		serverID := server.ServerID
+		present[serverID] = true
+
+		if s.vault.Unlocked() {
+			if !verified {
+				if err := s.vault.Verify(doc); err != nil {
+					return nil, err
+				}
+				verified = true
+			}
+
+			old, err := s.vault.OpenSecrets(doc, serverID)
+			if err != nil {
+				return nil, fmt.Errorf("解密服务器 %s 的凭据失败: %v", serverID, err)
+			}
+
+			// 掩码字段沿用原值，其余字段使用新值（空值即清除）
+			secrets := ServerSecrets{}
+			var fields []string
+			for _, name := range secretFieldNames {
+				value := *serverSecretField(server, name)
+				if value == MaskedSecret {
+					value = *old.field(name)
+				}
+				*secrets.field(name) = value
+				if value != "" {
+					fields = append(fields, name)
+				}
+			}
+
+			switch {
+			case len(fields) == 0:
+				if _, ok := doc.Entries[serverID]; ok {
+					delete(doc.Entries, serverID)
+					delete(doc.Fields, serverID)
+					changed = true
+				}
+			case secrets != old || !sameJSON(fields, doc.FieldsOf(serverID)):
+				sealed, err := s.vault.SealSecrets(doc, secrets)
+				if err != nil {
+					return nil, err
+				}
+				doc.Entries[serverID] = sealed
+				doc.Fields[serverID] = fields
+				changed = true
+			}
+		} else {
+			// 未解锁时无法写入新凭据，只能保留或整体清除
+			if hasPlainSecrets(server) {
+				return nil, ErrVaultLocked
+			}
+			cleared := true
+			for _, name := range secretFieldNames {
+				if *serverSecretField(server, name) == MaskedSecret {
+					cleared = false
+				}
+			}
+			if _, ok := doc.Entries[serverID]; ok && cleared {
+				delete(doc.Entries, serverID)
+				delete(doc.Fields, serverID)
+				changed = true
+			}
+		}
 
-	if err == nil {
-		log.Printf("Data saved successfully (no backend cache to clear)")
+		for _, name := range secretFieldNames {
+			*serverSecretField(server, name) = ""
+		}
 	}
 
+	// 清理已删除服务器的凭据
+	for serverID := range doc.Entries {
+		if !present[serverID] {
+			delete(doc.Entries, serverID)
+			delete(doc.Fields, serverID)
+			changed = true
+		}
+	}
+
+	if changed {
+		docJSON, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.store.Put(vaultKey(clientJson), string(docJSON), authorization); err != nil {
+			return nil, fmt.Errorf("保存保险库失败: %v", err)
+		}
+	}
+	s.vault.Remember(clientJson, doc, stored)
+
+	return stored, nil
+}
+
+// keepLegacySecrets 保险库未设置时的保存：掩码字段沿用已保存的旧明文，已保存过的明文（如更改ID的服务器）原样保留，
+// 新的或修改过的凭据返回 ErrVaultRequired
+func (s *JsonService) keepLegacySecrets(servers []ServerData, authorization, clientJson string) ([]ServerData, error) {
+	resp, err := s.store.Get(clientJson, authorization)
+	if err != nil {
+		return nil, err
+	}
+	var saved []ServerData
+	if resp.Code == 200 && resp.Data != nil && resp.Data.Value != "" {
+		if err := json.Unmarshal([]byte(resp.Data.Value), &saved); err != nil {
+			log.Printf("Failed to parse saved data for legacy credentials: %v", err)
+		}
+	}
+	previous := indexServers(saved)
+	known := make(map[string]bool)
+	for i := range saved {
+		for _, name := range secretFieldNames {
+			known[*serverSecretField(&saved[i], name)] = true
+		}
+	}
+
+	stored := cloneServers(servers)
+	for i := range stored {
+		for _, name := range secretFieldNames {
+			field := serverSecretField(&stored[i], name)
+			old := ""
+			if server := previous[stored[i].ServerID]; server != nil {
+				old = *serverSecretField(server, name)
+			}
+			if *field == MaskedSecret {
+				*field = old
+			} else if *field != "" && !known[*field] {
+				return nil, ErrVaultRequired
+			}
+		}
+	}
+	s.vault.Remember(clientJson, nil, stored)
+
+	return stored, nil
+}
+
+// UnlockVault 使用主密码解锁保险库，并将仍为明文的凭据迁移进保险库
+func (s *JsonService) UnlockVault(passphrase, authorization, clientJson string) error {
+	s.vault.Unlock(passphrase)
+
+	doc, err := s.loadVault(authorization, clientJson)
+	if err != nil {
+		s.vault.Lock()
+		return err
+	}
+	if doc != nil {
+		if err := s.vault.Verify(doc); err != nil {
+			s.vault.Lock()
+			return err
+		}
+	}
+
+	// 重新加载会触发明文凭据迁移
+	_, err = s.LoadJsonFile(authorization, clientJson)
 	return err
 }
 
+// VaultStatus 保险库状态
+func (s *JsonService) VaultStatus(authorization, clientJson string) (map[string]interface{}, error) {
+	doc, err := s.loadVault(authorization, clientJson)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := 0
+	if doc != nil {
+		entries = len(doc.Entries)
+	}
+	return map[string]interface{}{
+		"initialized": doc != nil,
+		"unlocked":    s.vault.Unlocked(),
+		"entries":     entries,
+	}, nil
+}
+
+// lock 同一份数据的进程内修改锁
+func (s *JsonService) lock(clientJson string) *sync.Mutex {
+	mutex, _ := s.locks.LoadOrStore(clientJson, &sync.Mutex{})
+	return mutex.(*sync.Mutex)
+}
+
+// mutate 以乐观并发方式修改服务器列表：
+// 加载数据并记录ETag，执行修改，保存前重新读取存储；
+// 若期间数据已被他人修改，则对双方修改做三方合并，存在重叠修改时返回 ConflictError。
+// 保存使用条件写入，重新读取与写入之间数据又被修改时重新合并
+func (s *JsonService) mutate(authorization, clientJson string, modify func(servers []ServerData) ([]ServerData, error)) error {
+	mutex := s.lock(clientJson)
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	base, _, baseETag, err := s.load(authorization, clientJson)
+	if err != nil {
+		return err
+	}
+
+	ours, err := modify(cloneServers(base))
+	if err != nil {
+		return err
+	}
+	if sameJSON(ours, base) {
+		log.Printf("No changes to save")
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		current, _, currentETag, err := s.load(authorization, clientJson)
+		if err != nil {
+			return err
+		}
+
+		toSave := cloneServers(ours)
+		if currentETag != baseETag {
+			log.Printf("Inventory changed concurrently (etag %s -> %s), merging", baseETag, currentETag)
+			toSave, err = MergeServers(base, ours, current)
+			if err != nil {
+				log.Printf("Merge failed: %v", err)
+				return err
+			}
+		}
+
+		bumpRevisions(toSave, current)
+		_, err = s.saveIfMatch(toSave, currentETag, authorization, clientJson)
+		if !errors.Is(err, ErrETagMismatch) {
+			return err
+		}
+		if attempt >= maxMutateAttempts {
+			return &ConflictError{Reason: "保存时数据反复被其他人修改，请稍后重试"}
+		}
+		log.Printf("Inventory changed while saving, retrying (attempt %d)", attempt)
+	}
+}
+
+// ListSnapshots 列出库存版本快照
+func (s *JsonService) ListSnapshots(authorization, clientJson string) ([]SnapshotMeta, error) {
+	return s.history.List(clientJson, authorization)
+}
+
+// DiffSnapshots 比较两个版本的服务器列表，toVersion 为0时与当前数据比较
+func (s *JsonService) DiffSnapshots(fromVersion, toVersion int64, authorization, clientJson string) (InventoryDiff, error) {
+	from, err := s.history.Get(clientJson, fromVersion, authorization)
+	if err != nil {
+		return InventoryDiff{}, err
+	}
+
+	var to []ServerData
+	if toVersion == 0 {
+		to, err = s.LoadJsonFile(authorization, clientJson)
+		if err != nil {
+			return InventoryDiff{}, err
+		}
+	} else {
+		snapshot, err := s.history.Get(clientJson, toVersion, authorization)
+		if err != nil {
+			return InventoryDiff{}, err
+		}
+		to = snapshot.Servers
+	}
+
+	return DiffServers(from.Servers, RedactSecrets(to)), nil
+}
+
+// RestoreSnapshot 恢复到指定版本（恢复本身也会记录为新版本）；快照不含凭据，沿用各服务器当前的凭据
+// 返回需要重新填写凭据的服务器ID：快照之后被删除的服务器的凭据已从保险库清除，恢复后无法连接
+func (s *JsonService) RestoreSnapshot(version int64, authorization, clientJson string) ([]string, error) {
+	snapshot, err := s.history.Get(clientJson, version, authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	// 版本号由 mutate 基于当前数据递增，避免回退后与并发检查冲突
+	var missing []string
+	err = s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		missing = nil
+		restored := cloneServers(snapshot.Servers)
+		current := indexServers(servers)
+		for i := range restored {
+			server := current[restored[i].ServerID]
+			lost := false
+			for _, name := range secretFieldNames {
+				field := serverSecretField(&restored[i], name)
+				if server == nil {
+					lost = lost || *field != ""
+					*field = ""
+					continue
+				}
+				*field = *serverSecretField(server, name)
+			}
+			if lost {
+				missing = append(missing, restored[i].ServerID)
+			}
+			// 连接状态不属于快照记录的配置，保留当前的测试结果
+			if server != nil {
+				restored[i].ConnectionStatus, restored[i].LastTestTime, restored[i].LastTestResult = server.ConnectionStatus, server.LastTestTime, server.LastTestResult
+			}
+		}
+		return restored, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
 // GetServerByID 根据ID获取服务器信息
 func (s *JsonService) GetServerByID(serverID, authorization, clientJson string) (*ServerData, error) {
 	servers, err := s.LoadJsonFile(authorization, clientJson)
@@ -175,192 +683,348 @@ func (s *JsonService) GetProjectByID(projectID, authorization, clientJson string
 
 // AddOrUpdateProject 添加或更新项目
 func (s *JsonService) AddOrUpdateProject(serverID string, projectInfo ProjectData, authorization, clientJson string) error {
-	servers, err := s.LoadJsonFile(authorization, clientJson)
-	if err != nil {
-		return err
-	}
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		// 查找服务器
+		for i, server := range servers {
+			if server.ServerID == serverID {
+				// 查找项目是否存在
+				var previous *ProjectData
+				for j := range server.ProjectList {
+					if server.ProjectList[j].ProjectID == projectInfo.ProjectID {
+						previous = &server.ProjectList[j]
+						break
+					}
+				}
 
-	// 查找服务器
-	for i, server := range servers {
-		if server.ServerID == serverID {
-			// 查找项目是否存在
-			found := false
-			for j, project := range server.ProjectList {
-				if project.ProjectID == projectInfo.ProjectID {
-					// 更新现有项目
-					servers[i].ProjectList[j] = projectInfo
-					found = true
-					break
+				// 检查改动的端口是否与同一服务器上的其他项目冲突
+				if err := CheckChangedProjectPorts(&servers[i], previous, projectInfo, nil); err != nil {
+					return nil, err
 				}
-			}
 
-			// 如果项目不存在，添加新项目
-			if !found {
-				servers[i].ProjectList = append(servers[i].ProjectList, projectInfo)
-			}
+				found := false
+				for j, project := range server.ProjectList {
+					if project.ProjectID == projectInfo.ProjectID {
+						if err := checkProjectRevision(serverID, project, projectInfo.Revision); err != nil {
+							return nil, err
+						}
+						// 更新现有项目（版本号由保存时递增）
+						projectInfo.Revision = project.Revision
+						servers[i].ProjectList[j] = projectInfo
+						found = true
+						break
+					}
+				}
 
-			return s.SaveJsonFile(servers, authorization, clientJson)
+				// 如果项目不存在，添加新项目；带版本号说明表单编辑的项目已被他人删除
+				if !found {
+					if projectInfo.Revision != 0 {
+						return nil, &ConflictError{
+							ServerIDs: []string{serverID},
+							Reason:    fmt.Sprintf("项目 %s 已被删除", projectInfo.ProjectID),
+						}
+					}
+					servers[i].ProjectList = append(servers[i].ProjectList, projectInfo)
+				}
+
+				return servers, nil
+			}
 		}
-	}
 
-	return fmt.Errorf("服务器ID %s 不存在", serverID)
+		return nil, fmt.Errorf("服务器ID %s 不存在", serverID)
+	})
 }
 
 // UpdateServerConnectionStatus 更新服务器连接状态
 func (s *JsonService) UpdateServerConnectionStatus(serverID, testResult, authorization, clientJson string) error {
-	servers, err := s.LoadJsonFile(authorization, clientJson)
-	if err != nil {
-		return err
-	}
-
 	// 解析测试结果
 	var result map[string]interface{}
 	if err := json.Unmarshal([]byte(testResult), &result); err != nil {
 		return fmt.Errorf("解析测试结果失败: %v", err)
 	}
 
-	// 查找并更新服务器
-	for i, server := range servers {
-		if server.ServerID == serverID {
-			// 更新连接状态
-			if data, ok := result["data"].(map[string]interface{}); ok {
-				if connected, ok := data["connected"].(bool); ok {
-					if connected {
-						servers[i].ConnectionStatus = "connected"
-					} else {
-						servers[i].ConnectionStatus = "disconnected"
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		// 查找并更新服务器
+		for i, server := range servers {
+			if server.ServerID == serverID {
+				// 更新连接状态
+				if data, ok := result["data"].(map[string]interface{}); ok {
+					if connected, ok := data["connected"].(bool); ok {
+						if connected {
+							servers[i].ConnectionStatus = "connected"
+						} else {
+							servers[i].ConnectionStatus = "disconnected"
+						}
+					}
+
+					if testTime, ok := data["test_time"].(string); ok {
+						servers[i].LastTestTime = testTime
+					}
+
+					// 首次信任：尚未记录主机密钥时保存本次连接的指纹
+					if fingerprint, ok := data["host_key_fingerprint"].(string); ok && fingerprint != "" && servers[i].HostKeyFingerprint == "" {
+						servers[i].HostKeyFingerprint = fingerprint
+						log.Printf("Recorded host key fingerprint for server %s: %s", serverID, fingerprint)
 					}
 				}
 
-				if testTime, ok := data["test_time"].(string); ok {
-					servers[i].LastTestTime = testTime
+				if msg, ok := result["msg"].(string); ok {
+					servers[i].LastTestResult = msg
 				}
-			}
 
-			if msg, ok := result["msg"].(string); ok {
-				servers[i].LastTestResult = msg
+				return servers, nil
 			}
-
-			return s.SaveJsonFile(servers, authorization, clientJson)
 		}
+
+		return nil, fmt.Errorf("服务器ID %s 不存在", serverID)
+	})
+}
+
+// UpdateConnectivity 批量保存连通性检测结果（一次写入），并对尚未记录主机密钥的服务器记录本次指纹
+func (s *JsonService) UpdateConnectivity(results []ConnectivityResult, authorization, clientJson string) error {
+	byID := make(map[string]ConnectivityResult, len(results))
+	for _, result := range results {
+		byID[result.ServerID] = result
 	}
 
-	return fmt.Errorf("服务器ID %s 不存在", serverID)
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		for i := range servers {
+			result, ok := byID[servers[i].ServerID]
+			if !ok {
+				continue
+			}
+			servers[i].LastTestTime = result.TestTime
+			if result.Connected {
+				servers[i].ConnectionStatus = "connected"
+				servers[i].LastTestResult = "SSH连接成功"
+			} else {
+				servers[i].ConnectionStatus = "disconnected"
+				servers[i].LastTestResult = fmt.Sprintf("SSH连接失败: %s", result.Error)
+			}
+			if result.Connected && result.HostKeyFingerprint != "" && servers[i].HostKeyFingerprint == "" {
+				servers[i].HostKeyFingerprint = result.HostKeyFingerprint
+				log.Printf("Recorded host key fingerprint for server %s: %s", servers[i].ServerID, result.HostKeyFingerprint)
+			}
+		}
+		return servers, nil
+	})
 }
 
 // DeleteProject 删除项目
 func (s *JsonService) DeleteProject(serverID, projectID, authorization, clientJson string) error {
-	servers, err := s.LoadJsonFile(authorization, clientJson)
-	if err != nil {
-		return err
-	}
-
-	// 查找服务器
-	for i, server := range servers {
-		if server.ServerID == serverID {
-			// 查找并删除项目
-			for j, project := range server.ProjectList {
-				if project.ProjectID == projectID {
-					// 删除项目
-					servers[i].ProjectList = append(
-						servers[i].ProjectList[:j],
-						servers[i].ProjectList[j+1:]...,
-					)
-					return s.SaveJsonFile(servers, authorization, clientJson)
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		// 查找服务器
+		for i, server := range servers {
+			if server.ServerID == serverID {
+				// 查找并删除项目
+				for j, project := range server.ProjectList {
+					if project.ProjectID == projectID {
+						// 删除项目
+						servers[i].ProjectList = append(
+							servers[i].ProjectList[:j],
+							servers[i].ProjectList[j+1:]...,
+						)
+						return servers, nil
+					}
 				}
 			}
 		}
-	}
 
-	return nil
+		return servers, nil
+	})
 }
 
 // AddServer 添加新服务器
 func (s *JsonService) AddServer(serverData ServerData, authorization, clientJson string) error {
-	servers, err := s.LoadJsonFile(authorization, clientJson)
-	if err != nil {
-		return err
-	}
-
-	// 检查服务器ID是否已存在
-	for _, server := range servers {
-		if server.ServerID == serverData.ServerID {
-			return fmt.Errorf("服务器ID %s 已存在", serverData.ServerID)
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		// 检查服务器ID是否已存在
+		for _, server := range servers {
+			if server.ServerID == serverData.ServerID {
+				return nil, fmt.Errorf("服务器ID %s 已存在", serverData.ServerID)
+			}
 		}
-	}
-
-	// 添加新服务器
-	servers = append(servers, serverData)
 
-	return s.SaveJsonFile(servers, authorization, clientJson)
+		// 添加新服务器
+		serverData.Revision = 0
+		return append(servers, serverData), nil
+	})
 }
 
 // UpdateServer 更新服务器信息
 func (s *JsonService) UpdateServer(serverID string, updatedServer ServerData, authorization, clientJson string) error {
-	servers, err := s.LoadJsonFile(authorization, clientJson)
-	if err != nil {
-		return err
-	}
-
-	// 查找并更新服务器
-	for i, server := range servers {
-		if server.ServerID == serverID {
-			// 保留原有的项目列表
-			updatedServer.ProjectList = server.ProjectList
-			servers[i] = updatedServer
-			return s.SaveJsonFile(servers, authorization, clientJson)
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		// 查找并更新服务器
+		for i, server := range servers {
+			if server.ServerID == serverID {
+				if err := checkRevision(server, updatedServer.Revision); err != nil {
+					return nil, err
+				}
+				// 保留原有的项目列表
+				updatedServer.ProjectList = server.ProjectList
+				updatedServer.Revision = server.Revision
+				servers[i] = updatedServer
+				return servers, nil
+			}
 		}
-	}
 
-	return nil
+		return servers, nil
+	})
 }
 
 // UpdateServerWithNewID 更新服务器信息（支持更改服务器ID）
+// updatedServer.Revision 非0时作为期望版本号，与存储中的版本不一致则返回 ConflictError
 func (s *JsonService) UpdateServerWithNewID(oldServerID string, updatedServer ServerData, authorization, clientJson string) error {
-	servers, err := s.LoadJsonFile(authorization, clientJson)
-	if err != nil {
-		return err
-	}
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		// 如果新ID与旧ID不同，需要检查新ID是否已存在
+		if oldServerID != updatedServer.ServerID {
+			for _, server := range servers {
+				if server.ServerID == updatedServer.ServerID {
+					return nil, fmt.Errorf("服务器ID %s 已存在", updatedServer.ServerID)
+				}
+			}
+		}
 
-	// 如果新ID与旧ID不同，需要检查新ID是否已存在
-	if oldServerID != updatedServer.ServerID {
-		for _, server := range servers {
-			if server.ServerID == updatedServer.ServerID {
-				return fmt.Errorf("服务器ID %s 已存在", updatedServer.ServerID)
+		// 查找并更新服务器
+		for i, server := range servers {
+			if server.ServerID == oldServerID {
+				if err := checkRevision(server, updatedServer.Revision); err != nil {
+					return nil, err
+				}
+				// 更改ID时保险库中的凭据需要迁移到新ID下
+				if oldServerID != updatedServer.ServerID {
+					for _, name := range secretFieldNames {
+						field := serverSecretField(&updatedServer, name)
+						if *field != MaskedSecret {
+							continue
+						}
+						secrets, err := s.vault.Secrets(clientJson, oldServerID)
+						if err != nil {
+							return nil, err
+						}
+						*field = *secrets.field(name)
+					}
+				}
+				// 地址未变化时保留已记录的主机密钥指纹，地址变化后需要重新首次信任
+				if updatedServer.ServerIP == server.ServerIP && updatedServer.ServerPort == server.ServerPort {
+					updatedServer.HostKeyFingerprint = server.HostKeyFingerprint
+				} else {
+					updatedServer.HostKeyFingerprint = ""
+				}
+				// 日志位置和跳板机单独设置（SetServerLogSource、SetServerJump），编辑服务器时保留
+				if updatedServer.LogSource == "" {
+					updatedServer.LogSource = server.LogSource
+				}
+				if updatedServer.JumpServerID == "" && updatedServer.JumpHost == nil {
+					updatedServer.JumpServerID = server.JumpServerID
+					updatedServer.JumpHost = server.JumpHost
+				}
+				// 更改ID时同步更新以该服务器为跳板机的引用
+				if oldServerID != updatedServer.ServerID {
+					for j := range servers {
+						if servers[j].JumpServerID == oldServerID {
+							servers[j].JumpServerID = updatedServer.ServerID
+						}
+					}
+				}
+				// 保留原有的项目列表和连接状态信息
+				updatedServer.ProjectList = server.ProjectList
+				updatedServer.ConnectionStatus = server.ConnectionStatus
+				updatedServer.LastTestTime = server.LastTestTime
+				updatedServer.LastTestResult = server.LastTestResult
+				updatedServer.Revision = server.Revision
+				servers[i] = updatedServer
+				return servers, nil
 			}
 		}
-	}
 
-	// 查找并更新服务器
-	for i, server := range servers {
-		if server.ServerID == oldServerID {
-			// 保留原有的项目列表和连接状态信息
-			updatedServer.ProjectList = server.ProjectList
-			updatedServer.ConnectionStatus = server.ConnectionStatus
-			updatedServer.LastTestTime = server.LastTestTime
-			updatedServer.LastTestResult = server.LastTestResult
-			servers[i] = updatedServer
-			return s.SaveJsonFile(servers, authorization, clientJson)
+		return nil, fmt.Errorf("服务器ID %s 不存在", oldServerID)
+	})
+}
+
+// AcceptHostKey 确认接受服务器新的主机密钥指纹
+func (s *JsonService) AcceptHostKey(serverID, fingerprint, authorization, clientJson string) error {
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		for i, server := range servers {
+			if server.ServerID == serverID {
+				servers[i].HostKeyFingerprint = fingerprint
+				return servers, nil
+			}
 		}
-	}
 
-	return fmt.Errorf("服务器ID %s 不存在", oldServerID)
+		return nil, fmt.Errorf("服务器ID %s 不存在", serverID)
+	})
 }
 
-// DeleteServer 删除服务器
+// SetServerLogSource 设置服务器的项目日志位置模板（为空恢复默认）
+func (s *JsonService) SetServerLogSource(serverID, logSource, authorization, clientJson string) error {
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		for i, server := range servers {
+			if server.ServerID == serverID {
+				servers[i].LogSource = logSource
+				return servers, nil
+			}
+		}
+
+		return nil, fmt.Errorf("服务器ID %s 不存在", serverID)
+	})
+}
+
+// SetServerJump 设置服务器的跳板机（jumpServerID 和 jumpHost 均为空表示直连）
+func (s *JsonService) SetServerJump(serverID, jumpServerID string, jumpHost *JumpHost, authorization, clientJson string) error {
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		for i, server := range servers {
+			if server.ServerID == serverID {
+				server.JumpServerID = jumpServerID
+				server.JumpHost = jumpHost
+				if err := ValidateJump(server, servers); err != nil {
+					return nil, err
+				}
+				servers[i] = server
+				return servers, nil
+			}
+		}
+
+		return nil, fmt.Errorf("服务器ID %s 不存在", serverID)
+	})
+}
+
+// DeleteServer 删除服务器（仍被其他服务器用作跳板机时拒绝删除）
 func (s *JsonService) DeleteServer(serverID, authorization, clientJson string) error {
-	servers, err := s.LoadJsonFile(authorization, clientJson)
-	if err != nil {
-		return err
-	}
+	return s.mutate(authorization, clientJson, func(servers []ServerData) ([]ServerData, error) {
+		for _, server := range servers {
+			if server.JumpServerID == serverID {
+				return nil, fmt.Errorf("服务器 %s 是 %s 的跳板机，请先修改 %s 的跳板机配置", serverID, server.ServerID, server.ServerID)
+			}
+		}
 
-	// 查找并删除服务器
-	for i, server := range servers {
-		if server.ServerID == serverID {
-			servers = append(servers[:i], servers[i+1:]...)
-			return s.SaveJsonFile(servers, authorization, clientJson)
+		// 查找并删除服务器
+		for i, server := range servers {
+			if server.ServerID == serverID {
+				return append(servers[:i], servers[i+1:]...), nil
+			}
+		}
+
+		return servers, nil
+	})
+}
+
+// checkProjectRevision 检查项目的期望版本号（0表示不检查）
+func checkProjectRevision(serverID string, project ProjectData, expected int64) error {
+	if expected != 0 && expected != project.Revision {
+		return &ConflictError{
+			ServerIDs: []string{serverID},
+			Reason:    fmt.Sprintf("项目 %s 期望版本 %d，当前版本 %d", project.ProjectID, expected, project.Revision),
 		}
 	}
+	return nil
+}
 
+// checkRevision 检查期望版本号（0表示不检查）
+func checkRevision(server ServerData, expected int64) error {
+	if expected != 0 && expected != server.Revision {
+		return &ConflictError{
+			ServerIDs: []string{server.ServerID},
+			Reason:    fmt.Sprintf("期望版本 %d，当前版本 %d", expected, server.Revision),
+		}
+	}
 	return nil
 }
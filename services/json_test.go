@@ -0,0 +1,270 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const testNamespace = "test_client.json"
+
+// racingStore 在第一次条件写入前插入一次其他客户端的写入，模拟重新读取与保存之间的并发修改
+type racingStore struct {
+	*MemoryStore
+	race func()
+}
+
+func (s *racingStore) PutIfMatch(key, value, etag, authorization string) error {
+	if race := s.race; race != nil && key == testNamespace {
+		s.race = nil
+		race()
+	}
+	return s.MemoryStore.PutIfMatch(key, value, etag, authorization)
+}
+
+// seedServers 直接写入存储的初始服务器列表
+func seedServers(t *testing.T, store Store, servers []ServerData) {
+	t.Helper()
+	data, err := json.Marshal(servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(testNamespace, string(data), ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testServers() []ServerData {
+	return []ServerData{
+		{ServerID: "a", ServerIP: "10.0.0.1", DefaultPath: "/srv", ProjectList: []ProjectData{
+			{ProjectID: "p1", ProjectName: "one", APIPort: "9001", FrontPort: "3001"},
+			{ProjectID: "p2", ProjectName: "two", APIPort: "9002", FrontPort: "3002"},
+		}},
+		{ServerID: "b", ServerIP: "10.0.0.2", DefaultPath: "/srv"},
+	}
+}
+
+func findProject(servers []ServerData, serverID, projectID string) *ProjectData {
+	for i := range servers {
+		if servers[i].ServerID != serverID {
+			continue
+		}
+		for j := range servers[i].ProjectList {
+			if servers[i].ProjectList[j].ProjectID == projectID {
+				return &servers[i].ProjectList[j]
+			}
+		}
+	}
+	return nil
+}
+
+func TestLoadMigratesRevisions(t *testing.T) {
+	store := NewMemoryStore()
+	seedServers(t, store, testServers())
+	service := NewJsonServiceWithStore(store)
+
+	servers, err := service.LoadJsonFile("", testNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, server := range servers {
+		if server.Revision != 1 {
+			t.Errorf("server %s revision = %d; want 1", server.ServerID, server.Revision)
+		}
+		for _, project := range server.ProjectList {
+			if project.Revision != 1 {
+				t.Errorf("project %s revision = %d; want 1", project.ProjectID, project.Revision)
+			}
+		}
+	}
+}
+
+func TestAddOrUpdateProjectRevision(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  ProjectData
+		conflict bool
+	}{
+		{"current revision", ProjectData{ProjectID: "p1", ProjectName: "renamed", APIPort: "9001", FrontPort: "3001", Revision: 1}, false},
+		{"no revision", ProjectData{ProjectID: "p1", ProjectName: "renamed", APIPort: "9001", FrontPort: "3001"}, false},
+		{"stale revision", ProjectData{ProjectID: "p1", ProjectName: "renamed", APIPort: "9001", FrontPort: "3001", Revision: 5}, true},
+		{"deleted project", ProjectData{ProjectID: "gone", ProjectName: "x", APIPort: "9100", FrontPort: "3100", Revision: 1}, true},
+		{"new project", ProjectData{ProjectID: "p3", ProjectName: "three", APIPort: "9003", FrontPort: "3003"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			seedServers(t, store, testServers())
+			service := NewJsonServiceWithStore(store)
+			if _, err := service.LoadJsonFile("", testNamespace); err != nil {
+				t.Fatal(err)
+			}
+
+			err := service.AddOrUpdateProject("a", tt.project, "", testNamespace)
+			if got := errors.Is(err, ErrConflict); got != tt.conflict {
+				t.Fatalf("AddOrUpdateProject error = %v; conflict = %v, want %v", err, got, tt.conflict)
+			}
+			if tt.conflict {
+				return
+			}
+
+			servers, _ := service.LoadJsonFile("", testNamespace)
+			project := findProject(servers, "a", tt.project.ProjectID)
+			if project == nil || project.ProjectName != tt.project.ProjectName {
+				t.Fatalf("project after save = %+v", project)
+			}
+		})
+	}
+}
+
+func TestStaleProjectFormIsRejected(t *testing.T) {
+	store := NewMemoryStore()
+	seedServers(t, store, testServers())
+	service := NewJsonServiceWithStore(store)
+	servers, _ := service.LoadJsonFile("", testNamespace)
+	form := *findProject(servers, "a", "p1")
+
+	// 同事先保存了修改
+	teammate := form
+	teammate.ProjectName = "teammate"
+	if err := service.AddOrUpdateProject("a", teammate, "", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+
+	form.ProjectName = "mine"
+	if err := service.AddOrUpdateProject("a", form, "", testNamespace); !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale form error = %v; want conflict", err)
+	}
+
+	servers, _ = service.LoadJsonFile("", testNamespace)
+	if project := findProject(servers, "a", "p1"); project.ProjectName != "teammate" || project.Revision != 2 {
+		t.Fatalf("project after stale save = %+v", project)
+	}
+}
+
+func TestUpdateServerRevision(t *testing.T) {
+	store := NewMemoryStore()
+	seedServers(t, store, testServers())
+	service := NewJsonServiceWithStore(store)
+	service.LoadJsonFile("", testNamespace)
+
+	update := ServerData{ServerID: "b", ServerIP: "10.0.0.3", DefaultPath: "/srv", Revision: 7}
+	if err := service.UpdateServerWithNewID("b", update, "", testNamespace); !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale server update error = %v; want conflict", err)
+	}
+	update.Revision = 1
+	if err := service.UpdateServerWithNewID("b", update, "", testNamespace); err != nil {
+		t.Fatalf("server update: %v", err)
+	}
+}
+
+func TestMutateMergesWriteBetweenReloadAndSave(t *testing.T) {
+	store := &racingStore{MemoryStore: NewMemoryStore()}
+	seedServers(t, store, testServers())
+	service := NewJsonServiceWithStore(store)
+	service.LoadJsonFile("", testNamespace)
+
+	// 另一个客户端在我方重新读取之后、写入之前修改了服务器 b
+	other := NewJsonServiceWithStore(store.MemoryStore)
+	store.race = func() {
+		if err := other.SetServerLogSource("b", "/var/log/b/*.log", "", testNamespace); err != nil {
+			t.Errorf("concurrent write: %v", err)
+		}
+	}
+
+	project := ProjectData{ProjectID: "p3", ProjectName: "three", APIPort: "9003", FrontPort: "3003"}
+	if err := service.AddOrUpdateProject("a", project, "", testNamespace); err != nil {
+		t.Fatalf("AddOrUpdateProject: %v", err)
+	}
+
+	servers, _ := service.LoadJsonFile("", testNamespace)
+	if findProject(servers, "a", "p3") == nil {
+		t.Errorf("our project was lost")
+	}
+	for _, server := range servers {
+		if server.ServerID == "b" && server.LogSource != "/var/log/b/*.log" {
+			t.Errorf("concurrent change was lost: %+v", server)
+		}
+	}
+}
+
+func TestMutateConflictBetweenReloadAndSave(t *testing.T) {
+	store := &racingStore{MemoryStore: NewMemoryStore()}
+	seedServers(t, store, testServers())
+	service := NewJsonServiceWithStore(store)
+	service.LoadJsonFile("", testNamespace)
+
+	other := NewJsonServiceWithStore(store.MemoryStore)
+	store.race = func() {
+		if err := other.SetServerLogSource("b", "/theirs", "", testNamespace); err != nil {
+			t.Errorf("concurrent write: %v", err)
+		}
+	}
+
+	err := service.SetServerLogSource("b", "/ours", "", testNamespace)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("overlapping change error = %v; want conflict", err)
+	}
+	servers, _ := service.LoadJsonFile("", testNamespace)
+	for _, server := range servers {
+		if server.ServerID == "b" && server.LogSource != "/theirs" {
+			t.Errorf("concurrent change was overwritten: %+v", server)
+		}
+	}
+}
+
+func TestStorePutIfMatch(t *testing.T) {
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"file":   NewFileStore(t.TempDir()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if err := store.PutIfMatch("key", "v1", "stale", ""); !errors.Is(err, ErrETagMismatch) {
+				t.Fatalf("PutIfMatch on missing key with etag = %v; want mismatch", err)
+			}
+			if err := store.PutIfMatch("key", "v1", "", ""); err != nil {
+				t.Fatalf("PutIfMatch create: %v", err)
+			}
+			resp, _ := store.Get("key", "")
+			etag := contentETag(resp)
+			if err := store.PutIfMatch("key", "v2", etag, ""); err != nil {
+				t.Fatalf("PutIfMatch with current etag: %v", err)
+			}
+			if err := store.PutIfMatch("key", "v3", etag, ""); !errors.Is(err, ErrETagMismatch) {
+				t.Fatalf("PutIfMatch with old etag = %v; want mismatch", err)
+			}
+			if resp, _ := store.Get("key", ""); resp.Data.Value != "v2" {
+				t.Fatalf("value = %q; want v2", resp.Data.Value)
+			}
+		})
+	}
+}
+
+func TestConnectivityKeepsRevision(t *testing.T) {
+	store := NewMemoryStore()
+	seedServers(t, store, testServers())
+	service := NewJsonServiceWithStore(store)
+	service.LoadJsonFile("", testNamespace)
+
+	// 编辑表单打开期间，定时测试多次更新了连接状态
+	for _, connected := range []bool{false, true, false} {
+		result := ConnectivityResult{ServerID: "b", Connected: connected, Error: "timeout", TestTime: "2026-01-01 00:00:00"}
+		if err := service.UpdateConnectivity([]ConnectivityResult{result}, "", testNamespace); err != nil {
+			t.Fatal(err)
+		}
+	}
+	servers, _ := service.LoadJsonFile("", testNamespace)
+	if servers[1].Revision != 1 || servers[1].ConnectionStatus != "disconnected" {
+		t.Fatalf("server after connectivity updates = %+v; want revision 1, disconnected", servers[1])
+	}
+
+	update := ServerData{ServerID: "b", ServerIP: "10.0.0.3", DefaultPath: "/srv", Revision: 1}
+	if err := service.UpdateServerWithNewID("b", update, "", testNamespace); err != nil {
+		t.Fatalf("server update after connectivity test: %v", err)
+	}
+	servers, _ = service.LoadJsonFile("", testNamespace)
+	if servers[1].Revision != 2 {
+		t.Fatalf("revision after edit = %d; want 2", servers[1].Revision)
+	}
+}
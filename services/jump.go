@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// maxJumpHops 跳板机最多串联的层数
+const maxJumpHops = 4
+
+// JumpHost 临时跳板机（不在服务器列表中）
+// 凭据不保存在保险库中，因此只支持 ssh-agent 和本地私钥文件认证；需要密码的跳板机请先添加为服务器再引用
+type JumpHost struct {
+	Host               string `json:"host"`
+	Port               string `json:"port,omitempty"`
+	User               string `json:"user,omitempty"`
+	AuthType           string `json:"auth_type,omitempty"`            // "agent"（默认）或 "key"
+	PrivateKey         string `json:"private_key,omitempty"`          // 本地私钥文件路径
+	HostKeyFingerprint string `json:"host_key_fingerprint,omitempty"` // 为空时不校验主机密钥
+}
+
+// server 转换为用于连接的服务器数据
+func (h *JumpHost) server() *ServerData {
+	authType := h.AuthType
+	if authType == "" {
+		authType = AuthTypeAgent
+	}
+	return &ServerData{
+		ServerID:           "jump:" + h.Host,
+		ServerIP:           h.Host,
+		ServerPort:         h.Port,
+		ServerUser:         h.User,
+		AuthType:           authType,
+		PrivateKey:         h.PrivateKey,
+		HostKeyFingerprint: h.HostKeyFingerprint,
+	}
+}
+
+// Validate 校验临时跳板机配置
+func (h *JumpHost) Validate() error {
+	if strings.TrimSpace(h.Host) == "" {
+		return fmt.Errorf("跳板机地址不能为空")
+	}
+	switch h.AuthType {
+	case "", AuthTypeAgent:
+	case AuthTypeKey:
+		if strings.TrimSpace(h.PrivateKey) == "" {
+			return fmt.Errorf("跳板机未配置私钥文件")
+		}
+		if strings.HasPrefix(strings.TrimSpace(h.PrivateKey), "-----BEGIN") {
+			return fmt.Errorf("临时跳板机只支持私钥文件路径，不保存私钥内容")
+		}
+	default:
+		return fmt.Errorf("临时跳板机不支持 %s 认证，请先添加为服务器再引用", h.AuthType)
+	}
+	return nil
+}
+
+// ValidateJump 校验服务器的跳板机配置：引用的服务器必须存在，且不能形成循环
+func ValidateJump(server ServerData, servers []ServerData) error {
+	if server.JumpServerID != "" && server.JumpHost != nil {
+		return fmt.Errorf("跳板机只能引用服务器或配置临时主机其中之一")
+	}
+	if server.JumpHost != nil {
+		return server.JumpHost.Validate()
+	}
+
+	byID := make(map[string]ServerData, len(servers))
+	for _, item := range servers {
+		byID[item.ServerID] = item
+	}
+	byID[server.ServerID] = server
+
+	visited := map[string]bool{server.ServerID: true}
+	current := server
+	for hops := 0; current.JumpServerID != ""; hops++ {
+		if hops >= maxJumpHops {
+			return fmt.Errorf("跳板机最多串联 %d 层", maxJumpHops)
+		}
+		next, ok := byID[current.JumpServerID]
+		if !ok {
+			return fmt.Errorf("跳板机服务器 %s 不存在", current.JumpServerID)
+		}
+		if visited[next.ServerID] {
+			return fmt.Errorf("跳板机配置存在循环引用（%s）", next.ServerID)
+		}
+		visited[next.ServerID] = true
+		if next.JumpHost != nil {
+			break
+		}
+		current = next
+	}
+	return nil
+}
+
+// RememberServers 缓存最近加载的服务器列表，用于按ID解析跳板机
+func (s *SSHService) RememberServers(servers []ServerData) {
+	known := make(map[string]ServerData, len(servers))
+	for _, server := range servers {
+		server.ProjectList = nil
+		known[server.ServerID] = server
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.servers = known
+}
+
+// jumpServer 服务器配置的跳板机，未配置时返回 nil
+func (s *SSHService) jumpServer(server *ServerData) (*ServerData, error) {
+	if server.JumpHost != nil {
+		return server.JumpHost.server(), nil
+	}
+	if server.JumpServerID == "" {
+		return nil, nil
+	}
+
+	s.mutex.Lock()
+	jump, ok := s.servers[server.JumpServerID]
+	s.mutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("跳板机服务器 %s 不存在", server.JumpServerID)
+	}
+	return &jump, nil
+}
+
+// dialTransport 建立到服务器SSH端口的连接，配置了跳板机时经由跳板机转发；
+// 返回的 closeJump 在目标连接关闭后调用，用于关闭跳板机连接
+func (s *SSHService) dialTransport(server *ServerData, timeout time.Duration, hops int) (net.Conn, func(), error) {
+	address := serverAddress(server)
+	jump, err := s.jumpServer(server)
+	if err != nil {
+		return nil, nil, err
+	}
+	if jump == nil {
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		return conn, func() {}, err
+	}
+	if hops >= maxJumpHops {
+		return nil, nil, fmt.Errorf("跳板机最多串联 %d 层", maxJumpHops)
+	}
+
+	jumpClient, _, err := s.dial(jump, timeout, hops+1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("连接跳板机 %s 失败: %w", serverAddress(jump), err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := jumpClient.DialContext(ctx, "tcp", address)
+	if err != nil {
+		jumpClient.Close()
+		return nil, nil, fmt.Errorf("通过跳板机 %s 连接 %s 失败: %w", serverAddress(jump), address, err)
+	}
+	return conn, func() { jumpClient.Close() }, nil
+}
+
+// dial 建立SSH连接（必要时经由跳板机），返回服务器出示的主机密钥指纹
+func (s *SSHService) dial(server *ServerData, timeout time.Duration, hops int) (*ssh.Client, string, error) {
+	config, cleanup, err := s.ClientConfig(server, timeout)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cleanup()
+
+	var fingerprint string
+	config.HostKeyCallback = hostKeyCallback(server, &fingerprint)
+
+	conn, closeJump, err := s.dialTransport(server, timeout, hops)
+	if err != nil {
+		return nil, "", err
+	}
+
+	address := serverAddress(server)
+	clientConn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		conn.Close()
+		closeJump()
+		return nil, fingerprint, err
+	}
+
+	client := ssh.NewClient(clientConn, channels, requests)
+	go func() {
+		client.Wait()
+		closeJump()
+	}()
+	return client, fingerprint, nil
+}
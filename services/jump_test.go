@@ -0,0 +1,61 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateJump(t *testing.T) {
+	// 已保存的服务器：c -> d，e -> f -> g -> h，y 使用临时跳板机，x -> a
+	saved := []ServerData{
+		{ServerID: "a"},
+		{ServerID: "b"},
+		{ServerID: "c", JumpServerID: "d"},
+		{ServerID: "d"},
+		{ServerID: "e", JumpServerID: "f"},
+		{ServerID: "f", JumpServerID: "g"},
+		{ServerID: "g", JumpServerID: "h"},
+		{ServerID: "h"},
+		{ServerID: "x", JumpServerID: "a"},
+		{ServerID: "y", JumpHost: &JumpHost{Host: "10.0.0.9"}},
+	}
+	tests := []struct {
+		name    string
+		server  ServerData
+		servers []ServerData
+		wantErr string // 错误信息包含的内容，为空表示校验通过
+	}{
+		{"no jump", ServerData{ServerID: "a"}, saved, ""},
+		{"direct", ServerData{ServerID: "a", JumpServerID: "b"}, saved, ""},
+		{"chain", ServerData{ServerID: "a", JumpServerID: "c"}, saved, ""},
+		{"max hops", ServerData{ServerID: "a", JumpServerID: "e"}, saved, ""},
+		{"through jump host", ServerData{ServerID: "a", JumpServerID: "y"}, saved, ""},
+		{"jump host", ServerData{ServerID: "a", JumpHost: &JumpHost{Host: "10.0.0.9"}}, saved, ""},
+		{"too many hops", ServerData{ServerID: "b", JumpServerID: "a"},
+			append(saved, ServerData{ServerID: "a", JumpServerID: "e"}), "最多串联"},
+		{"self", ServerData{ServerID: "a", JumpServerID: "a"}, saved, "循环"},
+		{"two servers", ServerData{ServerID: "a", JumpServerID: "x"}, saved, "循环"},
+		{"three servers", ServerData{ServerID: "d", JumpServerID: "x"},
+			[]ServerData{{ServerID: "a", JumpServerID: "c"}, {ServerID: "c", JumpServerID: "d"}, {ServerID: "d"}, {ServerID: "x", JumpServerID: "a"}}, "循环"},
+		{"cycle not through server", ServerData{ServerID: "a", JumpServerID: "b"},
+			[]ServerData{{ServerID: "a"}, {ServerID: "b", JumpServerID: "c"}, {ServerID: "c", JumpServerID: "b"}}, "循环"},
+		{"missing", ServerData{ServerID: "a", JumpServerID: "z"}, saved, "不存在"},
+		{"missing in chain", ServerData{ServerID: "a", JumpServerID: "c"}, []ServerData{{ServerID: "c", JumpServerID: "z"}}, "不存在"},
+		{"server and jump host", ServerData{ServerID: "a", JumpServerID: "b", JumpHost: &JumpHost{Host: "10.0.0.9"}}, saved, "其中之一"},
+		{"invalid jump host", ServerData{ServerID: "a", JumpHost: &JumpHost{}}, saved, "地址不能为空"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJump(tt.server, tt.servers)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateJump = %v; want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateJump = %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
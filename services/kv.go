@@ -1,15 +1,27 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"adsplat/utils"
 )
 
 const KV_BASE_URL = "https://kv.adswds.com/1ep2d8wb"
 
+// kvChunkSize 单个KV值的最大字节数，超过后分块存储
+const kvChunkSize = 512 * 1024
+
+// kvChunkMarker 分块存储时主key中保存的清单前缀
+const kvChunkMarker = "__kv_chunked__:"
+
 // KvData KV存储数据结构
 type KvData struct {
 	Key   string `json:"key"`
@@ -23,32 +35,81 @@ type KvResponse struct {
 	Data *KvData `json:"data,omitempty"`
 }
 
+// KvHTTPError KV服务返回了错误的HTTP状态码
+type KvHTTPError struct {
+	StatusCode int
+	Code       int
+	Msg        string
+}
+
+// Error 实现 error 接口
+func (e *KvHTTPError) Error() string {
+	if e.Msg != "" {
+		return fmt.Sprintf("KV请求失败，状态码: %d, code: %d, msg: %s", e.StatusCode, e.Code, e.Msg)
+	}
+	return fmt.Sprintf("KV请求失败，状态码: %d", e.StatusCode)
+}
+
+// KvDecodeError KV服务返回了无法解析的响应（如HTML错误页）
+type KvDecodeError struct {
+	StatusCode  int
+	ContentType string
+	Body        string
+	Err         error
+}
+
+// Error 实现 error 接口
+func (e *KvDecodeError) Error() string {
+	return fmt.Sprintf("KV响应不是有效的JSON（状态码: %d, Content-Type: %s）: %v", e.StatusCode, e.ContentType, e.Err)
+}
+
+// Unwrap 返回原始解析错误
+func (e *KvDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// kvChunkManifest 分块存储清单
+type kvChunkManifest struct {
+	Chunks int    `json:"chunks"`
+	Size   int    `json:"size"`
+	MD5    string `json:"md5"`
+}
+
 // KvService KV存储服务
 type KvService struct {
-	client *http.Client
+	client  *http.Client
+	baseURL string
 }
 
 // NewKvService 创建KV服务实例
 func NewKvService() *KvService {
 	return &KvService{
-		client: &http.Client{},
+		client:  &http.Client{},
+		baseURL: KV_BASE_URL,
 	}
 }
 
-// request 通用请求方法
+// request 通用请求方法，key放在查询参数中，value以JSON请求体发送（避免密码等内容出现在URL中）
 func (s *KvService) request(method, authorization, key, value string) (*KvResponse, error) {
-	reqURL := fmt.Sprintf("%s?key=%s", KV_BASE_URL, url.QueryEscape(key))
-	if value != "" {
-		reqURL = fmt.Sprintf("%s&value=%s", reqURL, url.QueryEscape(value))
+	reqURL := fmt.Sprintf("%s?key=%s", s.baseURL, url.QueryEscape(key))
+
+	var body io.Reader
+	if method == http.MethodPost || method == http.MethodPut {
+		payload, err := json.Marshal(KvData{Key: key, Value: value})
+		if err != nil {
+			return nil, fmt.Errorf("序列化KV请求失败: %v", err)
+		}
+		body = bytes.NewReader(payload)
 	}
 
-	req, err := http.NewRequest(method, reqURL, nil)
+	req, err := http.NewRequest(method, reqURL, body)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", authorization))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
 
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -56,35 +117,186 @@ func (s *KvService) request(method, authorization, key, value string) (*KvRespon
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
 	var kvResp KvResponse
-	if err := json.Unmarshal(body, &kvResp); err != nil {
-		return nil, err
+	if err := json.Unmarshal(respBody, &kvResp); err != nil {
+		// 401 即使不是JSON也按未授权处理，保持上层的登出逻辑
+		if resp.StatusCode == http.StatusUnauthorized {
+			return &KvResponse{Code: 401, Msg: "Unauthorized"}, nil
+		}
+		snippet := string(respBody)
+		if len(snippet) > 200 {
+			snippet = snippet[:200]
+		}
+		return nil, &KvDecodeError{
+			StatusCode:  resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        snippet,
+			Err:         err,
+		}
+	}
+
+	// 401 和 404 是业务层需要区分的状态，作为响应返回；其余错误状态码返回类型化错误
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusNotFound {
+		return nil, &KvHTTPError{StatusCode: resp.StatusCode, Code: kvResp.Code, Msg: kvResp.Msg}
 	}
 
 	return &kvResp, nil
 }
 
+// chunkKey 分块的key
+func chunkKey(key string, index int) string {
+	return fmt.Sprintf("%s.chunk.%d", key, index)
+}
+
+// splitChunks 按字节大小切分字符串，保证不拆开UTF-8字符
+func splitChunks(value string, size int) []string {
+	var chunks []string
+	for len(value) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(value[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			cut = size
+		}
+		chunks = append(chunks, value[:cut])
+		value = value[cut:]
+	}
+	return append(chunks, value)
+}
+
+// parseChunkManifest 解析分块清单，不是清单时返回 nil
+func parseChunkManifest(value string) *kvChunkManifest {
+	if !strings.HasPrefix(value, kvChunkMarker) {
+		return nil
+	}
+	var manifest kvChunkManifest
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(value, kvChunkMarker)), &manifest); err != nil {
+		return nil
+	}
+	return &manifest
+}
+
+// write 写入键值对，超过分块大小时先写入各分块再写入清单；写入成功后删除旧值多出的分块
+func (s *KvService) write(method, key, value, authorization string) (*KvResponse, error) {
+	oldChunks := 0
+	if resp, err := s.request(http.MethodGet, authorization, key, ""); err == nil && resp.Data != nil {
+		if manifest := parseChunkManifest(resp.Data.Value); manifest != nil {
+			oldChunks = manifest.Chunks
+		}
+	}
+
+	var chunks []string
+	stored := value
+	if len(value) > kvChunkSize {
+		chunks = splitChunks(value, kvChunkSize)
+		for i, chunk := range chunks {
+			resp, err := s.request(http.MethodPut, authorization, chunkKey(key, i), chunk)
+			if err != nil {
+				return nil, fmt.Errorf("写入分块 %d/%d 失败: %v", i+1, len(chunks), err)
+			}
+			if resp.Code != 200 {
+				return resp, nil
+			}
+		}
+
+		manifest, err := json.Marshal(kvChunkManifest{Chunks: len(chunks), Size: len(value), MD5: utils.MD5(value)})
+		if err != nil {
+			return nil, err
+		}
+		stored = kvChunkMarker + string(manifest)
+	}
+
+	resp, err := s.request(method, authorization, key, stored)
+	if err != nil || resp.Code != 200 {
+		return resp, err
+	}
+	for i := len(chunks); i < oldChunks; i++ {
+		if _, err := s.request(http.MethodDelete, authorization, chunkKey(key, i), ""); err != nil {
+			log.Printf("Failed to delete stale chunk %s: %v", chunkKey(key, i), err)
+		}
+	}
+	return resp, nil
+}
+
 // CreateKey 创建键值对
 func (s *KvService) CreateKey(key, value, authorization string) (*KvResponse, error) {
-	return s.request("POST", authorization, key, value)
+	return s.write(http.MethodPost, key, value, authorization)
 }
 
-// GetKey 获取键值对
+// GetKey 获取键值对（自动合并分块存储的值）
 func (s *KvService) GetKey(key, authorization string) (*KvResponse, error) {
-	return s.request("GET", authorization, key, "")
+	resp, err := s.request(http.MethodGet, authorization, key, "")
+	if err != nil || resp.Data == nil {
+		return resp, err
+	}
+
+	manifest := parseChunkManifest(resp.Data.Value)
+	if manifest == nil {
+		return resp, nil
+	}
+
+	var builder strings.Builder
+	builder.Grow(manifest.Size)
+	for i := 0; i < manifest.Chunks; i++ {
+		chunkResp, err := s.request(http.MethodGet, authorization, chunkKey(key, i), "")
+		if err != nil {
+			return nil, fmt.Errorf("读取分块 %d/%d 失败: %v", i+1, manifest.Chunks, err)
+		}
+		if chunkResp.Code == 401 {
+			return chunkResp, nil
+		}
+		if chunkResp.Data == nil {
+			return nil, fmt.Errorf("分块 %d/%d 缺失", i+1, manifest.Chunks)
+		}
+		builder.WriteString(chunkResp.Data.Value)
+	}
+
+	value := builder.String()
+	if utils.MD5(value) != manifest.MD5 {
+		return nil, fmt.Errorf("分块数据校验失败，可能正在被其他人写入")
+	}
+
+	resp.Data.Value = value
+	return resp, nil
+}
+
+// ETag 只读取主key得到值的ETag（与 contentETag 相同，不存在时为空）；分块存储时使用清单中的MD5，不读取各分块
+func (s *KvService) ETag(key, authorization string) (string, error) {
+	resp, err := s.request(http.MethodGet, authorization, key, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.Code == 401 {
+		return "", fmt.Errorf("KV读取未授权: %s", resp.Msg)
+	}
+	if resp.Code != 200 || resp.Data == nil {
+		return "", nil
+	}
+	if manifest := parseChunkManifest(resp.Data.Value); manifest != nil {
+		return manifest.MD5, nil
+	}
+	return utils.MD5(resp.Data.Value), nil
 }
 
 // UpdateKey 更新键值对
 func (s *KvService) UpdateKey(key, value, authorization string) (*KvResponse, error) {
-	return s.request("PUT", authorization, key, value)
+	return s.write(http.MethodPut, key, value, authorization)
 }
 
-// DeleteKey 删除键值对
+// DeleteKey 删除键值对（分块存储时同时删除各分块）
 func (s *KvService) DeleteKey(key, authorization string) (*KvResponse, error) {
-	return s.request("DELETE", authorization, key, "")
+	if resp, err := s.request(http.MethodGet, authorization, key, ""); err == nil && resp.Data != nil {
+		if manifest := parseChunkManifest(resp.Data.Value); manifest != nil {
+			for i := 0; i < manifest.Chunks; i++ {
+				s.request(http.MethodDelete, authorization, chunkKey(key, i), "")
+			}
+		}
+	}
+	return s.request(http.MethodDelete, authorization, key, "")
 }
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeKv 内存中的KV服务，putCode 非0时写入返回该业务码；afterPut 在写入后调用（已持有锁），模拟其他客户端紧接着写入
+type fakeKv struct {
+	mutex    sync.Mutex
+	values   map[string]string
+	putCode  int
+	afterPut func(key string)
+	gets     []string
+}
+
+func (f *fakeKv) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := r.URL.Query().Get("key")
+	resp := KvResponse{Code: 200}
+	switch r.Method {
+	case http.MethodGet:
+		f.gets = append(f.gets, key)
+		if value, ok := f.values[key]; ok {
+			resp.Data = &KvData{Key: key, Value: value}
+		} else {
+			resp.Code = 404
+		}
+	case http.MethodPost, http.MethodPut:
+		if f.putCode != 0 {
+			resp.Code = f.putCode
+			resp.Msg = "rejected"
+			break
+		}
+		var data KvData
+		json.NewDecoder(r.Body).Decode(&data)
+		f.values[key] = data.Value
+		if f.afterPut != nil {
+			f.afterPut(key)
+		}
+	case http.MethodDelete:
+		delete(f.values, key)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeKv) keys() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	keys := make([]string, 0, len(f.values))
+	for key := range f.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newFakeKvStore(t *testing.T) (*KvStore, *fakeKv) {
+	t.Helper()
+	fake := &fakeKv{values: make(map[string]string)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return NewKvStore(&KvService{client: server.Client(), baseURL: server.URL}), fake
+}
+
+func TestKvChunkedWrites(t *testing.T) {
+	store, fake := newFakeKvStore(t)
+
+	steps := []struct {
+		name  string
+		value string
+		keys  []string
+	}{
+		{"small", "small", []string{"k"}},
+		{"three chunks", strings.Repeat("a", 2*kvChunkSize+10), []string{"k", "k.chunk.0", "k.chunk.1", "k.chunk.2"}},
+		{"two chunks", strings.Repeat("中", kvChunkSize/2), []string{"k", "k.chunk.0", "k.chunk.1"}},
+		{"back to small", "small again", []string{"k"}},
+		{"exactly chunk size", strings.Repeat("b", kvChunkSize), []string{"k"}},
+	}
+	for _, step := range steps {
+		if err := store.Put("k", step.value, ""); err != nil {
+			t.Fatalf("%s: Put: %v", step.name, err)
+		}
+		resp, err := store.Get("k", "")
+		if err != nil || resp.Data == nil || resp.Data.Value != step.value {
+			t.Fatalf("%s: Get returned a different value (err %v)", step.name, err)
+		}
+		if keys := fake.keys(); strings.Join(keys, ",") != strings.Join(step.keys, ",") {
+			t.Fatalf("%s: stored keys = %v; want %v", step.name, keys, step.keys)
+		}
+	}
+}
+
+func TestKvGetDetectsMixedChunks(t *testing.T) {
+	store, fake := newFakeKvStore(t)
+	if err := store.Put("k", strings.Repeat("a", kvChunkSize+1), ""); err != nil {
+		t.Fatal(err)
+	}
+	fake.values["k.chunk.1"] = "b"
+	if _, err := store.Get("k", ""); err == nil {
+		t.Fatalf("Get with a modified chunk succeeded")
+	}
+}
+
+func TestKvStorePutCodes(t *testing.T) {
+	tests := []struct {
+		code    int
+		wantErr bool
+	}{
+		{0, false},
+		{401, true},
+		{403, true},
+		{500, true},
+	}
+	for _, tt := range tests {
+		store, fake := newFakeKvStore(t)
+		fake.putCode = tt.code
+		if err := store.Put("k", "v", ""); (err != nil) != tt.wantErr {
+			t.Errorf("Put with code %d: err = %v; want error %v", tt.code, err, tt.wantErr)
+		}
+	}
+}
+
+func TestKvStorePutIfMatch(t *testing.T) {
+	chunked := strings.Repeat("a", 2*kvChunkSize+10)
+	tests := []struct {
+		name     string
+		initial  string // 为空表示 key 不存在
+		value    string
+		staleTag bool
+		race     bool // 写入后其他客户端立即覆盖
+		wantErr  bool
+	}{
+		{"create", "", "v1", false, false, false},
+		{"current etag", "v1", "v2", false, false, false},
+		{"stale etag", "v1", "v2", true, false, true},
+		{"chunked current etag", chunked, "v2", false, false, false},
+		{"write to chunked", "v1", chunked, false, false, false},
+		{"overwritten after write", "v1", "v2", false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, fake := newFakeKvStore(t)
+			etag := ""
+			if tt.initial != "" {
+				if err := store.Put("k", tt.initial, ""); err != nil {
+					t.Fatal(err)
+				}
+				resp, _ := store.Get("k", "")
+				etag = contentETag(resp)
+			}
+			if tt.staleTag {
+				etag = "stale"
+			}
+			if tt.race {
+				fake.afterPut = func(key string) {
+					if key == "k" {
+						fake.afterPut = nil
+						fake.values["k"] = "theirs"
+					}
+				}
+			}
+			fake.gets = nil
+
+			err := store.PutIfMatch("k", tt.value, etag, "")
+			if tt.wantErr {
+				if !errors.Is(err, ErrETagMismatch) {
+					t.Fatalf("PutIfMatch = %v; want ErrETagMismatch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PutIfMatch: %v", err)
+			}
+			// ETag 只读取主key，不读取分块
+			for _, key := range fake.gets {
+				if key != "k" {
+					t.Fatalf("PutIfMatch read %s; want only the main key", key)
+				}
+			}
+			if resp, _ := store.Get("k", ""); resp.Data.Value != tt.value {
+				t.Fatalf("stored value differs from the written one")
+			}
+		})
+	}
+}
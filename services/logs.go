@@ -0,0 +1,370 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultLogSource 未配置日志位置时使用的模板
+const DefaultLogSource = "{default_path}/{project_id}/logs/*.log"
+
+// dockerLogPrefix 日志位置以此开头时表示读取容器日志，其后为容器名模板
+const dockerLogPrefix = "docker:"
+
+// 日志跟踪事件名称（通过 Wails 事件发送到前端）
+const (
+	LogOutputEvent = "log_output"
+	LogClosedEvent = "log_closed"
+)
+
+// 日志行数限制
+const (
+	defaultLogLines = 200
+	maxLogLines     = 5000
+)
+
+// ProjectContainer 项目相关的容器
+type ProjectContainer struct {
+	Name   string `json:"name"`
+	Image  string `json:"image"`
+	State  string `json:"state"` // running、exited 等
+	Status string `json:"status"`
+}
+
+// ProjectProcess 项目相关的进程
+type ProjectProcess struct {
+	PID     int    `json:"pid"`
+	Command string `json:"command"`
+}
+
+// ProjectPortStatus 项目端口的监听状态
+type ProjectPortStatus struct {
+	Field     string `json:"field"` // api_port 或 front_port
+	Port      int    `json:"port"`
+	Listening bool   `json:"listening"`
+}
+
+// ProjectRuntimeStatus 项目在服务器上的运行状态
+type ProjectRuntimeStatus struct {
+	ServerID   string              `json:"server_id"`
+	ProjectID  string              `json:"project_id"`
+	Running    bool                `json:"running"` // 有运行中的容器、进程或端口在监听
+	Containers []ProjectContainer  `json:"containers"`
+	Processes  []ProjectProcess    `json:"processes"`
+	Ports      []ProjectPortStatus `json:"ports"`
+	LogSource  string              `json:"log_source"`
+	CheckedAt  string              `json:"checked_at"`
+}
+
+// LogOutput 日志跟踪输出的一行
+type LogOutput struct {
+	StreamID string `json:"stream_id"`
+	Line     string `json:"line"`
+}
+
+// LogClosed 日志跟踪结束事件
+type LogClosed struct {
+	StreamID string `json:"stream_id"`
+	Error    string `json:"error,omitempty"`
+}
+
+// LogStreamInfo 日志跟踪信息
+type LogStreamInfo struct {
+	StreamID  string `json:"stream_id"`
+	ServerID  string `json:"server_id"`
+	ProjectID string `json:"project_id"`
+	Command   string `json:"command"`
+	StartedAt string `json:"started_at"`
+}
+
+// logStream 运行中的日志跟踪
+type logStream struct {
+	info    LogStreamInfo
+	session *ssh.Session
+	release func()
+	once    sync.Once
+}
+
+// ProjectLogService 项目运行状态和日志服务
+//
+// 日志位置按服务器配置（ServerData.LogSource），支持以下占位符：
+//
+//	{default_path}  服务器部署目录
+//	{project_id}    项目ID
+//
+// 普通模板为文件路径（可含通配符），如 /var/log/{project_id}/*.log；
+// 以 docker: 开头时读取容器日志，如 docker:{project_id}_api
+type ProjectLogService struct {
+	ssh      *SSHService
+	mutex    sync.Mutex
+	streams  map[string]*logStream
+	callback func(event string, data interface{})
+}
+
+// NewProjectLogService 创建项目日志服务实例
+func NewProjectLogService(sshService *SSHService) *ProjectLogService {
+	return &ProjectLogService{
+		ssh:     sshService,
+		streams: make(map[string]*logStream),
+	}
+}
+
+// SetEventCallback 设置事件回调（用于向前端推送日志）
+func (s *ProjectLogService) SetEventCallback(callback func(event string, data interface{})) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callback = callback
+}
+
+// emit 发送事件
+func (s *ProjectLogService) emit(event string, data interface{}) {
+	s.mutex.Lock()
+	callback := s.callback
+	s.mutex.Unlock()
+
+	if callback != nil {
+		callback(event, data)
+	}
+}
+
+// logSource 服务器的日志位置模板
+func logSource(server *ServerData) string {
+	if strings.TrimSpace(server.LogSource) != "" {
+		return strings.TrimSpace(server.LogSource)
+	}
+	return DefaultLogSource
+}
+
+// expandLogSource 替换占位符；文件路径中的替换值加引号，保留模板中的通配符
+func expandLogSource(template string, server *ServerData, projectID string, quote bool) string {
+	defaultPath, id := server.DefaultPath, projectID
+	if quote {
+		defaultPath, id = ShellQuote(defaultPath), ShellQuote(id)
+	}
+	return strings.NewReplacer("{default_path}", defaultPath, "{project_id}", id).Replace(template)
+}
+
+// logCommand 读取项目日志的命令（follow 为 true 时持续输出新内容）
+func logCommand(server *ServerData, projectID string, lines int, follow bool) string {
+	if lines <= 0 {
+		lines = defaultLogLines
+	}
+	if lines > maxLogLines {
+		lines = maxLogLines
+	}
+
+	source := logSource(server)
+	if strings.HasPrefix(source, dockerLogPrefix) {
+		container := expandLogSource(strings.TrimPrefix(source, dockerLogPrefix), server, projectID, false)
+		command := fmt.Sprintf("docker logs --tail %d", lines)
+		if follow {
+			command += " -f"
+		}
+		return fmt.Sprintf("%s %s 2>&1", command, ShellQuote(container))
+	}
+
+	paths := expandLogSource(source, server, projectID, true)
+	if follow {
+		return fmt.Sprintf("tail -n %d -F %s 2>&1", lines, paths)
+	}
+	return fmt.Sprintf("tail -n %d %s 2>&1", lines, paths)
+}
+
+// Status 查询项目的容器、进程和端口监听状态
+func (s *ProjectLogService) Status(server *ServerData, project ProjectData) (*ProjectRuntimeStatus, error) {
+	apiPort, frontPort, err := ProjectPorts(project)
+	if err != nil {
+		return nil, err
+	}
+
+	// 进程匹配模式首字符加方括号，避免匹配到执行本脚本的shell自身
+	id := project.ProjectID
+	pattern := id
+	if id != "" {
+		pattern = "[" + id[:1] + "]" + id[1:]
+	}
+	script := fmt.Sprintf(`if command -v docker >/dev/null 2>&1; then
+  docker ps -a --filter name=%s --format 'C|{{.Names}}|{{.Image}}|{{.State}}|{{.Status}}' 2>/dev/null
+fi
+pgrep -af %s 2>/dev/null | sed 's/^/P|/'
+(ss -Htln 2>/dev/null || netstat -tln 2>/dev/null) | sed 's/^/L|/'
+`, ShellQuote(id), ShellQuote(pattern))
+
+	output, err := s.ssh.Run(server, script)
+	if err != nil && strings.TrimSpace(output) == "" {
+		return nil, fmt.Errorf("查询项目状态失败: %w", err)
+	}
+
+	status := &ProjectRuntimeStatus{
+		ServerID:   server.ServerID,
+		ProjectID:  project.ProjectID,
+		Containers: []ProjectContainer{},
+		Processes:  []ProjectProcess{},
+		Ports:      []ProjectPortStatus{},
+		LogSource:  logSource(server),
+		CheckedAt:  time.Now().Format("2006-01-02 15:04:05"),
+	}
+
+	var listening strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "C|"):
+			fields := strings.SplitN(line[2:], "|", 4)
+			if len(fields) == 4 {
+				status.Containers = append(status.Containers, ProjectContainer{Name: fields[0], Image: fields[1], State: fields[2], Status: fields[3]})
+				if fields[2] == "running" {
+					status.Running = true
+				}
+			}
+		case strings.HasPrefix(line, "P|"):
+			fields := strings.SplitN(strings.TrimSpace(line[2:]), " ", 2)
+			pid, err := strconv.Atoi(fields[0])
+			if err != nil || len(fields) < 2 {
+				continue
+			}
+			status.Processes = append(status.Processes, ProjectProcess{PID: pid, Command: fields[1]})
+			status.Running = true
+		case strings.HasPrefix(line, "L|"):
+			listening.WriteString(line[2:])
+			listening.WriteByte('\n')
+		}
+	}
+
+	ports := parseListeningPorts(listening.String())
+	for _, item := range []ProjectPortStatus{{Field: "api_port", Port: apiPort}, {Field: "front_port", Port: frontPort}} {
+		item.Listening = ports[item.Port]
+		if item.Listening {
+			status.Running = true
+		}
+		status.Ports = append(status.Ports, item)
+	}
+	return status, nil
+}
+
+// Tail 读取项目日志的最后 lines 行
+func (s *ProjectLogService) Tail(server *ServerData, projectID string, lines int) (string, error) {
+	command := logCommand(server, projectID, lines, false)
+	output, err := s.ssh.Run(server, command)
+	if err != nil {
+		return output, fmt.Errorf("读取日志失败（%s）: %w", command, err)
+	}
+	return output, nil
+}
+
+// Follow 开始跟踪项目日志：先输出最后 lines 行，之后的新内容逐行通过 log_output 事件推送，返回跟踪ID
+func (s *ProjectLogService) Follow(server *ServerData, projectID string, lines int) (LogStreamInfo, error) {
+	session, release, err := s.ssh.Session(server)
+	if err != nil {
+		return LogStreamInfo{}, fmt.Errorf("SSH连接失败: %w", err)
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		release()
+		return LogStreamInfo{}, err
+	}
+
+	command := logCommand(server, projectID, lines, true)
+	if err := session.Start(command); err != nil {
+		release()
+		return LogStreamInfo{}, fmt.Errorf("启动日志跟踪失败: %v", err)
+	}
+
+	stream := &logStream{
+		info: LogStreamInfo{
+			StreamID:  newID("log"),
+			ServerID:  server.ServerID,
+			ProjectID: projectID,
+			Command:   command,
+			StartedAt: time.Now().Format("2006-01-02 15:04:05"),
+		},
+		session: session,
+		release: release,
+	}
+
+	s.mutex.Lock()
+	s.streams[stream.info.StreamID] = stream
+	s.mutex.Unlock()
+
+	log.Printf("Following logs of project %s on %s: %s", projectID, server.ServerID, command)
+	go s.pump(stream, stdout)
+	return stream.info, nil
+}
+
+// pump 逐行推送日志，命令结束后清理
+func (s *ProjectLogService) pump(stream *logStream, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		s.emit(LogOutputEvent, LogOutput{StreamID: stream.info.StreamID, Line: scanner.Text()})
+	}
+
+	err := stream.session.Wait()
+	closed := LogClosed{StreamID: stream.info.StreamID}
+	if err != nil {
+		closed.Error = err.Error()
+	}
+	s.close(stream)
+	s.emit(LogClosedEvent, closed)
+}
+
+// close 关闭日志跟踪会话
+func (s *ProjectLogService) close(stream *logStream) {
+	stream.once.Do(func() {
+		s.mutex.Lock()
+		delete(s.streams, stream.info.StreamID)
+		s.mutex.Unlock()
+
+		stream.session.Signal(ssh.SIGTERM)
+		stream.session.Close()
+		stream.release()
+	})
+}
+
+// Stop 停止日志跟踪
+func (s *ProjectLogService) Stop(streamID string) error {
+	s.mutex.Lock()
+	stream := s.streams[streamID]
+	s.mutex.Unlock()
+	if stream == nil {
+		return fmt.Errorf("日志跟踪 %s 不存在", streamID)
+	}
+
+	log.Printf("Stopping log stream %s", streamID)
+	s.close(stream)
+	return nil
+}
+
+// List 列出正在跟踪的日志
+func (s *ProjectLogService) List() []LogStreamInfo {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	list := make([]LogStreamInfo, 0, len(s.streams))
+	for _, stream := range s.streams {
+		list = append(list, stream.info)
+	}
+	return list
+}
+
+// StopAll 停止所有日志跟踪（应用退出时调用）
+func (s *ProjectLogService) StopAll() {
+	s.mutex.Lock()
+	streams := make([]*logStream, 0, len(s.streams))
+	for _, stream := range s.streams {
+		streams = append(streams, stream)
+	}
+	s.mutex.Unlock()
+
+	for _, stream := range streams {
+		s.close(stream)
+	}
+}
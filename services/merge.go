@@ -0,0 +1,267 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrConflict 并发修改冲突
+var ErrConflict = errors.New("数据已被其他人修改")
+
+// ConflictError 并发修改冲突详情
+type ConflictError struct {
+	ServerIDs []string `json:"server_ids"`
+	Reason    string   `json:"reason"`
+}
+
+// Error 实现 error 接口
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: %s (服务器: %s)", ErrConflict, e.Reason, strings.Join(e.ServerIDs, ", "))
+}
+
+// Unwrap 支持 errors.Is(err, ErrConflict)
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// serverHeader 服务器除项目列表、版本号和连接状态以外的字段
+func serverHeader(server ServerData) ServerData {
+	server.ProjectList = nil
+	server.Revision = 0
+	server.ConnectionStatus = ""
+	server.LastTestTime = ""
+	server.LastTestResult = ""
+	return server
+}
+
+// serverStatus 服务器连接状态字段
+func serverStatus(server ServerData) [3]string {
+	return [3]string{server.ConnectionStatus, server.LastTestTime, server.LastTestResult}
+}
+
+// sameJSON 通过JSON序列化比较两个值是否相同
+func sameJSON(a, b interface{}) bool {
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aj) == string(bj)
+}
+
+// sameServer 比较两个服务器内容是否相同（忽略版本号）
+func sameServer(a, b *ServerData) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ac, bc := *a, *b
+	ac.Revision, bc.Revision = 0, 0
+	return sameJSON(ac, bc)
+}
+
+// sameServerConfig 比较两个服务器的配置是否相同（忽略版本号和连接状态）
+// 定时连通性测试只改连接状态，不应递增版本号，否则打开的编辑表单会误报冲突
+func sameServerConfig(a, b ServerData) bool {
+	a.ConnectionStatus, a.LastTestTime, a.LastTestResult = "", "", ""
+	b.ConnectionStatus, b.LastTestTime, b.LastTestResult = "", "", ""
+	return sameServer(&a, &b)
+}
+
+// indexServers 按服务器ID建立索引
+func indexServers(servers []ServerData) map[string]*ServerData {
+	index := make(map[string]*ServerData, len(servers))
+	for i := range servers {
+		index[servers[i].ServerID] = &servers[i]
+	}
+	return index
+}
+
+// indexProjects 按项目ID建立索引
+func indexProjects(projects []ProjectData) map[string]*ProjectData {
+	index := make(map[string]*ProjectData, len(projects))
+	for i := range projects {
+		index[projects[i].ProjectID] = &projects[i]
+	}
+	return index
+}
+
+// pickThreeWay 三方合并单个值：只有一方修改时取修改方，双方修改相同时取任一方，否则冲突
+func pickThreeWay(base, ours, theirs interface{}) (interface{}, bool) {
+	switch {
+	case sameJSON(ours, theirs):
+		return ours, true
+	case sameJSON(base, ours):
+		return theirs, true
+	case sameJSON(base, theirs):
+		return ours, true
+	default:
+		return nil, false
+	}
+}
+
+// mergeProjects 按项目ID三方合并项目列表
+func mergeProjects(base, ours, theirs []ProjectData) ([]ProjectData, bool) {
+	baseIndex := indexProjects(base)
+	oursIndex := indexProjects(ours)
+	theirsIndex := indexProjects(theirs)
+
+	// 保持对方（最新存储）的顺序，再追加我方新增的项目
+	order := make([]string, 0, len(theirs)+len(ours))
+	seen := make(map[string]bool)
+	for _, list := range [][]ProjectData{theirs, ours} {
+		for _, project := range list {
+			if !seen[project.ProjectID] {
+				seen[project.ProjectID] = true
+				order = append(order, project.ProjectID)
+			}
+		}
+	}
+
+	merged := make([]ProjectData, 0, len(order))
+	for _, id := range order {
+		picked, ok := pickThreeWay(baseIndex[id], oursIndex[id], theirsIndex[id])
+		if !ok {
+			return nil, false
+		}
+		if project, _ := picked.(*ProjectData); project != nil {
+			merged = append(merged, *project)
+		}
+	}
+	return merged, true
+}
+
+// mergeServer 三方合并同一台服务器（双方均有修改时按字段组合并）
+func mergeServer(base, ours, theirs ServerData) (ServerData, bool) {
+	header, ok := pickThreeWay(serverHeader(base), serverHeader(ours), serverHeader(theirs))
+	if !ok {
+		return ServerData{}, false
+	}
+
+	projects, ok := mergeProjects(base.ProjectList, ours.ProjectList, theirs.ProjectList)
+	if !ok {
+		return ServerData{}, false
+	}
+
+	merged := header.(ServerData)
+	merged.ProjectList = projects
+	merged.Revision = theirs.Revision
+
+	// 连接状态只是测试结果，双方都修改时以我方（最新测试）为准
+	if sameJSON(serverStatus(base), serverStatus(ours)) {
+		merged.ConnectionStatus, merged.LastTestTime, merged.LastTestResult = theirs.ConnectionStatus, theirs.LastTestTime, theirs.LastTestResult
+	} else {
+		merged.ConnectionStatus, merged.LastTestTime, merged.LastTestResult = ours.ConnectionStatus, ours.LastTestTime, ours.LastTestResult
+	}
+
+	return merged, true
+}
+
+// MergeServers 三方合并服务器列表
+// base 为修改前加载的数据，ours 为本次修改结果，theirs 为当前存储中的最新数据
+func MergeServers(base, ours, theirs []ServerData) ([]ServerData, error) {
+	baseIndex := indexServers(base)
+	oursIndex := indexServers(ours)
+	theirsIndex := indexServers(theirs)
+
+	order := make([]string, 0, len(theirs)+len(ours))
+	seen := make(map[string]bool)
+	for _, list := range [][]ServerData{theirs, ours} {
+		for _, server := range list {
+			if !seen[server.ServerID] {
+				seen[server.ServerID] = true
+				order = append(order, server.ServerID)
+			}
+		}
+	}
+
+	merged := make([]ServerData, 0, len(order))
+	var conflicts []string
+	for _, id := range order {
+		b, o, t := baseIndex[id], oursIndex[id], theirsIndex[id]
+
+		switch {
+		case sameServer(b, o):
+			// 我方未修改，采用对方版本（包括删除）
+			if t != nil {
+				merged = append(merged, *t)
+			}
+		case sameServer(b, t) || sameServer(o, t):
+			// 只有我方修改，或双方修改一致
+			if o != nil {
+				merged = append(merged, *o)
+			}
+		case b != nil && o != nil && t != nil:
+			server, ok := mergeServer(*b, *o, *t)
+			if !ok {
+				conflicts = append(conflicts, id)
+				continue
+			}
+			merged = append(merged, server)
+		default:
+			// 一方删除而另一方修改，或双方新增了同ID的不同服务器
+			conflicts = append(conflicts, id)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, &ConflictError{ServerIDs: conflicts, Reason: "同一服务器被同时修改"}
+	}
+	return merged, nil
+}
+
+// bumpRevisions 为相对 previous 发生变化的服务器和项目递增版本号
+func bumpRevisions(servers, previous []ServerData) {
+	previousIndex := indexServers(previous)
+	for i := range servers {
+		prev := previousIndex[servers[i].ServerID]
+		if prev == nil {
+			if servers[i].Revision < 1 {
+				servers[i].Revision = 1
+			}
+			bumpProjectRevisions(servers[i].ProjectList, nil)
+			continue
+		}
+		bumpProjectRevisions(servers[i].ProjectList, prev.ProjectList)
+		if !sameServerConfig(servers[i], *prev) {
+			servers[i].Revision = prev.Revision + 1
+		} else {
+			servers[i].Revision = prev.Revision
+		}
+	}
+}
+
+// bumpProjectRevisions 为相对 previous 发生变化的项目递增版本号
+func bumpProjectRevisions(projects, previous []ProjectData) {
+	previousIndex := indexProjects(previous)
+	for i := range projects {
+		prev := previousIndex[projects[i].ProjectID]
+		if prev == nil {
+			if projects[i].Revision < 1 {
+				projects[i].Revision = 1
+			}
+			continue
+		}
+		current := projects[i]
+		current.Revision = prev.Revision
+		if sameJSON(current, *prev) {
+			projects[i].Revision = prev.Revision
+		} else {
+			projects[i].Revision = prev.Revision + 1
+		}
+	}
+}
+
+// cloneServers 深拷贝服务器列表
+func cloneServers(servers []ServerData) []ServerData {
+	data, err := json.Marshal(servers)
+	if err != nil {
+		return append([]ServerData(nil), servers...)
+	}
+	var cloned []ServerData
+	if err := json.Unmarshal(data, &cloned); err != nil {
+		return append([]ServerData(nil), servers...)
+	}
+	if cloned == nil {
+		cloned = []ServerData{}
+	}
+	return cloned
+}
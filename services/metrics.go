@@ -0,0 +1,320 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsFile 指标时间序列文件名
+const metricsFile = "metrics.json"
+
+// 指标采集参数
+const (
+	maxMetricsSamples      = 720 // 每台服务器保留的采样数（每分钟一次约12小时）
+	metricsParallelism     = 4   // 同时采集的服务器数
+	minMetricsInterval     = 10  // 定时采集最小间隔（秒）
+	defaultMetricsInterval = 60
+)
+
+// 指标事件名称（通过 Wails 事件发送到前端）
+const MetricsSampleEvent = "metrics_sample"
+
+// metricsScript 采集脚本：两次读取 /proc/stat 计算CPU使用率，其余读取内存、负载、运行时间和部署目录磁盘
+const metricsScript = `echo '@@cpu'; head -n1 /proc/stat; sleep 1; head -n1 /proc/stat
+echo '@@mem'; grep -E '^(MemTotal|MemAvailable):' /proc/meminfo
+echo '@@load'; cat /proc/loadavg
+echo '@@uptime'; cat /proc/uptime
+echo '@@disk'; df -Pk %s 2>&1 | tail -n1
+`
+
+// MetricsSample 服务器资源采样
+type MetricsSample struct {
+	ServerID      string  `json:"server_id"`
+	Time          string  `json:"time"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemTotalKB    int64   `json:"mem_total_kb"`
+	MemUsedKB     int64   `json:"mem_used_kb"`
+	MemPercent    float64 `json:"mem_percent"`
+	DiskPath      string  `json:"disk_path"`
+	DiskTotalKB   int64   `json:"disk_total_kb"`
+	DiskUsedKB    int64   `json:"disk_used_kb"`
+	DiskPercent   float64 `json:"disk_percent"`
+	Load1         float64 `json:"load1"`
+	Load5         float64 `json:"load5"`
+	Load15        float64 `json:"load15"`
+	UptimeSeconds int64   `json:"uptime_seconds"`
+	Error         string  `json:"error,omitempty"` // 采集失败时的错误，其他字段为零值
+}
+
+// MetricsService 服务器资源指标采集服务，采样保存在本地数据目录
+type MetricsService struct {
+	ssh      *SSHService
+	mutex    sync.Mutex
+	path     string
+	samples  map[string][]MetricsSample
+	schedule scheduler
+	callback func(event string, data interface{})
+}
+
+// NewMetricsService 创建指标采集服务实例并加载本地保存的采样
+func NewMetricsService(sshService *SSHService) *MetricsService {
+	service := &MetricsService{
+		ssh:     sshService,
+		samples: make(map[string][]MetricsSample),
+	}
+	dir, err := AppDataDir()
+	if err != nil {
+		log.Printf("Metrics history disabled: %v", err)
+		return service
+	}
+	service.path = filepath.Join(dir, metricsFile)
+
+	data, err := os.ReadFile(service.path)
+	if err == nil {
+		if err := json.Unmarshal(data, &service.samples); err != nil {
+			log.Printf("Failed to parse metrics history: %v", err)
+			service.samples = make(map[string][]MetricsSample)
+		}
+	}
+	return service
+}
+
+// SetEventCallback 设置事件回调（用于向前端推送采样）
+func (s *MetricsService) SetEventCallback(callback func(event string, data interface{})) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callback = callback
+}
+
+// emit 发送事件
+func (s *MetricsService) emit(event string, data interface{}) {
+	s.mutex.Lock()
+	callback := s.callback
+	s.mutex.Unlock()
+
+	if callback != nil {
+		callback(event, data)
+	}
+}
+
+// parseMetrics 解析采集脚本的输出
+func parseMetrics(output string, sample *MetricsSample) error {
+	var cpu [][]int64
+	var memAvailable int64
+	section := ""
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "@@") {
+			section = line[2:]
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch section {
+		case "cpu":
+			if fields[0] != "cpu" {
+				continue
+			}
+			values := make([]int64, 0, len(fields)-1)
+			for _, field := range fields[1:] {
+				value, _ := strconv.ParseInt(field, 10, 64)
+				values = append(values, value)
+			}
+			cpu = append(cpu, values)
+		case "mem":
+			if len(fields) < 2 {
+				continue
+			}
+			value, _ := strconv.ParseInt(fields[1], 10, 64)
+			switch fields[0] {
+			case "MemTotal:":
+				sample.MemTotalKB = value
+			case "MemAvailable:":
+				memAvailable = value
+			}
+		case "load":
+			if len(fields) >= 3 {
+				sample.Load1, _ = strconv.ParseFloat(fields[0], 64)
+				sample.Load5, _ = strconv.ParseFloat(fields[1], 64)
+				sample.Load15, _ = strconv.ParseFloat(fields[2], 64)
+			}
+		case "uptime":
+			uptime, _ := strconv.ParseFloat(fields[0], 64)
+			sample.UptimeSeconds = int64(uptime)
+		case "disk":
+			// Filesystem 1024-blocks Used Available Capacity Mounted-on
+			if len(fields) < 6 {
+				return fmt.Errorf("读取磁盘信息失败: %s", line)
+			}
+			sample.DiskTotalKB, _ = strconv.ParseInt(fields[1], 10, 64)
+			sample.DiskUsedKB, _ = strconv.ParseInt(fields[2], 10, 64)
+			available, _ := strconv.ParseInt(fields[3], 10, 64)
+			// 与 df 一致，按 已用/(已用+可用) 计算（不含保留块）
+			if sample.DiskUsedKB+available > 0 {
+				sample.DiskPercent = percent(float64(sample.DiskUsedKB), float64(sample.DiskUsedKB+available))
+			}
+		}
+	}
+
+	if len(cpu) != 2 || len(cpu[0]) < 4 || len(cpu[1]) < 4 {
+		return fmt.Errorf("读取CPU信息失败")
+	}
+	// user nice system idle iowait irq softirq steal ...
+	var total, idle int64
+	for i := range cpu[1] {
+		if i >= len(cpu[0]) {
+			break
+		}
+		delta := cpu[1][i] - cpu[0][i]
+		total += delta
+		if i == 3 || i == 4 {
+			idle += delta
+		}
+	}
+	if total > 0 {
+		sample.CPUPercent = percent(float64(total-idle), float64(total))
+	}
+
+	if sample.MemTotalKB <= 0 {
+		return fmt.Errorf("读取内存信息失败")
+	}
+	sample.MemUsedKB = sample.MemTotalKB - memAvailable
+	sample.MemPercent = percent(float64(sample.MemUsedKB), float64(sample.MemTotalKB))
+	return nil
+}
+
+// percent 百分比，保留一位小数
+func percent(part, total float64) float64 {
+	return float64(int64(part/total*1000+0.5)) / 10
+}
+
+// Collect 采集一台服务器的资源指标并保存（采集失败时也会保存一条带错误的采样）
+func (s *MetricsService) Collect(server *ServerData) MetricsSample {
+	sample := MetricsSample{ServerID: server.ServerID, DiskPath: server.DefaultPath}
+	if sample.DiskPath == "" {
+		sample.DiskPath = "/"
+	}
+
+	output, err := s.ssh.Run(server, fmt.Sprintf(metricsScript, ShellQuote(sample.DiskPath)))
+	if err == nil {
+		err = parseMetrics(output, &sample)
+	}
+	if err != nil {
+		log.Printf("Failed to collect metrics from %s: %v", server.ServerID, err)
+		sample = MetricsSample{ServerID: server.ServerID, DiskPath: sample.DiskPath, Error: err.Error()}
+	}
+	sample.Time = time.Now().Format("2006-01-02 15:04:05")
+
+	s.mutex.Lock()
+	series := append(s.samples[server.ServerID], sample)
+	if len(series) > maxMetricsSamples {
+		series = series[len(series)-maxMetricsSamples:]
+	}
+	s.samples[server.ServerID] = series
+	s.mutex.Unlock()
+
+	s.emit(MetricsSampleEvent, sample)
+	return sample
+}
+
+// CollectAll 并发采集多台服务器（并发数有限），采集完成后写入本地文件
+func (s *MetricsService) CollectAll(servers []ServerData) []MetricsSample {
+	samples := make([]MetricsSample, len(servers))
+	slots := make(chan struct{}, metricsParallelism)
+	var wg sync.WaitGroup
+	for i := range servers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			samples[i] = s.Collect(&servers[i])
+		}(i)
+	}
+	wg.Wait()
+
+	if err := s.save(); err != nil {
+		log.Printf("Failed to save metrics history: %v", err)
+	}
+	return samples
+}
+
+// save 写入本地文件
+func (s *MetricsService) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.mutex.Lock()
+	data, err := json.Marshal(s.samples)
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0600)
+}
+
+// History 查询服务器的采样（按时间顺序），since 为空表示全部
+func (s *MetricsService) History(serverID, since string) []MetricsSample {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	samples := []MetricsSample{}
+	for _, sample := range s.samples[serverID] {
+		// 时间格式固定，可直接按字符串比较
+		if since == "" || sample.Time >= since {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// Latest 每台服务器最近一次采样
+func (s *MetricsService) Latest() map[string]MetricsSample {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	latest := make(map[string]MetricsSample, len(s.samples))
+	for serverID, series := range s.samples {
+		if len(series) > 0 {
+			latest[serverID] = series[len(series)-1]
+		}
+	}
+	return latest
+}
+
+// StartSchedule 按间隔定时采集，load 在每次采集前获取服务器列表；已在运行时按新间隔重新开始
+func (s *MetricsService) StartSchedule(intervalSeconds int, load func() ([]ServerData, error)) ScheduleStatus {
+	intervalSeconds = clampInterval(intervalSeconds, defaultMetricsInterval, minMetricsInterval)
+	log.Printf("Metrics collection scheduled every %ds", intervalSeconds)
+	return s.schedule.Start(intervalSeconds, func() {
+		servers, err := load()
+		if err != nil {
+			log.Printf("Metrics schedule failed to load servers: %v", err)
+			return
+		}
+		s.CollectAll(servers)
+	})
+}
+
+// StopSchedule 停止定时采集
+func (s *MetricsService) StopSchedule() {
+	if s.schedule.Stop() {
+		log.Printf("Metrics collection schedule stopped")
+	}
+}
+
+// Schedule 定时采集状态
+func (s *MetricsService) Schedule() ScheduleStatus {
+	return s.schedule.Status()
+}
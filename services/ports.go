@@ -0,0 +1,259 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 项目未设置端口时使用的默认端口
+const (
+	DefaultAPIPort   = 9000
+	DefaultFrontPort = 3000
+)
+
+// maxPortSearch 建议端口时最多尝试的偏移量
+const maxPortSearch = 1000
+
+// ErrPortConflict 端口冲突
+var ErrPortConflict = errors.New("端口冲突")
+
+// PortConflict 单个端口冲突
+type PortConflict struct {
+	Field             string `json:"field"`                         // api_port 或 front_port
+	Port              int    `json:"port"`                          // 冲突的端口
+	Source            string `json:"source"`                        // project：与其他项目配置冲突；remote：远程主机上已被占用
+	ConflictProjectID string `json:"conflict_project_id,omitempty"` // 冲突的项目ID（source 为 project 时）
+}
+
+// PortSuggestion 建议的下一组空闲端口
+type PortSuggestion struct {
+	APIPort   int `json:"api_port"`
+	FrontPort int `json:"front_port"`
+}
+
+// PortConflictError 保存项目时的端口冲突详情
+type PortConflictError struct {
+	ServerID   string          `json:"server_id"`
+	ProjectID  string          `json:"project_id"`
+	Conflicts  []PortConflict  `json:"conflicts"`
+	Suggestion *PortSuggestion `json:"suggestion,omitempty"`
+}
+
+// Error 实现 error 接口
+func (e *PortConflictError) Error() string {
+	parts := make([]string, 0, len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		if conflict.Source == "remote" {
+			parts = append(parts, fmt.Sprintf("%s %d 已被远程主机占用", conflict.Field, conflict.Port))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s %d 与项目 %s 冲突", conflict.Field, conflict.Port, conflict.ConflictProjectID))
+		}
+	}
+	return fmt.Sprintf("%v: %s", ErrPortConflict, strings.Join(parts, "; "))
+}
+
+// Unwrap 支持 errors.Is(err, ErrPortConflict)
+func (e *PortConflictError) Unwrap() error {
+	return ErrPortConflict
+}
+
+// parsePort 解析端口，为空时使用默认端口
+func parsePort(value string, defaultPort int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultPort, nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("端口无效: %s", value)
+	}
+	return port, nil
+}
+
+// ProjectPorts 项目实际使用的端口（未设置时为默认端口）
+func ProjectPorts(project ProjectData) (apiPort, frontPort int, err error) {
+	if apiPort, err = parsePort(project.APIPort, DefaultAPIPort); err != nil {
+		return 0, 0, fmt.Errorf("项目 %s 的 API 端口无效: %s", project.ProjectID, project.APIPort)
+	}
+	if frontPort, err = parsePort(project.FrontPort, DefaultFrontPort); err != nil {
+		return 0, 0, fmt.Errorf("项目 %s 的前端端口无效: %s", project.ProjectID, project.FrontPort)
+	}
+	return apiPort, frontPort, nil
+}
+
+// usedPorts 服务器上除指定项目以外的项目占用的端口（端口 -> 项目ID）
+func usedPorts(server *ServerData, excludeProjectID string) map[int]string {
+	used := make(map[int]string)
+	for _, project := range server.ProjectList {
+		if project.ProjectID == excludeProjectID {
+			continue
+		}
+		apiPort, frontPort, err := ProjectPorts(project)
+		if err != nil {
+			continue
+		}
+		used[apiPort] = project.ProjectID
+		used[frontPort] = project.ProjectID
+	}
+	return used
+}
+
+// ownPorts 项目当前已保存的端口，远程主机上这些端口被监听属于正常情况
+func ownPorts(server *ServerData, projectID string) map[int]bool {
+	own := make(map[int]bool)
+	for _, project := range server.ProjectList {
+		if project.ProjectID != projectID {
+			continue
+		}
+		if apiPort, frontPort, err := ProjectPorts(project); err == nil {
+			own[apiPort] = true
+			own[frontPort] = true
+		}
+	}
+	return own
+}
+
+// CheckProjectPorts 检查项目端口是否与同一服务器上的其他项目或远程已监听的端口冲突
+// listening 为 nil 时不检查远程端口；无冲突时返回 nil
+func CheckProjectPorts(server *ServerData, project ProjectData, listening map[int]bool) error {
+	apiPort, frontPort, err := ProjectPorts(project)
+	if err != nil {
+		return err
+	}
+
+	conflicts := []PortConflict{}
+	if apiPort == frontPort {
+		conflicts = append(conflicts, PortConflict{Field: "front_port", Port: frontPort, Source: "project", ConflictProjectID: project.ProjectID})
+	}
+
+	used := usedPorts(server, project.ProjectID)
+	own := ownPorts(server, project.ProjectID)
+	for _, item := range []struct {
+		field string
+		port  int
+	}{{"api_port", apiPort}, {"front_port", frontPort}} {
+		if other, ok := used[item.port]; ok {
+			conflicts = append(conflicts, PortConflict{Field: item.field, Port: item.port, Source: "project", ConflictProjectID: other})
+		} else if listening[item.port] && !own[item.port] {
+			conflicts = append(conflicts, PortConflict{Field: item.field, Port: item.port, Source: "remote"})
+		}
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+	suggestion, _ := SuggestProjectPorts(server, project.ProjectID, listening)
+	return &PortConflictError{
+		ServerID:   server.ServerID,
+		ProjectID:  project.ProjectID,
+		Conflicts:  conflicts,
+		Suggestion: suggestion,
+	}
+}
+
+// CheckChangedProjectPorts 检查保存项目时的端口冲突，只报告相对已保存项目 previous 发生变化的端口
+// 迁移时补上默认端口的旧项目之间可能已经冲突，未改动端口的编辑不应因此被拒绝；previous 为 nil 表示新项目
+func CheckChangedProjectPorts(server *ServerData, previous *ProjectData, project ProjectData, listening map[int]bool) error {
+	err := CheckProjectPorts(server, project, listening)
+	var conflict *PortConflictError
+	if previous == nil || !errors.As(err, &conflict) {
+		return err
+	}
+	oldAPIPort, oldFrontPort, oldErr := ProjectPorts(*previous)
+	if oldErr != nil {
+		return err
+	}
+	apiPort, frontPort, _ := ProjectPorts(project)
+	changed := map[string]bool{
+		"api_port":   apiPort != oldAPIPort,
+		"front_port": frontPort != oldFrontPort,
+	}
+
+	conflicts := []PortConflict{}
+	for _, item := range conflict.Conflicts {
+		// API 端口与前端端口相同时任一端口改动都算
+		self := item.Source == "project" && item.ConflictProjectID == project.ProjectID
+		if changed[item.Field] || (self && (changed["api_port"] || changed["front_port"])) {
+			conflicts = append(conflicts, item)
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	conflict.Conflicts = conflicts
+	return conflict
+}
+
+// SuggestProjectPorts 从默认端口开始按相同偏移查找下一组空闲的 API/前端端口
+func SuggestProjectPorts(server *ServerData, excludeProjectID string, listening map[int]bool) (*PortSuggestion, error) {
+	used := usedPorts(server, excludeProjectID)
+	own := ownPorts(server, excludeProjectID)
+	free := func(port int) bool {
+		if _, ok := used[port]; ok {
+			return false
+		}
+		return !listening[port] || own[port]
+	}
+
+	for offset := 0; offset < maxPortSearch; offset++ {
+		apiPort, frontPort := DefaultAPIPort+offset, DefaultFrontPort+offset
+		if free(apiPort) && free(frontPort) {
+			return &PortSuggestion{APIPort: apiPort, FrontPort: frontPort}, nil
+		}
+	}
+	return nil, fmt.Errorf("服务器 %s 上没有可用的端口", server.ServerID)
+}
+
+// PortService 远程端口探测服务
+type PortService struct {
+	ssh *SSHService
+}
+
+// NewPortService 创建端口探测服务实例
+func NewPortService(sshService *SSHService) *PortService {
+	return &PortService{ssh: sshService}
+}
+
+// ListeningPorts 通过SSH获取远程主机正在监听的TCP端口
+func (s *PortService) ListeningPorts(server *ServerData) (map[int]bool, error) {
+	output, err := s.ssh.Run(server, "ss -Htln 2>/dev/null || netstat -tln 2>/dev/null")
+	if err != nil {
+		return nil, fmt.Errorf("获取监听端口失败: %w: %s", err, strings.TrimSpace(output))
+	}
+	return parseListeningPorts(output), nil
+}
+
+// parseListeningPorts 解析 ss/netstat 输出中的本地监听端口
+func parseListeningPorts(output string) map[int]bool {
+	ports := make(map[int]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		for _, field := range fields {
+			// 本地地址形如 0.0.0.0:80、[::]:80、*:80、:::80
+			index := strings.LastIndex(field, ":")
+			if index < 0 || index == len(field)-1 {
+				continue
+			}
+			port, err := strconv.Atoi(field[index+1:])
+			if err != nil || port < 1 || port > 65535 {
+				continue
+			}
+			ports[port] = true
+			break
+		}
+	}
+	return ports
+}
+
+// SortedPorts 端口集合转为有序列表
+func SortedPorts(ports map[int]bool) []int {
+	list := make([]int, 0, len(ports))
+	for port := range ports {
+		list = append(list, port)
+	}
+	sort.Ints(list)
+	return list
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckProjectPorts(t *testing.T) {
+	server := &ServerData{ServerID: "a", ProjectList: []ProjectData{
+		{ProjectID: "p1", APIPort: "9001", FrontPort: "3001"},
+		{ProjectID: "p2", APIPort: "9002", FrontPort: "3002"},
+	}}
+	tests := []struct {
+		name      string
+		project   ProjectData
+		listening map[int]bool
+		want      []PortConflict
+	}{
+		{"free ports", ProjectData{ProjectID: "p3", APIPort: "9003", FrontPort: "3003"}, nil, nil},
+		{"own ports", ProjectData{ProjectID: "p1", APIPort: "9001", FrontPort: "3001"}, map[int]bool{9001: true, 3001: true}, nil},
+		{"other project", ProjectData{ProjectID: "p3", APIPort: "9002", FrontPort: "3003"}, nil,
+			[]PortConflict{{Field: "api_port", Port: 9002, Source: "project", ConflictProjectID: "p2"}}},
+		{"same api and front port", ProjectData{ProjectID: "p3", APIPort: "9003", FrontPort: "9003"}, nil,
+			[]PortConflict{{Field: "front_port", Port: 9003, Source: "project", ConflictProjectID: "p3"}}},
+		{"remote listening", ProjectData{ProjectID: "p3", APIPort: "9003", FrontPort: "3003"}, map[int]bool{3003: true},
+			[]PortConflict{{Field: "front_port", Port: 3003, Source: "remote"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckProjectPorts(server, tt.project, tt.listening)
+			var conflict *PortConflictError
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("CheckProjectPorts = %v; want nil", err)
+				}
+				return
+			}
+			if !errors.As(err, &conflict) || !sameJSON(conflict.Conflicts, tt.want) {
+				t.Fatalf("CheckProjectPorts = %v; want conflicts %+v", err, tt.want)
+			}
+			if conflict.Suggestion == nil {
+				t.Fatalf("conflict without suggestion")
+			}
+		})
+	}
+}
+
+func TestAddOrUpdateProjectPorts(t *testing.T) {
+	// 迁移前未设置端口的旧项目，加载后都使用默认端口 9000/3000
+	legacy := func() []ServerData {
+		return []ServerData{{ServerID: "a", ServerIP: "10.0.0.1", DefaultPath: "/srv", ProjectList: []ProjectData{
+			{ProjectID: "p1", ProjectName: "one"},
+			{ProjectID: "p2", ProjectName: "two"},
+			{ProjectID: "p3", ProjectName: "three", APIPort: "9003", FrontPort: "3003"},
+		}}}
+	}
+	tests := []struct {
+		name     string
+		project  ProjectData
+		conflict bool
+	}{
+		{"legacy edit keeps ports", ProjectData{ProjectID: "p1", ProjectName: "renamed", APIPort: "9000", FrontPort: "3000"}, false},
+		{"legacy edit only changes front port", ProjectData{ProjectID: "p1", ProjectName: "one", APIPort: "9000", FrontPort: "3010"}, false},
+		{"changed port conflicts", ProjectData{ProjectID: "p1", ProjectName: "one", APIPort: "9003", FrontPort: "3000"}, true},
+		{"changed to same api and front port", ProjectData{ProjectID: "p3", ProjectName: "three", APIPort: "9003", FrontPort: "9003"}, true},
+		{"new project with used port", ProjectData{ProjectID: "p4", ProjectName: "four", APIPort: "9000", FrontPort: "3004"}, true},
+		{"new project with free ports", ProjectData{ProjectID: "p4", ProjectName: "four", APIPort: "9004", FrontPort: "3004"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			seedServers(t, store, legacy())
+			service := NewJsonServiceWithStore(store)
+			if _, err := service.LoadJsonFile("", testNamespace); err != nil {
+				t.Fatal(err)
+			}
+
+			err := service.AddOrUpdateProject("a", tt.project, "", testNamespace)
+			if got := errors.Is(err, ErrPortConflict); got != tt.conflict {
+				t.Fatalf("AddOrUpdateProject = %v; port conflict = %v, want %v", err, got, tt.conflict)
+			}
+			if tt.conflict {
+				return
+			}
+			servers, _ := service.LoadJsonFile("", testNamespace)
+			if project := findProject(servers, "a", tt.project.ProjectID); project == nil || project.ProjectName != tt.project.ProjectName {
+				t.Fatalf("project after save = %+v", project)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+)
+
+// ProjectConfigFile 服务器上项目配置文件名（位于 DefaultPath 下）
+const ProjectConfigFile = "project_config.json"
+
+// projectConfigFields project_config.json 中每个项目需要比较的字段
+var projectConfigFields = []string{"api_port", "web_port", "api_domain"}
+
+// ProjectConfigChange 单个项目字段的变化
+type ProjectConfigChange struct {
+	ProjectID string `json:"project_id"`
+	Field     string `json:"field"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// ProjectConfigPlan 上传 project_config.json 前的变更计划
+type ProjectConfigPlan struct {
+	ServerID        string                       `json:"server_id"`
+	Path            string                       `json:"path"`
+	RemoteExists    bool                         `json:"remote_exists"`
+	RemoteSHA256    string                       `json:"remote_sha256"` // 应用时用于确认远程文件未被修改
+	AddedProjects   []string                     `json:"added_projects"`
+	RemovedProjects []string                     `json:"removed_projects"`
+	Changes         []ProjectConfigChange        `json:"changes"`
+	Unchanged       bool                         `json:"unchanged"`
+	Config          map[string]map[string]string `json:"config"`
+}
+
+// ContentSHA256 计算内容的SHA-256
+func ContentSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseProjectConfig 解析 project_config.json（字段值统一转为字符串，端口写成数字时也能比较）
+func ParseProjectConfig(data []byte) (map[string]map[string]string, error) {
+	var raw map[string]map[string]interface{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return map[string]map[string]string{}, nil
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析项目配置失败: %v", err)
+	}
+
+	config := make(map[string]map[string]string, len(raw))
+	for projectID, fields := range raw {
+		config[projectID] = make(map[string]string, len(fields))
+		for name, value := range fields {
+			if value == nil {
+				config[projectID][name] = ""
+				continue
+			}
+			config[projectID][name] = fmt.Sprintf("%v", value)
+		}
+	}
+	return config, nil
+}
+
+// PlanProjectConfig 比较远程配置（remote 为 nil 表示远程文件不存在）与将要上传的配置
+func PlanProjectConfig(server *ServerData, remote []byte, proposed map[string]map[string]string) (*ProjectConfigPlan, error) {
+	plan := &ProjectConfigPlan{
+		ServerID:        server.ServerID,
+		Path:            path.Join(server.DefaultPath, ProjectConfigFile),
+		AddedProjects:   []string{},
+		RemovedProjects: []string{},
+		Changes:         []ProjectConfigChange{},
+		Config:          proposed,
+	}
+
+	current := map[string]map[string]string{}
+	if remote != nil {
+		plan.RemoteExists = true
+		plan.RemoteSHA256 = ContentSHA256(remote)
+		parsed, err := ParseProjectConfig(remote)
+		if err != nil {
+			return nil, fmt.Errorf("远程 %s 格式错误: %v", ProjectConfigFile, err)
+		}
+		current = parsed
+	}
+
+	for projectID := range proposed {
+		if _, ok := current[projectID]; !ok {
+			plan.AddedProjects = append(plan.AddedProjects, projectID)
+		}
+	}
+	for projectID, fields := range current {
+		next, ok := proposed[projectID]
+		if !ok {
+			plan.RemovedProjects = append(plan.RemovedProjects, projectID)
+			continue
+		}
+		for _, field := range projectConfigFields {
+			if fields[field] != next[field] {
+				plan.Changes = append(plan.Changes, ProjectConfigChange{
+					ProjectID: projectID,
+					Field:     field,
+					From:      fields[field],
+					To:        next[field],
+				})
+			}
+		}
+	}
+
+	sort.Strings(plan.AddedProjects)
+	sort.Strings(plan.RemovedProjects)
+	sort.Slice(plan.Changes, func(i, j int) bool {
+		if plan.Changes[i].ProjectID != plan.Changes[j].ProjectID {
+			return plan.Changes[i].ProjectID < plan.Changes[j].ProjectID
+		}
+		return plan.Changes[i].Field < plan.Changes[j].Field
+	})
+
+	plan.Unchanged = plan.RemoteExists && len(plan.AddedProjects) == 0 &&
+		len(plan.RemovedProjects) == 0 && len(plan.Changes) == 0
+	return plan, nil
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultReleaseKeep 每台服务器默认保留的发布版本数量（可通过 ADSPLAT_RELEASE_KEEP 覆盖）
+const defaultReleaseKeep = 5
+
+// releasesDir 发布版本目录（相对 DefaultPath）
+const releasesDir = "releases"
+
+// ReleaseInfo 服务器上的一个发布版本
+type ReleaseInfo struct {
+	Name      string `json:"name"`
+	Current   bool   `json:"current"`
+	SHA256    string `json:"sha256,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ReleaseService 版本化发布服务：每次发布解压到 releases/<时间戳>，通过 current 符号链接切换版本
+//
+// 目录结构（DefaultPath 下）：
+//
+//	releases/20250101120000/      解压后的发布内容
+//	releases/20250101120000.sha256 发布包的SHA-256
+//	current -> releases/20250101120000
+//
+// codedeploy.sh 仍在 DefaultPath 下执行，切换版本时会把对应版本的内容同步回 DefaultPath（删除旧版本独有的文件）
+type ReleaseService struct {
+	ssh *SSHService
+}
+
+// NewReleaseService 创建发布服务实例
+func NewReleaseService(sshService *SSHService) *ReleaseService {
+	return &ReleaseService{ssh: sshService}
+}
+
+// releaseKeep 保留的发布版本数量
+func releaseKeep() int {
+	if value, err := strconv.Atoi(os.Getenv("ADSPLAT_RELEASE_KEEP")); err == nil && value > 0 {
+		return value
+	}
+	return defaultReleaseKeep
+}
+
+// switchScript 切换 current 链接并把版本内容同步到 DefaultPath 的脚本片段（$name 为版本名）
+// 先删除上一个版本带来、新版本中没有的文件，再复制新版本；DefaultPath 下不属于发布包的文件（配置、项目数据）不受影响
+const switchScript = `prev=$(basename "$(readlink current 2>/dev/null)")
+ln -sfn "releases/$name" current.tmp && mv -Tf current.tmp current
+if [ -n "$prev" ] && [ "$prev" != "$name" ] && [ -d "releases/$prev" ]; then
+  (cd "releases/$prev" && find . -depth -mindepth 1) | while IFS= read -r f; do
+    [ -e "releases/$name/$f" ] || [ -L "releases/$name/$f" ] || rm -rf "./$f"
+  done
+fi
+cp -a "releases/$name/." ./
+`
+
+// Activate 把 DefaultPath 下的 release.zip 解压为新版本、切换 current 并清理旧版本，返回新版本名
+func (s *ReleaseService) Activate(server *ServerData) (string, error) {
+	script := fmt.Sprintf(`set -e
+cd %s
+mkdir -p %s
+name=$(date +%%Y%%m%%d%%H%%M%%S)
+i=1
+while [ -e "releases/$name" ]; do name="$(date +%%Y%%m%%d%%H%%M%%S)-$i"; i=$((i+1)); done
+mkdir "releases/$name"
+if ! unzip -o -q release.zip -d "releases/$name"; then rm -rf "releases/$name"; exit 1; fi
+(sha256sum release.zip | cut -d' ' -f1 > "releases/$name.sha256") || true
+%srm -f release.zip
+cur=$(basename "$(readlink current)")
+ls -1 releases | grep -v '\.sha256$' | sort -r | tail -n +%d | while read r; do
+  [ "$r" = "$cur" ] || rm -rf "releases/$r" "releases/$r.sha256"
+done
+echo "$name"
+`, ShellQuote(server.DefaultPath), releasesDir, switchScript, releaseKeep()+1)
+
+	output, err := s.ssh.Run(server, script)
+	if err != nil {
+		return "", fmt.Errorf("解压发布包失败: %v: %s", err, strings.TrimSpace(output))
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	name := strings.TrimSpace(lines[len(lines)-1])
+	log.Printf("Activated release %s on server %s", name, server.ServerID)
+	return name, nil
+}
+
+// List 列出服务器上的发布版本（按时间倒序）
+func (s *ReleaseService) List(server *ServerData) ([]ReleaseInfo, error) {
+	script := fmt.Sprintf(`cd %s || exit 1
+[ -d releases ] || exit 0
+cur=$(basename "$(readlink current 2>/dev/null)")
+for r in $(ls -1 releases | grep -v '\.sha256$'); do
+  [ -d "releases/$r" ] || continue
+  c=0; [ "$r" = "$cur" ] && c=1
+  echo "$r|$c|$(cat "releases/$r.sha256" 2>/dev/null)|$(stat -c %%Y "releases/$r" 2>/dev/null)"
+done
+`, ShellQuote(server.DefaultPath))
+
+	output, err := s.ssh.Run(server, script)
+	if err != nil {
+		return nil, fmt.Errorf("读取发布版本失败: %v: %s", err, strings.TrimSpace(output))
+	}
+
+	releases := []ReleaseInfo{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "|")
+		if len(fields) != 4 || fields[0] == "" {
+			continue
+		}
+		release := ReleaseInfo{Name: fields[0], Current: fields[1] == "1", SHA256: fields[2]}
+		if ts, err := strconv.ParseInt(fields[3], 10, 64); err == nil {
+			release.CreatedAt = time.Unix(ts, 0).Format("2006-01-02 15:04:05")
+		}
+		releases = append(releases, release)
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Name > releases[j].Name
+	})
+	return releases, nil
+}
+
+// Rollback 切换到指定版本（为空时切换到当前版本的上一个版本），返回切换后的版本名
+func (s *ReleaseService) Rollback(server *ServerData, target string) (string, error) {
+	if target == "" {
+		releases, err := s.List(server)
+		if err != nil {
+			return "", err
+		}
+		for i, release := range releases {
+			if release.Current && i+1 < len(releases) {
+				target = releases[i+1].Name
+				break
+			}
+		}
+		if target == "" {
+			return "", fmt.Errorf("服务器 %s 没有可回滚的上一个版本", server.ServerID)
+		}
+	}
+	if strings.ContainsAny(target, "/ ") || target == "." || target == ".." {
+		return "", fmt.Errorf("版本名称无效: %s", target)
+	}
+
+	script := fmt.Sprintf(`set -e
+cd %s
+name=%s
+[ -d "releases/$name" ] || { echo "版本 $name 不存在"; exit 1; }
+%s`, ShellQuote(server.DefaultPath), ShellQuote(target), switchScript)
+
+	output, err := s.ssh.Run(server, script)
+	if err != nil {
+		return "", fmt.Errorf("切换版本失败: %v: %s", err, strings.TrimSpace(output))
+	}
+
+	log.Printf("Rolled back server %s to release %s", server.ServerID, target)
+	return target, nil
+}
+
+// RollbackFrom 当前版本仍为 release 时切换到它的上一个版本，返回切换后的版本名
+// 用于部署后的自动回滚：release 已被之后的部署或手动回滚替换时不再切换
+func (s *ReleaseService) RollbackFrom(server *ServerData, release string) (string, error) {
+	releases, err := s.List(server)
+	if err != nil {
+		return "", err
+	}
+	for i, item := range releases {
+		if item.Name != release {
+			continue
+		}
+		if !item.Current {
+			return "", fmt.Errorf("服务器 %s 的当前版本已不是 %s，跳过回滚", server.ServerID, release)
+		}
+		if i+1 >= len(releases) {
+			return "", fmt.Errorf("服务器 %s 没有可回滚的上一个版本", server.ServerID)
+		}
+		return s.Rollback(server, releases[i+1].Name)
+	}
+	return "", fmt.Errorf("服务器 %s 上不存在版本 %s", server.ServerID, release)
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 发布批次及目标状态
+const (
+	RolloutPending   = "pending"
+	RolloutRunning   = "running"
+	RolloutSucceeded = "succeeded"
+	RolloutFailed    = "failed"
+	RolloutSkipped   = "skipped"
+	RolloutCanceled  = "canceled"
+)
+
+// 发布事件名称（通过 Wails 事件发送到前端）
+const (
+	RolloutTargetEvent = "rollout_target" // 单个目标（服务器）状态变化
+	RolloutStatusEvent = "rollout_status" // 整个发布的状态变化
+)
+
+// maxFinishedRollouts 保留的已结束发布数量
+const maxFinishedRollouts = 20
+
+// RolloutOptions 发布选项
+type RolloutOptions struct {
+	Parallelism   int  `json:"parallelism"`     // 同一批次内同时执行的服务器数量，默认1
+	BatchSize     int  `json:"batch_size"`      // 每批服务器数量，0 表示全部为一批
+	StopOnFailure bool `json:"stop_on_failure"` // 出现失败后不再开始新的服务器
+}
+
+// RolloutTarget 发布目标：一台服务器及其要更新的项目（为空表示服务器上的所有项目）
+type RolloutTarget struct {
+	ServerID   string   `json:"server_id"`
+	ProjectIDs []string `json:"project_ids,omitempty"`
+}
+
+// RolloutProjectStatus 目标服务器上单个项目的更新结果
+type RolloutProjectStatus struct {
+	ProjectID string `json:"project_id"`
+	Status    string `json:"status"`
+	JobID     string `json:"job_id,omitempty"`
+	ExitCode  int    `json:"exit_code"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RolloutTargetStatus 发布目标的状态（状态矩阵中的一行）
+type RolloutTargetStatus struct {
+	RolloutID  string                 `json:"rollout_id"`
+	ServerID   string                 `json:"server_id"`
+	Batch      int                    `json:"batch"`
+	Status     string                 `json:"status"`
+	Step       string                 `json:"step,omitempty"` // 当前步骤，如 uploading、config、updating
+	Projects   []RolloutProjectStatus `json:"projects"`
+	Error      string                 `json:"error,omitempty"`
+	StartedAt  string                 `json:"started_at,omitempty"`
+	FinishedAt string                 `json:"finished_at,omitempty"`
+}
+
+// RolloutStatus 发布整体状态
+type RolloutStatus struct {
+	RolloutID  string                `json:"rollout_id"`
+	Status     string                `json:"status"`
+	Options    RolloutOptions        `json:"options"`
+	Batches    int                   `json:"batches"`
+	Targets    []RolloutTargetStatus `json:"targets"`
+	Succeeded  int                   `json:"succeeded"`
+	Failed     int                   `json:"failed"`
+	Skipped    int                   `json:"skipped"`
+	StartedAt  string                `json:"started_at"`
+	FinishedAt string                `json:"finished_at,omitempty"`
+}
+
+// RolloutRunner 在单个目标上执行发布步骤，通过 report 汇报进度；ctx 在发布取消时结束
+type RolloutRunner func(ctx context.Context, target RolloutTarget, report *RolloutReport) error
+
+// RolloutReport 发布步骤向状态矩阵汇报进度
+type RolloutReport struct {
+	service *RolloutService
+	rollout *rollout
+	index   int
+}
+
+// rollout 运行中的发布
+type rollout struct {
+	status  RolloutStatus
+	targets []RolloutTarget
+	cancel  context.CancelFunc
+}
+
+// RolloutService 多服务器分批发布引擎
+type RolloutService struct {
+	mutex    sync.Mutex
+	rollouts map[string]*rollout
+	finished []string
+	callback func(event string, data interface{})
+}
+
+// NewRolloutService 创建发布引擎实例
+func NewRolloutService() *RolloutService {
+	return &RolloutService{rollouts: make(map[string]*rollout)}
+}
+
+// SetEventCallback 设置事件回调（用于向前端推送状态矩阵）
+func (s *RolloutService) SetEventCallback(callback func(event string, data interface{})) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callback = callback
+}
+
+// emit 发送事件
+func (s *RolloutService) emit(event string, data interface{}) {
+	s.mutex.Lock()
+	callback := s.callback
+	s.mutex.Unlock()
+
+	if callback != nil {
+		callback(event, data)
+	}
+}
+
+// snapshot 复制发布状态（调用方需持有锁）
+func (r *rollout) snapshot() RolloutStatus {
+	status := r.status
+	status.Targets = make([]RolloutTargetStatus, len(r.status.Targets))
+	for i, target := range r.status.Targets {
+		target.Projects = append([]RolloutProjectStatus(nil), target.Projects...)
+		status.Targets[i] = target
+	}
+	return status
+}
+
+// Start 登记发布并在后台按批次执行，返回初始状态
+func (s *RolloutService) Start(targets []RolloutTarget, options RolloutOptions, runner RolloutRunner) (RolloutStatus, error) {
+	if len(targets) == 0 {
+		return RolloutStatus{}, fmt.Errorf("没有发布目标")
+	}
+	if options.Parallelism < 1 {
+		options.Parallelism = 1
+	}
+	if options.BatchSize < 1 || options.BatchSize > len(targets) {
+		options.BatchSize = len(targets)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &rollout{
+		status: RolloutStatus{
+			RolloutID: newID("rollout"),
+			Status:    RolloutRunning,
+			Options:   options,
+			Batches:   (len(targets) + options.BatchSize - 1) / options.BatchSize,
+			Targets:   make([]RolloutTargetStatus, len(targets)),
+			StartedAt: time.Now().Format("2006-01-02 15:04:05"),
+		},
+		targets: targets,
+		cancel:  cancel,
+	}
+	for i, target := range targets {
+		projects := make([]RolloutProjectStatus, 0, len(target.ProjectIDs))
+		for _, projectID := range target.ProjectIDs {
+			projects = append(projects, RolloutProjectStatus{ProjectID: projectID, Status: RolloutPending, ExitCode: -1})
+		}
+		r.status.Targets[i] = RolloutTargetStatus{
+			RolloutID: r.status.RolloutID,
+			ServerID:  target.ServerID,
+			Batch:     i/options.BatchSize + 1,
+			Status:    RolloutPending,
+			Projects:  projects,
+		}
+	}
+
+	s.mutex.Lock()
+	s.rollouts[r.status.RolloutID] = r
+	status := r.snapshot()
+	s.mutex.Unlock()
+
+	log.Printf("Rollout %s started: %d target(s), batch size %d, parallelism %d, stop on failure %v",
+		status.RolloutID, len(targets), options.BatchSize, options.Parallelism, options.StopOnFailure)
+	s.emit(RolloutStatusEvent, status)
+
+	go s.run(ctx, r, runner)
+	return status, nil
+}
+
+// run 按批次执行发布：批次内最多 Parallelism 台并行，批次之间串行
+func (s *RolloutService) run(ctx context.Context, r *rollout, runner RolloutRunner) {
+	defer r.cancel()
+
+	size := r.status.Options.BatchSize
+	halted := false
+	for start := 0; start < len(r.targets); start += size {
+		end := start + size
+		if end > len(r.targets) {
+			end = len(r.targets)
+		}
+
+		slots := make(chan struct{}, r.status.Options.Parallelism)
+		var wg sync.WaitGroup
+		for index := start; index < end; index++ {
+			slots <- struct{}{}
+			if ctx.Err() != nil || (halted || s.hasFailure(r)) && r.status.Options.StopOnFailure {
+				halted = true
+				<-slots
+				continue
+			}
+
+			wg.Add(1)
+			go func(index int) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				s.runTarget(ctx, r, index, runner)
+			}(index)
+		}
+		wg.Wait()
+
+		if ctx.Err() != nil || r.status.Options.StopOnFailure && s.hasFailure(r) {
+			halted = true
+			break
+		}
+	}
+
+	s.mutex.Lock()
+	skipStatus := RolloutSkipped
+	if ctx.Err() != nil {
+		skipStatus = RolloutCanceled
+	}
+	var skipped []RolloutTargetStatus
+	for i := range r.status.Targets {
+		target := &r.status.Targets[i]
+		if target.Status == RolloutPending {
+			target.Status = skipStatus
+			for j := range target.Projects {
+				target.Projects[j].Status = skipStatus
+			}
+			skipped = append(skipped, *target)
+		}
+	}
+	r.status.Succeeded, r.status.Failed, r.status.Skipped = 0, 0, 0
+	for _, target := range r.status.Targets {
+		switch target.Status {
+		case RolloutSucceeded:
+			r.status.Succeeded++
+		case RolloutFailed:
+			r.status.Failed++
+		default:
+			r.status.Skipped++
+		}
+	}
+	switch {
+	case ctx.Err() != nil:
+		r.status.Status = RolloutCanceled
+	case r.status.Failed > 0 || halted:
+		r.status.Status = RolloutFailed
+	default:
+		r.status.Status = RolloutSucceeded
+	}
+	r.status.FinishedAt = time.Now().Format("2006-01-02 15:04:05")
+	status := r.snapshot()
+
+	s.finished = append(s.finished, status.RolloutID)
+	if len(s.finished) > maxFinishedRollouts {
+		delete(s.rollouts, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+	s.mutex.Unlock()
+
+	for _, target := range skipped {
+		s.emit(RolloutTargetEvent, target)
+	}
+	log.Printf("Rollout %s finished: %s (%d succeeded, %d failed, %d skipped)",
+		status.RolloutID, status.Status, status.Succeeded, status.Failed, status.Skipped)
+	s.emit(RolloutStatusEvent, status)
+}
+
+// hasFailure 是否已有目标失败
+func (s *RolloutService) hasFailure(r *rollout) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, target := range r.status.Targets {
+		if target.Status == RolloutFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// runTarget 执行单个目标并记录结果
+func (s *RolloutService) runTarget(ctx context.Context, r *rollout, index int, runner RolloutRunner) {
+	report := &RolloutReport{service: s, rollout: r, index: index}
+	report.update(func(target *RolloutTargetStatus) {
+		target.Status = RolloutRunning
+		target.StartedAt = time.Now().Format("2006-01-02 15:04:05")
+	})
+
+	err := runner(ctx, r.targets[index], report)
+
+	report.update(func(target *RolloutTargetStatus) {
+		target.FinishedAt = time.Now().Format("2006-01-02 15:04:05")
+		target.Step = ""
+		if err != nil {
+			target.Status = RolloutFailed
+			target.Error = err.Error()
+			if ctx.Err() != nil {
+				target.Status = RolloutCanceled
+			}
+			return
+		}
+		target.Status = RolloutSucceeded
+	})
+}
+
+// update 修改目标状态并推送事件
+func (p *RolloutReport) update(modify func(target *RolloutTargetStatus)) {
+	p.service.mutex.Lock()
+	target := &p.rollout.status.Targets[p.index]
+	modify(target)
+	snapshot := *target
+	snapshot.Projects = append([]RolloutProjectStatus(nil), target.Projects...)
+	p.service.mutex.Unlock()
+
+	p.service.emit(RolloutTargetEvent, snapshot)
+}
+
+// Step 汇报当前步骤
+func (p *RolloutReport) Step(step string) {
+	p.update(func(target *RolloutTargetStatus) {
+		target.Step = step
+	})
+}
+
+// Project 汇报单个项目的状态（不存在时追加）
+func (p *RolloutReport) Project(status RolloutProjectStatus) {
+	p.update(func(target *RolloutTargetStatus) {
+		for i := range target.Projects {
+			if target.Projects[i].ProjectID == status.ProjectID {
+				target.Projects[i] = status
+				return
+			}
+		}
+		target.Projects = append(target.Projects, status)
+	})
+}
+
+// Cancel 取消发布：运行中的步骤通过 ctx 结束，尚未开始的目标标记为已取消
+func (s *RolloutService) Cancel(rolloutID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r := s.rollouts[rolloutID]
+	if r == nil {
+		return fmt.Errorf("发布 %s 不存在", rolloutID)
+	}
+	if r.status.Status != RolloutRunning {
+		return fmt.Errorf("发布 %s 已结束", rolloutID)
+	}
+	log.Printf("Canceling rollout %s", rolloutID)
+	r.cancel()
+	return nil
+}
+
+// Status 查询发布状态矩阵
+func (s *RolloutService) Status(rolloutID string) (RolloutStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r := s.rollouts[rolloutID]
+	if r == nil {
+		return RolloutStatus{}, fmt.Errorf("发布 %s 不存在", rolloutID)
+	}
+	return r.snapshot(), nil
+}
+
+// List 列出发布（运行中的在前）
+func (s *RolloutService) List() []RolloutStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var running, done []RolloutStatus
+	for _, r := range s.rollouts {
+		if r.status.Status == RolloutRunning {
+			running = append(running, r.snapshot())
+		}
+	}
+	for i := len(s.finished) - 1; i >= 0; i-- {
+		if r := s.rollouts[s.finished[i]]; r != nil {
+			done = append(done, r.snapshot())
+		}
+	}
+	return append(running, done...)
+}
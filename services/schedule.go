@@ -0,0 +1,90 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// ScheduleStatus 定时任务状态
+type ScheduleStatus struct {
+	Running         bool   `json:"running"`
+	IntervalSeconds int    `json:"interval_seconds"`
+	StartedAt       string `json:"started_at,omitempty"`
+	LastRunAt       string `json:"last_run_at,omitempty"`
+}
+
+// scheduler 按固定间隔在后台执行任务（启动后立即执行一次），供指标采集、连通性检测等复用
+type scheduler struct {
+	mutex  sync.Mutex
+	status ScheduleStatus
+	stop   chan struct{}
+}
+
+// clampInterval 间隔为0时使用默认值，并不小于最小值
+func clampInterval(intervalSeconds, defaultSeconds, minSeconds int) int {
+	if intervalSeconds <= 0 {
+		intervalSeconds = defaultSeconds
+	}
+	if intervalSeconds < minSeconds {
+		intervalSeconds = minSeconds
+	}
+	return intervalSeconds
+}
+
+// Start 开始定时执行；已在运行时按新间隔重新开始
+func (s *scheduler) Start(intervalSeconds int, run func()) ScheduleStatus {
+	s.Stop()
+
+	s.mutex.Lock()
+	stop := make(chan struct{})
+	s.stop = stop
+	s.status = ScheduleStatus{
+		Running:         true,
+		IntervalSeconds: intervalSeconds,
+		StartedAt:       time.Now().Format("2006-01-02 15:04:05"),
+	}
+	status := s.status
+	s.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(intervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			run()
+
+			s.mutex.Lock()
+			if s.stop == stop {
+				s.status.LastRunAt = time.Now().Format("2006-01-02 15:04:05")
+			}
+			s.mutex.Unlock()
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return status
+}
+
+// Stop 停止定时执行（正在执行的一轮会继续完成），返回之前是否在运行
+func (s *scheduler) Stop() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.status.Running = false
+	if s.stop == nil {
+		return false
+	}
+	close(s.stop)
+	s.stop = nil
+	return true
+}
+
+// Status 定时任务状态
+func (s *scheduler) Status() ScheduleStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.status
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 远程数据库方言（决定字符串转义和布尔值写法）
+const (
+	DialectSQLite = "sqlite" // 默认，包括 Cloudflare D1
+	DialectMySQL  = "mysql"
+)
+
+// sqlNumber 可直接写入语句的数字（JSON 数字格式）
+var sqlNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// NamedArg 命名参数，对应语句中的 :name 占位符
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Named 创建命名参数
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// ParseSQLParams 解析前端传递的参数：数组对应 ? 占位符，对象对应 :name 占位符（按名称排序）
+func ParseSQLParams(paramsJson string) ([]interface{}, error) {
+	paramsJson = strings.TrimSpace(paramsJson)
+	if paramsJson == "" || paramsJson == "null" {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(paramsJson))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("参数格式错误: %v", err)
+	}
+
+	switch params := raw.(type) {
+	case []interface{}:
+		return params, nil
+	case map[string]interface{}:
+		names := make([]string, 0, len(params))
+		for name := range params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		args := make([]interface{}, 0, len(params))
+		for _, name := range names {
+			args = append(args, Named(name, params[name]))
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("参数必须是数组或对象")
+}
+
+// BindSQL 把参数按方言转义后填入语句中的占位符（? 按顺序对应位置参数，:name 对应命名参数，不能混用）；
+// 字符串、引号标识符和注释中的占位符不会被替换。strict 为 true 时，语句本身出现字符串或数字字面量即拒绝，
+// SQLite 的双引号标识符可能被当作字符串，严格模式下同样拒绝
+func BindSQL(statement, dialect string, strict bool, args ...interface{}) (string, error) {
+	if len(args) == 0 && !strict {
+		return statement, nil
+	}
+	if dialect == "" {
+		dialect = DialectSQLite
+	}
+	if dialect != DialectSQLite && dialect != DialectMySQL {
+		return "", fmt.Errorf("不支持的数据库方言 %q", dialect)
+	}
+
+	var positional []interface{}
+	named := make(map[string]interface{})
+	for _, arg := range args {
+		if arg, ok := arg.(NamedArg); ok {
+			if _, exists := named[arg.Name]; exists {
+				return "", fmt.Errorf("参数 %s 重复", arg.Name)
+			}
+			named[arg.Name] = arg.Value
+			continue
+		}
+		positional = append(positional, arg)
+	}
+	if len(positional) > 0 && len(named) > 0 {
+		return "", fmt.Errorf("位置参数和命名参数不能混用")
+	}
+
+	var out strings.Builder
+	used := make(map[string]bool, len(named))
+	next := 0
+	s := statement
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\'' || (c == '"' && dialect == DialectMySQL):
+			end := quotedEnd(s, i, dialect)
+			if end < 0 {
+				return "", fmt.Errorf("第 %d 个字符处的字符串没有结束", i+1)
+			}
+			if strict {
+				return "", fmt.Errorf("严格模式下不允许在语句中直接写字符串 %s，请改用参数", s[i:end])
+			}
+			out.WriteString(s[i:end])
+			i = end
+		case c == '"' || c == '`' || (c == '[' && dialect == DialectSQLite):
+			// SQLite 找不到双引号标识符时会把它当作字符串，严格模式下无法区分，一律拒绝
+			if strict && c == '"' {
+				return "", fmt.Errorf("严格模式下不允许使用双引号，标识符请改用 [] 或 ``，字符串请改用参数")
+			}
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(s[i+1:], closing)
+			if end < 0 {
+				return "", fmt.Errorf("第 %d 个字符处的标识符没有结束", i+1)
+			}
+			out.WriteString(s[i : i+end+2])
+			i += end + 2
+		case c == '-' && strings.HasPrefix(s[i:], "--"), c == '#' && dialect == DialectMySQL:
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				end = len(s) - i
+			}
+			out.WriteString(s[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return "", fmt.Errorf("第 %d 个字符处的注释没有结束", i+1)
+			}
+			out.WriteString(s[i : i+end+4])
+			i += end + 4
+		case c == '?':
+			if len(named) > 0 {
+				return "", fmt.Errorf("语句使用命名参数时不能再使用 ? 占位符")
+			}
+			if next >= len(positional) {
+				return "", fmt.Errorf("参数不足：第 %d 个 ? 没有对应的参数", next+1)
+			}
+			literal, err := SQLLiteral(positional[next], dialect)
+			if err != nil {
+				return "", fmt.Errorf("第 %d 个参数: %v", next+1, err)
+			}
+			out.WriteString(literal)
+			next++
+			i++
+		case c == ':' && i+1 < len(s) && isIdentStart(s[i+1]) && (i == 0 || s[i-1] != ':'):
+			end := i + 1
+			for end < len(s) && isIdentPart(s[end]) {
+				end++
+			}
+			name := s[i+1 : end]
+			value, ok := named[name]
+			if !ok {
+				return "", fmt.Errorf("缺少参数 %s", name)
+			}
+			literal, err := SQLLiteral(value, dialect)
+			if err != nil {
+				return "", fmt.Errorf("参数 %s: %v", name, err)
+			}
+			out.WriteString(literal)
+			used[name] = true
+			i = end
+		case isIdentStart(c):
+			end := i
+			for end < len(s) && isIdentPart(s[end]) {
+				end++
+			}
+			out.WriteString(s[i:end])
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(s) && isDigit(s[i+1])):
+			end := i
+			for end < len(s) && (isIdentPart(s[end]) || s[end] == '.' ||
+				((s[end] == '+' || s[end] == '-') && (s[end-1] == 'e' || s[end-1] == 'E'))) {
+				end++
+			}
+			if strict {
+				return "", fmt.Errorf("严格模式下不允许在语句中直接写数字 %s，请改用参数", s[i:end])
+			}
+			out.WriteString(s[i:end])
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	if next < len(positional) {
+		return "", fmt.Errorf("参数过多：语句只有 %d 个 ? 占位符，传入了 %d 个参数", next, len(positional))
+	}
+	for name := range named {
+		if !used[name] {
+			return "", fmt.Errorf("参数 %s 在语句中没有使用", name)
+		}
+	}
+	return out.String(), nil
+}
+
+// quotedEnd 字符串字面量结束后的位置（引号双写表示转义，MySQL 还支持反斜杠转义），未结束时返回 -1
+func quotedEnd(s string, start int, dialect string) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && dialect == DialectMySQL:
+			i++
+		case s[i] == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+// SQLLiteral 把参数值转换为方言对应的SQL字面量；支持 nil、布尔、整数、浮点数、json.Number、字符串、[]byte 和 time.Time
+// 负数加括号，避免紧跟在语句中的减号之后组成 -- 注释（如 1-? 填入 -5）
+func SQLLiteral(value interface{}, dialect string) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if dialect == DialectMySQL {
+			return strings.ToUpper(strconv.FormatBool(v)), nil
+		}
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case int:
+		return signedLiteral(strconv.FormatInt(int64(v), 10)), nil
+	case int8:
+		return signedLiteral(strconv.FormatInt(int64(v), 10)), nil
+	case int16:
+		return signedLiteral(strconv.FormatInt(int64(v), 10)), nil
+	case int32:
+		return signedLiteral(strconv.FormatInt(int64(v), 10)), nil
+	case int64:
+		return signedLiteral(strconv.FormatInt(v, 10)), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return formatSQLFloat(float64(v))
+	case float64:
+		return formatSQLFloat(v)
+	case json.Number:
+		if !sqlNumber.MatchString(string(v)) {
+			return "", fmt.Errorf("%q 不是有效的数字", string(v))
+		}
+		return signedLiteral(string(v)), nil
+	case string:
+		return quoteSQLString(v, dialect)
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	case time.Time:
+		return quoteSQLString(v.Format("2006-01-02 15:04:05"), dialect)
+	}
+	return "", fmt.Errorf("不支持的参数类型 %T", value)
+}
+
+// formatSQLFloat 浮点数字面量，NaN 和无穷大没有对应的SQL写法
+func formatSQLFloat(v float64) (string, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "", fmt.Errorf("不支持的数值 %v", v)
+	}
+	return signedLiteral(strconv.FormatFloat(v, 'g', -1, 64)), nil
+}
+
+// signedLiteral 负数字面量加括号
+func signedLiteral(literal string) string {
+	if strings.HasPrefix(literal, "-") {
+		return "(" + literal + ")"
+	}
+	return literal
+}
+
+// quoteSQLString 字符串字面量：SQLite 只需双写单引号；MySQL 默认把反斜杠作为转义符，需一并转义
+func quoteSQLString(value, dialect string) (string, error) {
+	if dialect != DialectMySQL {
+		if strings.IndexByte(value, 0) >= 0 {
+			return "", fmt.Errorf("字符串不能包含空字符")
+		}
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'", nil
+	}
+
+	var out strings.Builder
+	out.WriteByte('\'')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case 0:
+			out.WriteString(`\0`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case 0x1a:
+			out.WriteString(`\Z`)
+		case '\\', '\'', '"':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteByte('\'')
+	return out.String(), nil
+}
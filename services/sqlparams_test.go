@@ -0,0 +1,120 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLLiteral(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		dialect string
+		want    string
+		wantErr bool
+	}{
+		{"nil", nil, DialectSQLite, "NULL", false},
+		{"bool sqlite", true, DialectSQLite, "1", false},
+		{"bool mysql", false, DialectMySQL, "FALSE", false},
+		{"int", 42, DialectSQLite, "42", false},
+		{"negative int", -5, DialectSQLite, "(-5)", false},
+		{"min int64", int64(math.MinInt64), DialectMySQL, "(-9223372036854775808)", false},
+		{"max uint64", uint64(math.MaxUint64), DialectSQLite, "18446744073709551615", false},
+		{"float", 1.5, DialectSQLite, "1.5", false},
+		{"negative float", -0.25, DialectMySQL, "(-0.25)", false},
+		{"NaN", math.NaN(), DialectSQLite, "", true},
+		{"infinity", math.Inf(-1), DialectSQLite, "", true},
+		{"json number", json.Number("1e3"), DialectSQLite, "1e3", false},
+		{"negative json number", json.Number("-5"), DialectSQLite, "(-5)", false},
+		{"invalid json number", json.Number("1;drop"), DialectSQLite, "", true},
+		{"string", "abc", DialectSQLite, "'abc'", false},
+		{"quote sqlite", "it's", DialectSQLite, "'it''s'", false},
+		{"quote mysql", "it's", DialectMySQL, `'it\'s'`, false},
+		{"backslash sqlite", `a\b`, DialectSQLite, `'a\b'`, false},
+		{"backslash mysql", `a\b`, DialectMySQL, `'a\\b'`, false},
+		{"backslash quote sqlite", `a\'`, DialectSQLite, `'a\'''`, false},
+		{"backslash quote mysql", `a\'`, DialectMySQL, `'a\\\''`, false},
+		{"double quote sqlite", `"x"`, DialectSQLite, `'"x"'`, false},
+		{"double quote mysql", `"x"`, DialectMySQL, `'\"x\"'`, false},
+		{"control characters mysql", "a\n\r\x1a", DialectMySQL, `'a\n\r\Z'`, false},
+		{"newline sqlite", "a\nb", DialectSQLite, "'a\nb'", false},
+		{"NUL sqlite", "a\x00b", DialectSQLite, "", true},
+		{"NUL mysql", "a\x00b", DialectMySQL, `'a\0b'`, false},
+		{"bytes", []byte{0xde, 0xad}, DialectSQLite, "X'dead'", false},
+		{"time", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), DialectMySQL, "'2024-01-02 03:04:05'", false},
+		{"unsupported", struct{}{}, DialectSQLite, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SQLLiteral(tt.value, tt.dialect)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SQLLiteral(%#v) error = %v; want error %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("SQLLiteral(%#v) = %s; want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindSQL(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		dialect   string
+		strict    bool
+		args      []interface{}
+		want      string
+		wantErr   string // 错误信息包含的内容，为空表示绑定成功
+	}{
+		{"no args", "select 1", "", false, nil, "select 1", ""},
+		{"positional", "select * from t where a = ? and b = ?", "", false, []interface{}{"x'y", 3}, "select * from t where a = 'x''y' and b = 3", ""},
+		{"negative after minus", "select 1-?", "", false, []interface{}{-5}, "select 1-(-5)", ""},
+		{"negative json number after minus", "update t set n = n-? where id = ?", DialectMySQL, false,
+			[]interface{}{json.Number("-1"), 7}, "update t set n = n-(-1) where id = 7", ""},
+		{"mysql escaping", "select ?", DialectMySQL, false, []interface{}{`x\'; drop table t; --`}, `select 'x\\\'; drop table t; --'`, ""},
+		{"placeholder in string", "select '?', ?", "", false, []interface{}{1}, "select '?', 1", ""},
+		{"placeholder in comments", "select ? -- ?\n/* ? */", "", false, []interface{}{1}, "select 1 -- ?\n/* ? */", ""},
+		{"placeholder in mysql comment", "select ? # ?", DialectMySQL, false, []interface{}{1}, "select 1 # ?", ""},
+		{"placeholder in identifiers sqlite", `select "a?", [b?], ? from t`, "", false, []interface{}{1}, `select "a?", [b?], 1 from t`, ""},
+		{"placeholder in identifiers mysql", "select `a?`, \"b?\", ? from t", DialectMySQL, false, []interface{}{1}, "select `a?`, \"b?\", 1 from t", ""},
+		{"backslash in mysql string", `select 'a\'?', ?`, DialectMySQL, false, []interface{}{1}, `select 'a\'?', 1`, ""},
+		{"backslash in sqlite string", `select 'a\'?', ?`, DialectSQLite, false, []interface{}{1}, "", "没有结束"},
+		{"named", "select :b, :a", "", false, []interface{}{Named("a", "x"), Named("b", -2)}, "select (-2), 'x'", ""},
+		{"named before cast", "select :a::text", "", false, []interface{}{Named("a", "v")}, "select 'v'::text", ""},
+		{"strict placeholders", "select * from t1 where a = ?", "", true, []interface{}{1}, "select * from t1 where a = 1", ""},
+		{"strict string", "select * from t where a = 'x'", "", true, nil, "", "不允许在语句中直接写字符串"},
+		{"strict double quoted sqlite", `select * from t where name = "admin"`, "", true, nil, "", "不允许使用双引号"},
+		{"strict double quoted identifier", `select "name" from t where id = ?`, DialectSQLite, true, []interface{}{1}, "", "不允许使用双引号"},
+		{"strict bracket and backtick identifiers", "select [name], `id` from t where id = ?", "", true, []interface{}{1}, "select [name], `id` from t where id = 1", ""},
+		{"strict double quoted mysql", `select * from t where name = "admin"`, DialectMySQL, true, nil, "", "不允许在语句中直接写字符串"},
+		{"strict number", "select * from t where a = 1", "", true, nil, "", "不允许在语句中直接写数字"},
+		{"too few", "select ?, ?", "", false, []interface{}{1}, "", "参数不足"},
+		{"too many", "select ?", "", false, []interface{}{1, 2}, "", "参数过多"},
+		{"missing named", "select :a, :b", "", false, []interface{}{Named("a", 1)}, "", "缺少参数 b"},
+		{"unused named", "select :a", "", false, []interface{}{Named("a", 1), Named("b", 2)}, "", "没有使用"},
+		{"duplicate named", "select :a", "", false, []interface{}{Named("a", 1), Named("a", 2)}, "", "重复"},
+		{"mixed", "select ?, :a", "", false, []interface{}{1, Named("a", 2)}, "", "不能混用"},
+		{"question mark with named", "select ?, :a", "", false, []interface{}{Named("a", 2)}, "", "不能再使用 ?"},
+		{"unterminated comment", "select ? /*", "", false, []interface{}{1}, "", "注释没有结束"},
+		{"unsupported dialect", "select ?", "postgres", false, []interface{}{1}, "", "不支持的数据库方言"},
+		{"unsupported value", "select ?", "", false, []interface{}{math.NaN()}, "", "第 1 个参数"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BindSQL(tt.statement, tt.dialect, tt.strict, tt.args...)
+			if tt.wantErr == "" {
+				if err != nil || got != tt.want {
+					t.Fatalf("BindSQL = %q, %v; want %q", got, err, tt.want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("BindSQL = %q, %v; want error containing %q", got, err, tt.wantErr)
+			}
+		})
+	}
+}
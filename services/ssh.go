@@ -0,0 +1,276 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// SSH 认证方式
+const (
+	AuthTypePassword            = "password"
+	AuthTypeKey                 = "key"
+	AuthTypeAgent               = "agent"
+	AuthTypeKeyboardInteractive = "keyboard-interactive"
+)
+
+// HostKeyMismatchError 服务器主机密钥与已记录的指纹不一致（可能是重装系统，也可能是中间人攻击）
+type HostKeyMismatchError struct {
+	ServerID string `json:"server_id"`
+	Address  string `json:"address"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Error 实现 error 接口
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("服务器 %s (%s) 的主机密钥已变化，记录的指纹: %s，当前指纹: %s", e.ServerID, e.Address, e.Expected, e.Actual)
+}
+
+// errHostKeyFetched 仅获取主机密钥时用于中断握手
+var errHostKeyFetched = errors.New("host key fetched")
+
+// SSHService SSH连接服务，负责根据服务器配置构建认证方式并建立连接
+type SSHService struct {
+	vault   *VaultService
+	pool    sshPool
+	mutex   sync.Mutex
+	servers map[string]ServerData // 最近加载的服务器列表，用于解析跳板机
+}
+
+// NewSSHService 创建SSH服务实例
+func NewSSHService(vault *VaultService) *SSHService {
+	return &SSHService{
+		vault: vault,
+		pool:  sshPool{clients: make(map[string]*pooledClient)},
+	}
+}
+
+// isPlainSecret 是否为前端直接传入的明文凭据（非空且不是掩码）
+func isPlainSecret(value string) bool {
+	return value != "" && value != MaskedSecret
+}
+
+// Secrets 获取服务器凭据：前端传入明文时直接使用，否则在连接时从保险库解密
+func (s *SSHService) Secrets(server *ServerData) (ServerSecrets, error) {
+	secrets := ServerSecrets{}
+	needVault := false
+	for _, name := range secretFieldNames {
+		value := *serverSecretField(server, name)
+		if isPlainSecret(value) {
+			*secrets.field(name) = value
+		} else {
+			needVault = true
+		}
+	}
+	if !needVault {
+		return secrets, nil
+	}
+
+	stored, err := s.vault.Secrets(server.namespace, server.ServerID)
+	if err != nil {
+		// 保险库中没有该服务器，且前端也未传入掩码，说明未配置对应凭据
+		masked := false
+		for _, name := range secretFieldNames {
+			if *serverSecretField(server, name) == MaskedSecret {
+				masked = true
+			}
+		}
+		if !masked {
+			return secrets, nil
+		}
+		return ServerSecrets{}, err
+	}
+
+	for _, name := range secretFieldNames {
+		if !isPlainSecret(*serverSecretField(server, name)) {
+			*secrets.field(name) = *stored.field(name)
+		}
+	}
+	return secrets, nil
+}
+
+// loadPrivateKey 解析私钥，值不是PEM内容时按本地文件路径读取
+func loadPrivateKey(privateKey, passphrase string) (ssh.Signer, error) {
+	keyData := []byte(privateKey)
+	if !strings.HasPrefix(strings.TrimSpace(privateKey), "-----BEGIN") {
+		path := strings.TrimSpace(privateKey)
+		if strings.HasPrefix(path, "~") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥文件失败: %v", err)
+		}
+		keyData = data
+	}
+
+	if passphrase != "" {
+		signer, err := ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败（请检查私钥密码）: %v", err)
+		}
+		return signer, nil
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		if _, ok := err.(*ssh.PassphraseMissingError); ok {
+			return nil, fmt.Errorf("私钥已加密，请配置私钥密码")
+		}
+		return nil, fmt.Errorf("解析私钥失败: %v", err)
+	}
+	return signer, nil
+}
+
+// authMethods 根据服务器的认证方式构建SSH认证方法，返回的 cleanup 用于关闭 ssh-agent 连接
+func (s *SSHService) authMethods(server *ServerData) ([]ssh.AuthMethod, func(), error) {
+	secrets, err := s.Secrets(server)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() {}
+	switch server.AuthType {
+	case AuthTypeKey:
+		if secrets.PrivateKey == "" {
+			return nil, nil, fmt.Errorf("服务器 %s 未配置私钥", server.ServerID)
+		}
+		signer, err := loadPrivateKey(secrets.PrivateKey, secrets.KeyPassphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, cleanup, nil
+
+	case AuthTypeAgent:
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, nil, fmt.Errorf("未检测到 ssh-agent（SSH_AUTH_SOCK 未设置）")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, nil, fmt.Errorf("连接 ssh-agent 失败: %v", err)
+		}
+		agentClient := agent.NewClient(conn)
+		return []ssh.AuthMethod{ssh.PublicKeysCallback(agentClient.Signers)}, func() { conn.Close() }, nil
+
+	case AuthTypeKeyboardInteractive:
+		password := secrets.Password
+		challenge := func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range questions {
+				answers[i] = password
+			}
+			return answers, nil
+		}
+		return []ssh.AuthMethod{ssh.KeyboardInteractive(challenge)}, cleanup, nil
+
+	default:
+		// 默认密码认证（兼容旧数据）
+		var methods []ssh.AuthMethod
+		if secrets.Password != "" {
+			methods = append(methods, ssh.Password(secrets.Password))
+		}
+		return methods, cleanup, nil
+	}
+}
+
+// ClientConfig 构建SSH客户端配置，返回的 cleanup 需在握手完成后调用
+func (s *SSHService) ClientConfig(server *ServerData, timeout time.Duration) (*ssh.ClientConfig, func(), error) {
+	methods, cleanup, err := s.authMethods(server)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user := server.ServerUser
+	if user == "" {
+		user = "root"
+	}
+
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback(server, nil),
+		Timeout:         timeout,
+	}
+	return config, cleanup, nil
+}
+
+// hostKeyCallback 首次信任（TOFU）的主机密钥校验：未记录指纹时接受，已记录时必须一致
+// observed 不为空时写入服务器实际出示的指纹
+func hostKeyCallback(server *ServerData, observed *string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		fingerprint := ssh.FingerprintSHA256(key)
+		if observed != nil {
+			*observed = fingerprint
+		}
+		if server.HostKeyFingerprint == "" || server.HostKeyFingerprint == fingerprint {
+			return nil
+		}
+		return &HostKeyMismatchError{
+			ServerID: server.ServerID,
+			Address:  serverAddress(server),
+			Expected: server.HostKeyFingerprint,
+			Actual:   fingerprint,
+		}
+	}
+}
+
+// serverAddress 服务器SSH地址（默认端口22）
+func serverAddress(server *ServerData) string {
+	port := server.ServerPort
+	if port == "" {
+		port = "22"
+	}
+	return net.JoinHostPort(server.ServerIP, port)
+}
+
+// Dial 按服务器配置建立SSH连接（校验已记录的主机密钥）
+func (s *SSHService) Dial(server *ServerData, timeout time.Duration) (*ssh.Client, error) {
+	client, _, err := s.DialWithFingerprint(server, timeout)
+	return client, err
+}
+
+// DialWithFingerprint 建立SSH连接并返回服务器出示的主机密钥指纹（配置了跳板机时经由跳板机连接）
+func (s *SSHService) DialWithFingerprint(server *ServerData, timeout time.Duration) (*ssh.Client, string, error) {
+	return s.dial(server, timeout, 0)
+}
+
+// FetchHostKey 只完成密钥交换，获取服务器当前的主机密钥指纹（不需要凭据）
+func (s *SSHService) FetchHostKey(server *ServerData, timeout time.Duration) (string, error) {
+	var fingerprint string
+	config := &ssh.ClientConfig{
+		User: "probe",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			fingerprint = ssh.FingerprintSHA256(key)
+			return errHostKeyFetched
+		},
+		Timeout: timeout,
+	}
+
+	conn, closeJump, err := s.dialTransport(server, timeout, 0)
+	if err == nil {
+		defer closeJump()
+		var clientConn ssh.Conn
+		clientConn, _, _, err = ssh.NewClientConn(conn, serverAddress(server), config)
+		if err == nil {
+			clientConn.Close()
+		} else {
+			conn.Close()
+		}
+	}
+	if fingerprint == "" {
+		return "", fmt.Errorf("获取主机密钥失败: %v", err)
+	}
+	return fingerprint, nil
+}
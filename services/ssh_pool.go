@@ -0,0 +1,266 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 连接池参数
+const (
+	sshDialTimeout       = 30 * time.Second // 建立连接超时
+	sshKeepAliveInterval = 30 * time.Second // 保活请求间隔
+	sshIdleTimeout       = 5 * time.Minute  // 空闲多久后关闭连接
+)
+
+// pooledClient 连接池中的SSH连接
+type pooledClient struct {
+	client   *ssh.Client
+	key      string    // 连接配置摘要，配置变化后重建连接
+	refs     int       // 正在使用该连接的调用数
+	lastUsed time.Time // 最近一次释放的时间
+	stale    bool      // 已从池中移除，引用归零后关闭
+	done     chan struct{}
+}
+
+// sshPool 按服务器ID复用SSH连接
+type sshPool struct {
+	mutex   sync.Mutex
+	clients map[string]*pooledClient
+	janitor bool
+}
+
+// connectionKey 连接配置摘要（地址、用户、认证方式、主机密钥、跳板机和前端传入的凭据）
+func connectionKey(server *ServerData) string {
+	jumpHost := ""
+	if server.JumpHost != nil {
+		jumpHost = fmt.Sprintf("%+v", *server.JumpHost)
+	}
+	hash := sha256.New()
+	for _, value := range []string{
+		serverAddress(server), server.ServerUser, server.AuthType, server.HostKeyFingerprint,
+		server.ServerPassword, server.PrivateKey, server.KeyPassphrase, server.JumpServerID, jumpHost,
+	} {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// poolID 连接池的key，未保存的服务器按地址区分
+func poolID(server *ServerData) string {
+	if server.ServerID != "" {
+		return server.ServerID
+	}
+	return serverAddress(server)
+}
+
+// Client 从连接池获取服务器的SSH连接，不存在或已失效时重新建立；使用完毕后必须调用 release
+func (s *SSHService) Client(server *ServerData) (*ssh.Client, func(), error) {
+	id := poolID(server)
+	key := connectionKey(server)
+
+	s.pool.mutex.Lock()
+	entry := s.pool.clients[id]
+	if entry != nil && entry.key != key {
+		// 服务器配置已变化，旧连接不再复用
+		s.evictLocked(id, entry)
+		entry = nil
+	}
+	var lastUsed time.Time
+	if entry != nil {
+		entry.refs++
+		lastUsed = entry.lastUsed
+	}
+	s.pool.mutex.Unlock()
+
+	// 空闲过一段时间的连接先确认仍然可用
+	if entry != nil && time.Since(lastUsed) > sshKeepAliveInterval {
+		if _, _, err := entry.client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+			log.Printf("Pooled SSH connection to %s is dead, reconnecting: %v", id, err)
+			s.release(id, entry)
+			s.evict(id, entry)
+			entry = nil
+		}
+	}
+
+	if entry == nil {
+		var err error
+		entry, err = s.connect(server, id, key)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var once sync.Once
+	return entry.client, func() { once.Do(func() { s.release(id, entry) }) }, nil
+}
+
+// Session 在池化连接上创建会话，连接已断开时自动重连一次；使用完毕后调用 release 关闭会话
+func (s *SSHService) Session(server *ServerData) (*ssh.Session, func(), error) {
+	for attempt := 0; ; attempt++ {
+		client, release, err := s.Client(server)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		session, err := client.NewSession()
+		if err == nil {
+			return session, func() {
+				session.Close()
+				release()
+			}, nil
+		}
+
+		release()
+		s.Evict(server)
+		if attempt > 0 {
+			return nil, nil, fmt.Errorf("创建SSH会话失败: %v", err)
+		}
+		log.Printf("Failed to open session on pooled connection to %s, reconnecting: %v", poolID(server), err)
+	}
+}
+
+// Run 在池化连接上执行命令，返回合并的标准输出和错误输出
+func (s *SSHService) Run(server *ServerData, command string) (string, error) {
+	session, release, err := s.Session(server)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	output, err := session.CombinedOutput(command)
+	return string(output), err
+}
+
+// connect 建立新连接并放入连接池
+func (s *SSHService) connect(server *ServerData, id, key string) (*pooledClient, error) {
+	client, err := s.Dial(server, sshDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &pooledClient{
+		client:   client,
+		key:      key,
+		refs:     1,
+		lastUsed: time.Now(),
+		done:     make(chan struct{}),
+	}
+
+	s.pool.mutex.Lock()
+	if old := s.pool.clients[id]; old != nil {
+		s.evictLocked(id, old)
+	}
+	s.pool.clients[id] = entry
+	if !s.pool.janitor {
+		s.pool.janitor = true
+		go s.evictIdle()
+	}
+	s.pool.mutex.Unlock()
+
+	go s.keepAlive(id, entry)
+	log.Printf("Opened pooled SSH connection to %s (%s)", id, serverAddress(server))
+	return entry, nil
+}
+
+// keepAlive 定期发送保活请求，失败时从连接池移除
+func (s *SSHService) keepAlive(id string, entry *pooledClient) {
+	ticker := time.NewTicker(sshKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-entry.done:
+			return
+		case <-ticker.C:
+			if _, _, err := entry.client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				log.Printf("SSH keepalive to %s failed, dropping connection: %v", id, err)
+				s.evict(id, entry)
+				return
+			}
+		}
+	}
+}
+
+// evictIdle 定期关闭空闲超时的连接
+func (s *SSHService) evictIdle() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.pool.mutex.Lock()
+		for id, entry := range s.pool.clients {
+			if entry.refs == 0 && time.Since(entry.lastUsed) > sshIdleTimeout {
+				log.Printf("Closing idle SSH connection to %s", id)
+				s.evictLocked(id, entry)
+			}
+		}
+		s.pool.mutex.Unlock()
+	}
+}
+
+// release 释放连接引用
+func (s *SSHService) release(id string, entry *pooledClient) {
+	s.pool.mutex.Lock()
+	defer s.pool.mutex.Unlock()
+
+	entry.refs--
+	entry.lastUsed = time.Now()
+	if entry.stale && entry.refs <= 0 {
+		entry.client.Close()
+	}
+}
+
+// evict 从连接池移除指定连接
+func (s *SSHService) evict(id string, entry *pooledClient) {
+	s.pool.mutex.Lock()
+	defer s.pool.mutex.Unlock()
+	s.evictLocked(id, entry)
+}
+
+// evictLocked 从连接池移除连接，无人使用时立即关闭（调用方需持有锁）
+func (s *SSHService) evictLocked(id string, entry *pooledClient) {
+	if s.pool.clients[id] == entry {
+		delete(s.pool.clients, id)
+	}
+	if entry.stale {
+		return
+	}
+	entry.stale = true
+	close(entry.done)
+	if entry.refs <= 0 {
+		entry.client.Close()
+	}
+}
+
+// Evict 关闭服务器的池化连接（服务器配置修改或删除后调用）
+func (s *SSHService) Evict(server *ServerData) {
+	id := poolID(server)
+
+	s.pool.mutex.Lock()
+	defer s.pool.mutex.Unlock()
+	if entry := s.pool.clients[id]; entry != nil {
+		s.evictLocked(id, entry)
+	}
+}
+
+// EvictID 按服务器ID关闭池化连接
+func (s *SSHService) EvictID(serverID string) {
+	s.Evict(&ServerData{ServerID: serverID})
+}
+
+// Close 关闭连接池中的所有连接
+func (s *SSHService) Close() {
+	s.pool.mutex.Lock()
+	defer s.pool.mutex.Unlock()
+
+	for id, entry := range s.pool.clients {
+		s.evictLocked(id, entry)
+	}
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"adsplat/utils"
+)
+
+// Store 库存数据存储后端
+type Store interface {
+	// Name 后端名称，用于日志
+	Name() string
+	// Get 读取指定key的数据，key不存在时返回 Code 404
+	Get(key, authorization string) (*KvResponse, error)
+	// Put 写入指定key的数据
+	Put(key, value, authorization string) error
+	// PutIfMatch 仅当当前内容的ETag（内容MD5，key不存在时为空）与 etag 一致时写入，否则返回 ErrETagMismatch
+	PutIfMatch(key, value, etag, authorization string) error
+}
+
+// ErrETagMismatch 条件写入时存储内容已被修改
+var ErrETagMismatch = errors.New("存储内容已被修改")
+
+// contentETag 存储内容的ETag
+func contentETag(resp *KvResponse) string {
+	if resp == nil || resp.Code != 200 || resp.Data == nil {
+		return ""
+	}
+	return utils.MD5(resp.Data.Value)
+}
+
+// NewStoreFromEnv 根据环境变量选择存储后端
+// ADSPLAT_STORE=kv（默认）| file | memory，ADSPLAT_STORE_DIR 指定本地文件目录
+func NewStoreFromEnv() Store {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("ADSPLAT_STORE"))) {
+	case "file", "local":
+		dir := os.Getenv("ADSPLAT_STORE_DIR")
+		if dir == "" {
+			var err error
+			dir, err = AppDataDir("store")
+			if err != nil {
+				log.Printf("Failed to prepare local store dir, falling back to KV: %v", err)
+				return NewKvStore(NewKvService())
+			}
+		}
+		log.Printf("Using local encrypted file store: %s", dir)
+		return NewFileStore(dir)
+	case "memory":
+		log.Printf("Using in-memory store")
+		return NewMemoryStore()
+	default:
+		return NewKvStore(NewKvService())
+	}
+}
+
+// KvStore 远程KV存储后端
+type KvStore struct {
+	kvService *KvService
+}
+
+// NewKvStore 创建远程KV存储后端
+func NewKvStore(kvService *KvService) *KvStore {
+	return &KvStore{kvService: kvService}
+}
+
+// Name 后端名称
+func (s *KvStore) Name() string {
+	return "kv"
+}
+
+// Get 从远程KV读取数据
+func (s *KvStore) Get(key, authorization string) (*KvResponse, error) {
+	return s.kvService.GetKey(key, authorization)
+}
+
+// Put 写入远程KV
+func (s *KvStore) Put(key, value, authorization string) error {
+	resp, err := s.kvService.UpdateKey(key, value, authorization)
+	if err != nil {
+		return err
+	}
+	if resp.Code == 401 {
+		return fmt.Errorf("KV写入未授权: %s", resp.Msg)
+	}
+	if resp.Code != 200 {
+		return fmt.Errorf("KV写入失败，code: %d, msg: %s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// fileStoreKeyFile 本地存储密钥文件名（与数据文件同目录）
+const fileStoreKeyFile = "store.key"
+
+// fileStoreKeyPrefix 使用本地密钥加密的文件前缀，没有前缀的是旧版本用固定密钥加密的文件
+const fileStoreKeyPrefix = "v2:"
+
+// PutIfMatch 比较远程KV当前的ETag后写入，写入后再读取一次确认没有被覆盖
+// KV服务不支持条件写入，比较与写入之间存在竞争窗口：两个客户端可能都通过比较，后写入的覆盖先写入的。
+// 先写入的一方如果在确认时发现内容已被覆盖，返回 ErrETagMismatch 由调用方重新读取合并；
+// 覆盖发生在确认之后时仍无法发现。同一进程内的写入由调用方串行化
+func (s *KvStore) PutIfMatch(key, value, etag, authorization string) error {
+	current, err := s.kvService.ETag(key, authorization)
+	if err != nil {
+		return err
+	}
+	if current != etag {
+		return ErrETagMismatch
+	}
+	if err := s.Put(key, value, authorization); err != nil {
+		return err
+	}
+
+	written, err := s.kvService.ETag(key, authorization)
+	if err != nil {
+		log.Printf("Failed to confirm KV write of %s: %v", key, err)
+		return nil
+	}
+	if written != utils.MD5(value) {
+		log.Printf("KV write of %s was overwritten by another client", key)
+		return ErrETagMismatch
+	}
+	return nil
+}
+
+// FileStore 本地加密文件存储后端，每个key对应一个AES加密文件
+// 密钥在首次使用时随机生成并保存在同一目录，只防止单独拷走的数据文件被直接读取，
+// 不能防御能读取整个目录的人；服务器凭据的保护依赖保险库
+type FileStore struct {
+	dir        string
+	aesService *AesService
+	legacy     *AesService // 旧版本使用固定密钥加密的文件，读取时兼容，下次写入时改用本地密钥（密钥不可用时也用它写入）
+	mutex      sync.Mutex
+}
+
+// NewFileStore 创建本地加密文件存储后端
+func NewFileStore(dir string) *FileStore {
+	legacy := NewAesService()
+	store := &FileStore{dir: dir, aesService: legacy, legacy: legacy}
+	key, err := loadOrCreateStoreKey(dir)
+	if err != nil {
+		log.Printf("Failed to prepare local store key, using built-in key: %v", err)
+		return store
+	}
+	store.aesService = NewAesServiceWithKey(key)
+	return store
+}
+
+// loadOrCreateStoreKey 读取本地存储密钥，不存在时随机生成
+func loadOrCreateStoreKey(dir string) ([]byte, error) {
+	path := filepath.Join(dir, fileStoreKeyFile)
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("密钥文件 %s 长度无效", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, key, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Name 后端名称
+func (s *FileStore) Name() string {
+	return "file"
+}
+
+// path 根据key计算文件路径（key可能包含特殊字符，使用MD5命名）
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, utils.MD5(key)+".dat")
+}
+
+// Get 读取并解密本地文件
+func (s *FileStore) Get(key, authorization string) (*KvResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.read(key)
+}
+
+// read 读取并解密本地文件（调用方持有锁）
+func (s *FileStore) read(key string) (*KvResponse, error) {
+	content, err := os.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return &KvResponse{Code: 404, Msg: "key not found"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取本地存储失败: %v", err)
+	}
+
+	var value string
+	if data, ok := strings.CutPrefix(string(content), fileStoreKeyPrefix); ok {
+		value, err = s.aesService.Decrypt(data)
+	} else {
+		value, err = s.legacy.Decrypt(string(content))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解密本地存储失败: %v", err)
+	}
+
+	return &KvResponse{Code: 200, Msg: "success", Data: &KvData{Key: key, Value: value}}, nil
+}
+
+// Put 加密并写入本地文件
+func (s *FileStore) Put(key, value, authorization string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.write(key, value)
+}
+
+// PutIfMatch 在同一把锁内比较ETag并写入
+func (s *FileStore) PutIfMatch(key, value, etag, authorization string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	resp, err := s.read(key)
+	if err != nil {
+		return err
+	}
+	if contentETag(resp) != etag {
+		return ErrETagMismatch
+	}
+	return s.write(key, value)
+}
+
+// write 加密并写入本地文件（调用方持有锁）
+func (s *FileStore) write(key, value string) error {
+	prefix := fileStoreKeyPrefix
+	if s.aesService == s.legacy {
+		prefix = ""
+	}
+	encrypted, err := s.aesService.Encrypt(value)
+	if err != nil {
+		return fmt.Errorf("加密本地存储失败: %v", err)
+	}
+	encrypted = prefix + encrypted
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("创建本地存储目录失败: %v", err)
+	}
+
+	return writeFileAtomic(s.path(key), []byte(encrypted), 0600)
+}
+
+// MemoryStore 内存存储后端，用于离线调试和测试
+type MemoryStore struct {
+	values map[string]string
+	mutex  sync.RWMutex
+}
+
+// NewMemoryStore 创建内存存储后端
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string]string)}
+}
+
+// Name 后端名称
+func (s *MemoryStore) Name() string {
+	return "memory"
+}
+
+// Get 读取内存数据
+func (s *MemoryStore) Get(key, authorization string) (*KvResponse, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return &KvResponse{Code: 404, Msg: "key not found"}, nil
+	}
+	return &KvResponse{Code: 200, Msg: "success", Data: &KvData{Key: key, Value: value}}, nil
+}
+
+// Put 写入内存数据
+func (s *MemoryStore) Put(key, value, authorization string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.values[key] = value
+	return nil
+}
+
+// PutIfMatch 在同一把锁内比较ETag并写入
+func (s *MemoryStore) PutIfMatch(key, value, etag, authorization string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current := ""
+	if old, ok := s.values[key]; ok {
+		current = utils.MD5(old)
+	}
+	if current != etag {
+		return ErrETagMismatch
+	}
+	s.values[key] = value
+	return nil
+}
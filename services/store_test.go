@@ -0,0 +1,133 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"file":   NewFileStore(t.TempDir()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			resp, err := store.Get("missing", "")
+			if err != nil || resp.Code != 404 {
+				t.Fatalf("Get(missing) = %+v, %v; want code 404", resp, err)
+			}
+
+			for _, value := range []string{"", "x", `{"servers":[]}`, strings.Repeat("中文", 1000)} {
+				if err := store.Put("key", value, ""); err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+				resp, err := store.Get("key", "")
+				if err != nil || resp.Code != 200 || resp.Data == nil || resp.Data.Value != value {
+					t.Fatalf("Get after Put(%.20q) = %+v, %v", value, resp, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreUsesInstallKey(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	if err := store.Put("key", "secret", ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	content, err := os.ReadFile(store.path("key"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.HasPrefix(string(content), fileStoreKeyPrefix) {
+		t.Fatalf("file content %q has no %q prefix", content, fileStoreKeyPrefix)
+	}
+	if _, err := NewAesService().Decrypt(strings.TrimPrefix(string(content), fileStoreKeyPrefix)); err == nil {
+		t.Fatalf("file decrypts with the built-in key")
+	}
+
+	// 重新打开时使用同一个密钥
+	resp, err := NewFileStore(dir).Get("key", "")
+	if err != nil || resp.Data == nil || resp.Data.Value != "secret" {
+		t.Fatalf("Get from reopened store = %+v, %v", resp, err)
+	}
+}
+
+func TestFileStoreReadsLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	encrypted, err := NewAesService().Encrypt("legacy")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if err := os.WriteFile(store.path("key"), []byte(encrypted), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	resp, err := store.Get("key", "")
+	if err != nil || resp.Data == nil || resp.Data.Value != "legacy" {
+		t.Fatalf("Get legacy file = %+v, %v", resp, err)
+	}
+}
+
+func TestFileStoreCorruptFile(t *testing.T) {
+	valid, _ := NewAesService().Encrypt("value")
+	raw, _ := base64.StdEncoding.DecodeString(valid)
+
+	tests := map[string]string{
+		"empty":            "",
+		"not base64":       "!!!",
+		"header only":      base64.StdEncoding.EncodeToString(raw[:24]),
+		"truncated block":  base64.StdEncoding.EncodeToString(raw[:len(raw)-3]),
+		"truncated prefix": fileStoreKeyPrefix + base64.StdEncoding.EncodeToString(raw[:30]),
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := NewFileStore(t.TempDir())
+			if err := os.WriteFile(store.path("key"), []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if resp, err := store.Get("key", ""); err == nil {
+				t.Fatalf("Get corrupt file = %+v; want error", resp)
+			}
+		})
+	}
+}
+
+func TestAesDecryptRejectsInvalidInput(t *testing.T) {
+	service := NewAesService()
+	valid, err := service.Encrypt("hello")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(valid)
+
+	// 最后一个字节为0的明文块，解密后填充无效
+	block, _ := aes.NewCipher([]byte(KEY))
+	badPadding := make([]byte, 24+aes.BlockSize)
+	cipher.NewCBCEncrypter(block, badPadding[8:24]).CryptBlocks(badPadding[24:], make([]byte, aes.BlockSize))
+
+	tests := map[string][]byte{
+		"too short":     raw[:10],
+		"no ciphertext": raw[:24],
+		"partial block": raw[:24+5],
+		"bad padding":   badPadding,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if plain, err := service.Decrypt(base64.StdEncoding.EncodeToString(data)); err == nil {
+				t.Fatalf("Decrypt = %q; want error", plain)
+			}
+		})
+	}
+
+	if plain, err := service.Decrypt(valid); err != nil || plain != "hello" {
+		t.Fatalf("Decrypt(valid) = %q, %v", plain, err)
+	}
+}
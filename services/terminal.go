@@ -0,0 +1,292 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 终端事件名称（通过 Wails 事件发送到前端）
+const (
+	TerminalOutputEvent = "terminal_output"
+	TerminalClosedEvent = "terminal_closed"
+)
+
+// TerminalOutput 终端输出（data 为 base64 编码的原始字节，避免拆开多字节字符）
+type TerminalOutput struct {
+	TerminalID string `json:"terminal_id"`
+	Stream     string `json:"stream"` // "stdout" 或 "stderr"
+	Data       string `json:"data"`
+}
+
+// TerminalClosed 终端关闭事件
+type TerminalClosed struct {
+	TerminalID string `json:"terminal_id"`
+	ExitCode   int    `json:"exit_code"` // -1 表示未获得退出码
+	Error      string `json:"error,omitempty"`
+}
+
+// TerminalInfo 终端会话信息
+type TerminalInfo struct {
+	TerminalID string `json:"terminal_id"`
+	ServerID   string `json:"server_id"`
+	ServerName string `json:"server_name"`
+	Cols       int    `json:"cols"`
+	Rows       int    `json:"rows"`
+}
+
+// terminalSession 交互式终端会话
+type terminalSession struct {
+	info    TerminalInfo
+	session *ssh.Session
+	stdin   io.WriteCloser
+	release func()
+	once    sync.Once
+}
+
+// TerminalService 交互式终端服务，在已保存的服务器上打开PTY会话
+type TerminalService struct {
+	ssh      *SSHService
+	mutex    sync.Mutex
+	sessions map[string]*terminalSession
+	callback func(event string, data interface{})
+}
+
+// NewTerminalService 创建终端服务实例
+func NewTerminalService(sshService *SSHService) *TerminalService {
+	return &TerminalService{
+		ssh:      sshService,
+		sessions: make(map[string]*terminalSession),
+	}
+}
+
+// SetEventCallback 设置事件回调（用于向前端推送输出）
+func (s *TerminalService) SetEventCallback(callback func(event string, data interface{})) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callback = callback
+}
+
+// emit 发送事件
+func (s *TerminalService) emit(event string, data interface{}) {
+	s.mutex.Lock()
+	callback := s.callback
+	s.mutex.Unlock()
+
+	if callback != nil {
+		callback(event, data)
+	}
+}
+
+// Open 在服务器上打开交互式终端
+func (s *TerminalService) Open(server *ServerData, cols, rows int) (*TerminalInfo, error) {
+	if cols <= 0 {
+		cols = 80
+	}
+	if rows <= 0 {
+		rows = 24
+	}
+
+	session, release, err := s.ssh.Session(server)
+	if err != nil {
+		return nil, fmt.Errorf("SSH连接失败: %w", err)
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty("xterm-256color", rows, cols, modes); err != nil {
+		release()
+		return nil, fmt.Errorf("申请终端失败: %v", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		release()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		release()
+		return nil, err
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	if err := session.Shell(); err != nil {
+		release()
+		return nil, fmt.Errorf("启动Shell失败: %v", err)
+	}
+
+	terminal := &terminalSession{
+		info: TerminalInfo{
+			TerminalID: newID("term"),
+			ServerID:   server.ServerID,
+			ServerName: server.ServerName,
+			Cols:       cols,
+			Rows:       rows,
+		},
+		session: session,
+		stdin:   stdin,
+		release: release,
+	}
+
+	s.mutex.Lock()
+	s.sessions[terminal.info.TerminalID] = terminal
+	s.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go s.pump(terminal, "stdout", stdout, &wg)
+	go s.pump(terminal, "stderr", stderr, &wg)
+	go func() {
+		wg.Wait()
+		s.finish(terminal, session.Wait())
+	}()
+
+	log.Printf("Opened terminal %s on server %s", terminal.info.TerminalID, server.ServerID)
+	info := terminal.info
+	return &info, nil
+}
+
+// pump 转发终端输出
+func (s *TerminalService) pump(terminal *terminalSession, stream string, reader io.Reader, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			s.emit(TerminalOutputEvent, TerminalOutput{
+				TerminalID: terminal.info.TerminalID,
+				Stream:     stream,
+				Data:       base64.StdEncoding.EncodeToString(buf[:n]),
+			})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// finish 终端结束后释放连接并通知前端
+func (s *TerminalService) finish(terminal *terminalSession, err error) {
+	closed := TerminalClosed{TerminalID: terminal.info.TerminalID, ExitCode: -1}
+
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		closed.ExitCode = 0
+	case errors.As(err, &exitErr):
+		closed.ExitCode = exitErr.ExitStatus()
+	default:
+		closed.Error = err.Error()
+	}
+
+	s.release(terminal)
+	log.Printf("Terminal %s closed, exit code %d", terminal.info.TerminalID, closed.ExitCode)
+	s.emit(TerminalClosedEvent, closed)
+}
+
+// release 移除终端会话并归还连接
+func (s *TerminalService) release(terminal *terminalSession) {
+	terminal.once.Do(func() {
+		s.mutex.Lock()
+		delete(s.sessions, terminal.info.TerminalID)
+		s.mutex.Unlock()
+
+		terminal.session.Close()
+		terminal.release()
+	})
+}
+
+// get 获取终端会话
+func (s *TerminalService) get(terminalID string) (*terminalSession, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	terminal := s.sessions[terminalID]
+	if terminal == nil {
+		return nil, fmt.Errorf("终端 %s 不存在或已关闭", terminalID)
+	}
+	return terminal, nil
+}
+
+// Write 向终端写入输入
+func (s *TerminalService) Write(terminalID, data string) error {
+	terminal, err := s.get(terminalID)
+	if err != nil {
+		return err
+	}
+	if _, err := terminal.stdin.Write([]byte(data)); err != nil {
+		return fmt.Errorf("写入终端失败: %v", err)
+	}
+	return nil
+}
+
+// Resize 调整终端窗口大小
+func (s *TerminalService) Resize(terminalID string, cols, rows int) error {
+	terminal, err := s.get(terminalID)
+	if err != nil {
+		return err
+	}
+	if cols <= 0 || rows <= 0 {
+		return fmt.Errorf("终端尺寸无效: %dx%d", cols, rows)
+	}
+	if err := terminal.session.WindowChange(rows, cols); err != nil {
+		return fmt.Errorf("调整终端大小失败: %v", err)
+	}
+
+	s.mutex.Lock()
+	terminal.info.Cols = cols
+	terminal.info.Rows = rows
+	s.mutex.Unlock()
+	return nil
+}
+
+// Close 关闭终端
+func (s *TerminalService) Close(terminalID string) error {
+	terminal, err := s.get(terminalID)
+	if err != nil {
+		return err
+	}
+	terminal.stdin.Close()
+	s.release(terminal)
+	return nil
+}
+
+// List 列出打开的终端
+func (s *TerminalService) List() []TerminalInfo {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	list := make([]TerminalInfo, 0, len(s.sessions))
+	for _, terminal := range s.sessions {
+		list = append(list, terminal.info)
+	}
+	return list
+}
+
+// CloseAll 关闭所有终端（应用退出时调用）
+func (s *TerminalService) CloseAll() {
+	s.mutex.Lock()
+	terminals := make([]*terminalSession, 0, len(s.sessions))
+	for _, terminal := range s.sessions {
+		terminals = append(terminals, terminal)
+	}
+	s.mutex.Unlock()
+
+	for _, terminal := range terminals {
+		s.release(terminal)
+	}
+}
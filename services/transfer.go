@@ -0,0 +1,509 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/sftp"
+)
+
+// TransferProgressEvent 文件传输进度事件名称
+const TransferProgressEvent = "transfer_progress"
+
+// transferProgressStep 每传输多少字节推送一次进度
+const transferProgressStep = 256 * 1024
+
+// 传输方向
+const (
+	TransferUpload   = "upload"
+	TransferDownload = "download"
+)
+
+// TransferOptions 传输选项
+type TransferOptions struct {
+	Mode       os.FileMode // 文件权限，0 表示保留目标原有权限（新文件为 0644）
+	Owner      string      // 远程文件属主（如 "www:www"），为空不修改
+	TransferID string      // 进度事件中的传输ID，为空时自动生成
+}
+
+// TransferProgress 传输进度
+type TransferProgress struct {
+	TransferID string `json:"transfer_id"`
+	ServerID   string `json:"server_id"`
+	Direction  string `json:"direction"`
+	Phase      string `json:"phase"` // "transferring", "verifying", "done", "failed"
+	File       string `json:"file"`
+	BytesDone  int64  `json:"bytes_done"`
+	BytesTotal int64  `json:"bytes_total"`
+	FilesDone  int    `json:"files_done"`
+	FilesTotal int    `json:"files_total"`
+	Error      string `json:"error,omitempty"`
+}
+
+// TransferResult 单个文件的传输结果
+type TransferResult struct {
+	LocalPath  string `json:"local_path,omitempty"`
+	RemotePath string `json:"remote_path"`
+	Size       int64  `json:"size"`
+	SHA256     string `json:"sha256"`
+}
+
+// TransferService 基于SFTP的文件传输服务（先写临时文件再重命名，传输后校验SHA-256）
+type TransferService struct {
+	ssh      *SSHService
+	mutex    sync.Mutex
+	callback func(event string, data interface{})
+}
+
+// NewTransferService 创建文件传输服务实例
+func NewTransferService(sshService *SSHService) *TransferService {
+	return &TransferService{ssh: sshService}
+}
+
+// SetEventCallback 设置事件回调（用于向前端推送进度）
+func (s *TransferService) SetEventCallback(callback func(event string, data interface{})) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callback = callback
+}
+
+// emit 发送事件
+func (s *TransferService) emit(event string, data interface{}) {
+	s.mutex.Lock()
+	callback := s.callback
+	s.mutex.Unlock()
+
+	if callback != nil {
+		callback(event, data)
+	}
+}
+
+// transfer 一次传输（可能包含多个文件）的进度状态
+type transfer struct {
+	service  *TransferService
+	progress TransferProgress
+	lastSent int64
+}
+
+// newTransfer 创建传输进度跟踪
+func (s *TransferService) newTransfer(server *ServerData, direction string, opts TransferOptions) *transfer {
+	id := opts.TransferID
+	if id == "" {
+		id = newID("transfer")
+	}
+	return &transfer{
+		service: s,
+		progress: TransferProgress{
+			TransferID: id,
+			ServerID:   server.ServerID,
+			Direction:  direction,
+			Phase:      "transferring",
+		},
+	}
+}
+
+// add 累加已传输字节数，按步长推送进度
+func (t *transfer) add(n int64) {
+	t.progress.BytesDone += n
+	if t.progress.BytesDone-t.lastSent >= transferProgressStep {
+		t.lastSent = t.progress.BytesDone
+		t.service.emit(TransferProgressEvent, t.progress)
+	}
+}
+
+// phase 切换阶段并推送进度
+func (t *transfer) phase(phase, file string) {
+	t.progress.Phase = phase
+	if file != "" {
+		t.progress.File = file
+	}
+	t.service.emit(TransferProgressEvent, t.progress)
+}
+
+// done 结束传输并推送最终状态
+func (t *transfer) done(err error) error {
+	if err != nil {
+		t.progress.Error = err.Error()
+		t.phase("failed", "")
+		return err
+	}
+	t.phase("done", "")
+	return nil
+}
+
+// progressWriter 统计写入字节数
+type progressWriter struct {
+	transfer *transfer
+}
+
+// Write 实现 io.Writer 接口
+func (w progressWriter) Write(p []byte) (int, error) {
+	w.transfer.add(int64(len(p)))
+	return len(p), nil
+}
+
+// FileSHA256 计算本地文件的SHA-256
+func FileSHA256(localPath string) (string, int64, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// sftpClient 在池化连接上打开SFTP会话，使用完毕后调用 release
+func (s *TransferService) sftpClient(server *ServerData) (*sftp.Client, func(), error) {
+	client, release, err := s.ssh.Client(server)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SSH连接失败: %w", err)
+	}
+
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		release()
+		// 连接可能已失效，移除后下次重连
+		s.ssh.Evict(server)
+		return nil, nil, fmt.Errorf("打开SFTP会话失败: %v", err)
+	}
+
+	return sc, func() {
+		sc.Close()
+		release()
+	}, nil
+}
+
+// remoteSHA256 计算远程文件的SHA-256（优先使用 sha256sum，不可用时通过SFTP读回计算）
+func (s *TransferService) remoteSHA256(server *ServerData, sc *sftp.Client, remotePath string) (string, error) {
+	if session, release, err := s.ssh.Session(server); err == nil {
+		output, err := session.Output("sha256sum -- " + ShellQuote(remotePath))
+		release()
+		if fields := strings.Fields(string(output)); err == nil && len(fields) > 0 && len(fields[0]) == 64 {
+			return fields[0], nil
+		}
+	}
+
+	file, err := sc.Open(remotePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// upload 上传数据流到远程路径：写入同目录临时文件，校验后重命名为目标文件
+func (s *TransferService) upload(server *ServerData, sc *sftp.Client, t *transfer, reader io.Reader, remotePath string, opts TransferOptions) (*TransferResult, error) {
+	dir := path.Dir(remotePath)
+	if err := sc.MkdirAll(dir); err != nil {
+		return nil, fmt.Errorf("创建远程目录 %s 失败: %v", dir, err)
+	}
+
+	// 未指定权限时保留目标文件原有权限
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0644
+		if info, err := sc.Stat(remotePath); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	tmpPath := path.Join(dir, fmt.Sprintf(".%s.%s.tmp", path.Base(remotePath), newID("upload")))
+	file, err := sc.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return nil, fmt.Errorf("创建远程临时文件失败: %v", err)
+	}
+
+	t.phase("transferring", remotePath)
+	hash := sha256.New()
+	size, err := io.Copy(file, io.TeeReader(reader, io.MultiWriter(hash, progressWriter{t})))
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		sc.Remove(tmpPath)
+		return nil, fmt.Errorf("上传 %s 失败: %v", remotePath, err)
+	}
+
+	localSum := hex.EncodeToString(hash.Sum(nil))
+	t.phase("verifying", remotePath)
+	remoteSum, err := s.remoteSHA256(server, sc, tmpPath)
+	if err != nil {
+		sc.Remove(tmpPath)
+		return nil, fmt.Errorf("校验 %s 失败: %v", remotePath, err)
+	}
+	if remoteSum != localSum {
+		sc.Remove(tmpPath)
+		return nil, fmt.Errorf("校验 %s 失败: 本地 %s，远程 %s", remotePath, localSum, remoteSum)
+	}
+
+	if err := sc.Chmod(tmpPath, mode); err != nil {
+		sc.Remove(tmpPath)
+		return nil, fmt.Errorf("设置 %s 权限失败: %v", remotePath, err)
+	}
+	if opts.Owner != "" {
+		if output, err := s.ssh.Run(server, fmt.Sprintf("chown %s -- %s", ShellQuote(opts.Owner), ShellQuote(tmpPath))); err != nil {
+			sc.Remove(tmpPath)
+			return nil, fmt.Errorf("设置 %s 属主失败: %v: %s", remotePath, err, strings.TrimSpace(output))
+		}
+	}
+
+	// 原子替换目标文件（服务端不支持 posix-rename 扩展时先删除再重命名）
+	if err := sc.PosixRename(tmpPath, remotePath); err != nil {
+		sc.Remove(remotePath)
+		if err := sc.Rename(tmpPath, remotePath); err != nil {
+			sc.Remove(tmpPath)
+			return nil, fmt.Errorf("重命名 %s 失败: %v", remotePath, err)
+		}
+	}
+
+	t.progress.FilesDone++
+	return &TransferResult{RemotePath: remotePath, Size: size, SHA256: localSum}, nil
+}
+
+// UploadBytes 上传内容到远程文件
+func (s *TransferService) UploadBytes(server *ServerData, content []byte, remotePath string, opts TransferOptions) (*TransferResult, error) {
+	sc, release, err := s.sftpClient(server)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	t := s.newTransfer(server, TransferUpload, opts)
+	t.progress.BytesTotal = int64(len(content))
+	t.progress.FilesTotal = 1
+
+	result, err := s.upload(server, sc, t, bytes.NewReader(content), remotePath, opts)
+	return result, t.done(err)
+}
+
+// UploadFile 上传本地文件
+func (s *TransferService) UploadFile(server *ServerData, localPath, remotePath string, opts TransferOptions) (*TransferResult, error) {
+	results, err := s.Upload(server, localPath, remotePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// Upload 上传本地文件或目录（目录时递归上传到 remotePath 下）
+func (s *TransferService) Upload(server *ServerData, localPath, remotePath string, opts TransferOptions) ([]TransferResult, error) {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取本地文件失败: %v", err)
+	}
+
+	// 收集需要上传的文件
+	type item struct{ local, remote string }
+	var items []item
+	var total int64
+	if info.IsDir() {
+		err = filepath.Walk(localPath, func(p string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(localPath, p)
+			if err != nil {
+				return err
+			}
+			items = append(items, item{p, path.Join(remotePath, filepath.ToSlash(rel))})
+			total += fi.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("读取本地目录失败: %v", err)
+		}
+	} else {
+		items = append(items, item{localPath, remotePath})
+		total = info.Size()
+	}
+
+	sc, release, err := s.sftpClient(server)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	t := s.newTransfer(server, TransferUpload, opts)
+	t.progress.BytesTotal = total
+	t.progress.FilesTotal = len(items)
+	if info.IsDir() {
+		if err := sc.MkdirAll(remotePath); err != nil {
+			return nil, t.done(fmt.Errorf("创建远程目录 %s 失败: %v", remotePath, err))
+		}
+	}
+
+	results := make([]TransferResult, 0, len(items))
+	for _, it := range items {
+		file, err := os.Open(it.local)
+		if err != nil {
+			return results, t.done(fmt.Errorf("打开本地文件失败: %v", err))
+		}
+		result, err := s.upload(server, sc, t, file, it.remote, opts)
+		file.Close()
+		if err != nil {
+			return results, t.done(err)
+		}
+		result.LocalPath = it.local
+		results = append(results, *result)
+	}
+
+	log.Printf("Uploaded %d file(s), %d bytes to %s:%s", len(results), total, server.ServerID, remotePath)
+	return results, t.done(nil)
+}
+
+// download 下载远程文件到本地：写入同目录临时文件，校验后重命名
+func (s *TransferService) download(server *ServerData, sc *sftp.Client, t *transfer, remotePath, localPath string, opts TransferOptions) (*TransferResult, error) {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return nil, fmt.Errorf("创建本地目录失败: %v", err)
+	}
+
+	remote, err := sc.Open(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开远程文件 %s 失败: %v", remotePath, err)
+	}
+	defer remote.Close()
+
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0644
+		if info, err := remote.Stat(); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("创建本地临时文件失败: %v", err)
+	}
+	tmpPath := tmp.Name()
+
+	t.phase("transferring", remotePath)
+	hash := sha256.New()
+	size, err := io.Copy(tmp, io.TeeReader(remote, io.MultiWriter(hash, progressWriter{t})))
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("下载 %s 失败: %v", remotePath, err)
+	}
+
+	localSum := hex.EncodeToString(hash.Sum(nil))
+	t.phase("verifying", remotePath)
+	remoteSum, err := s.remoteSHA256(server, sc, remotePath)
+	if err != nil || remoteSum != localSum {
+		os.Remove(tmpPath)
+		if err == nil {
+			err = fmt.Errorf("本地 %s，远程 %s", localSum, remoteSum)
+		}
+		return nil, fmt.Errorf("校验 %s 失败: %v", remotePath, err)
+	}
+
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		log.Printf("Failed to chmod %s: %v", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("保存 %s 失败: %v", localPath, err)
+	}
+
+	t.progress.FilesDone++
+	return &TransferResult{LocalPath: localPath, RemotePath: remotePath, Size: size, SHA256: localSum}, nil
+}
+
+// Download 下载远程文件或目录（目录时递归下载到 localPath 下）
+func (s *TransferService) Download(server *ServerData, remotePath, localPath string, opts TransferOptions) ([]TransferResult, error) {
+	sc, release, err := s.sftpClient(server)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	info, err := sc.Stat(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取远程文件 %s 失败: %v", remotePath, err)
+	}
+
+	type item struct{ remote, local string }
+	var items []item
+	var total int64
+	if info.IsDir() {
+		walker := sc.Walk(remotePath)
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				return nil, fmt.Errorf("读取远程目录失败: %v", err)
+			}
+			if walker.Stat().IsDir() {
+				continue
+			}
+			rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), remotePath), "/")
+			items = append(items, item{walker.Path(), filepath.Join(localPath, filepath.FromSlash(rel))})
+			total += walker.Stat().Size()
+		}
+	} else {
+		items = append(items, item{remotePath, localPath})
+		total = info.Size()
+	}
+
+	t := s.newTransfer(server, TransferDownload, opts)
+	t.progress.BytesTotal = total
+	t.progress.FilesTotal = len(items)
+
+	results := make([]TransferResult, 0, len(items))
+	for _, it := range items {
+		result, err := s.download(server, sc, t, it.remote, it.local, opts)
+		if err != nil {
+			return results, t.done(err)
+		}
+		results = append(results, *result)
+	}
+
+	log.Printf("Downloaded %d file(s), %d bytes from %s:%s", len(results), total, server.ServerID, remotePath)
+	return results, t.done(nil)
+}
+
+// ReadFile 读取远程文件内容（不存在时返回 os.ErrNotExist）
+func (s *TransferService) ReadFile(server *ServerData, remotePath string) ([]byte, error) {
+	sc, release, err := s.sftpClient(server)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	file, err := sc.Open(remotePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, os.ErrNotExist
+		}
+		return nil, fmt.Errorf("打开远程文件 %s 失败: %v", remotePath, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取远程文件 %s 失败: %v", remotePath, err)
+	}
+	return data, nil
+}
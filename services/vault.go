@@ -0,0 +1,336 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// MaskedSecret 返回给前端的凭据掩码，提交时原样传回表示不修改
+const MaskedSecret = "******"
+
+// vaultCheckText 用于校验主密码是否正确的明文
+const vaultCheckText = "adsplat-vault"
+
+// ErrVaultLocked 凭据保险库未解锁
+var ErrVaultLocked = errors.New("凭据保险库未解锁，请先输入主密码")
+
+// ErrVaultRequired 尚未设置保险库时不能保存新的凭据
+var ErrVaultRequired = errors.New("尚未设置凭据保险库，请先设置主密码后再保存凭据")
+
+// ErrVaultPassphrase 主密码错误
+var ErrVaultPassphrase = errors.New("主密码错误")
+
+// ServerSecrets 服务器凭据（只保存在加密的保险库中）
+type ServerSecrets struct {
+	Password      string `json:"password,omitempty"`
+	PrivateKey    string `json:"private_key,omitempty"`
+	KeyPassphrase string `json:"key_passphrase,omitempty"`
+}
+
+// secretFieldNames 需要放入保险库的凭据字段
+var secretFieldNames = []string{"password", "private_key", "key_passphrase"}
+
+// field 按名称获取凭据字段
+func (s *ServerSecrets) field(name string) *string {
+	switch name {
+	case "private_key":
+		return &s.PrivateKey
+	case "key_passphrase":
+		return &s.KeyPassphrase
+	default:
+		return &s.Password
+	}
+}
+
+// serverSecretField 按名称获取服务器数据中对应的凭据字段
+func serverSecretField(server *ServerData, name string) *string {
+	switch name {
+	case "private_key":
+		return &server.PrivateKey
+	case "key_passphrase":
+		return &server.KeyPassphrase
+	default:
+		return &server.ServerPassword
+	}
+}
+
+// VaultDocument 保险库文档，与库存数据分开存储在 <key>_vault 中
+// 每个用户（KV命名空间）的保险库有独立的随机盐，密钥由主密码经 scrypt 派生
+type VaultDocument struct {
+	Version int                 `json:"version"`
+	Salt    string              `json:"salt"`
+	Check   string              `json:"check"`
+	Entries map[string]string   `json:"entries"`
+	Fields  map[string][]string `json:"fields,omitempty"` // 服务器ID -> 已保存的凭据字段名（不含值，用于掩码显示）
+}
+
+// FieldsOf 获取服务器在保险库中已保存的凭据字段
+func (d *VaultDocument) FieldsOf(serverID string) []string {
+	if _, ok := d.Entries[serverID]; !ok {
+		return nil
+	}
+	if fields, ok := d.Fields[serverID]; ok {
+		return fields
+	}
+	// 早期保险库只保存密码
+	return []string{"password"}
+}
+
+// vaultCache 某个KV命名空间最近加载的凭据
+type vaultCache struct {
+	salt   string
+	sealed map[string]string        // 服务器ID -> 密文
+	plain  map[string]ServerSecrets // 服务器ID -> 尚未迁移进保险库的旧明文凭据
+}
+
+// VaultService 凭据保险库服务
+type VaultService struct {
+	mutex      sync.RWMutex
+	passphrase []byte
+	keys       map[string][]byte      // salt -> 派生密钥
+	caches     map[string]*vaultCache // KV命名空间 -> 最近加载的凭据
+	current    string                 // 最近加载的命名空间，前端传入的服务器数据不带命名空间时使用
+}
+
+// NewVaultService 创建保险库服务实例
+func NewVaultService() *VaultService {
+	return &VaultService{
+		keys:   make(map[string][]byte),
+		caches: make(map[string]*vaultCache),
+	}
+}
+
+// Unlock 设置主密码（仅保存在内存中）
+func (s *VaultService) Unlock(passphrase string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.passphrase = []byte(passphrase)
+	s.keys = make(map[string][]byte)
+}
+
+// Lock 清除内存中的主密码和派生密钥
+func (s *VaultService) Lock() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.passphrase {
+		s.passphrase[i] = 0
+	}
+	s.passphrase = nil
+	s.keys = make(map[string][]byte)
+}
+
+// Unlocked 是否已解锁
+func (s *VaultService) Unlocked() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.passphrase) > 0
+}
+
+// key 获取指定盐对应的派生密钥
+func (s *VaultService) key(salt string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.passphrase) == 0 {
+		return nil, ErrVaultLocked
+	}
+	if key, ok := s.keys[salt]; ok {
+		return key, nil
+	}
+
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, fmt.Errorf("保险库盐格式错误: %v", err)
+	}
+	key, err := scrypt.Key(s.passphrase, saltBytes, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %v", err)
+	}
+	s.keys[salt] = key
+	return key, nil
+}
+
+// seal AES-GCM 加密
+func (s *VaultService) seal(salt string, plaintext []byte) (string, error) {
+	key, err := s.key(salt)
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// open AES-GCM 解密
+func (s *VaultService) open(salt, sealed string) ([]byte, error) {
+	key, err := s.key(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("密文长度错误")
+	}
+
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// NewDocument 创建新的保险库文档（需要已解锁）
+func (s *VaultService) NewDocument() (*VaultDocument, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	doc := &VaultDocument{
+		Version: 1,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Entries: make(map[string]string),
+	}
+
+	check, err := s.seal(doc.Salt, []byte(vaultCheckText))
+	if err != nil {
+		return nil, err
+	}
+	doc.Check = check
+	return doc, nil
+}
+
+// Verify 校验当前主密码能否打开保险库文档
+func (s *VaultService) Verify(doc *VaultDocument) error {
+	plaintext, err := s.open(doc.Salt, doc.Check)
+	if errors.Is(err, ErrVaultLocked) {
+		return err
+	}
+	if err != nil || string(plaintext) != vaultCheckText {
+		return ErrVaultPassphrase
+	}
+	return nil
+}
+
+// SealSecrets 加密服务器凭据
+func (s *VaultService) SealSecrets(doc *VaultDocument, secrets ServerSecrets) (string, error) {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return "", err
+	}
+	return s.seal(doc.Salt, plaintext)
+}
+
+// OpenSecrets 解密保险库文档中指定服务器的凭据
+func (s *VaultService) OpenSecrets(doc *VaultDocument, serverID string) (ServerSecrets, error) {
+	var secrets ServerSecrets
+	sealed, ok := doc.Entries[serverID]
+	if !ok {
+		return secrets, nil
+	}
+
+	plaintext, err := s.open(doc.Salt, sealed)
+	if err != nil {
+		return secrets, err
+	}
+	err = json.Unmarshal(plaintext, &secrets)
+	return secrets, err
+}
+
+// Remember 缓存命名空间最近加载的保险库密文和仍为明文的旧凭据，供连接时使用
+func (s *VaultService) Remember(namespace string, doc *VaultDocument, servers []ServerData) {
+	cache := &vaultCache{
+		sealed: make(map[string]string),
+		plain:  make(map[string]ServerSecrets),
+	}
+	if doc != nil {
+		cache.salt = doc.Salt
+		for serverID, sealed := range doc.Entries {
+			cache.sealed[serverID] = sealed
+		}
+	}
+	for i := range servers {
+		if !hasPlainSecrets(&servers[i]) {
+			continue
+		}
+		secrets := ServerSecrets{}
+		for _, name := range secretFieldNames {
+			if value := *serverSecretField(&servers[i], name); isPlainSecret(value) {
+				*secrets.field(name) = value
+			}
+		}
+		cache.plain[servers[i].ServerID] = secrets
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.caches[namespace] = cache
+	s.current = namespace
+}
+
+// Secrets 获取指定命名空间中服务器的凭据（仅在建立连接时调用），namespace 为空时使用最近加载的命名空间
+func (s *VaultService) Secrets(namespace, serverID string) (ServerSecrets, error) {
+	s.mutex.RLock()
+	if namespace == "" {
+		namespace = s.current
+	}
+	cache := s.caches[namespace]
+	s.mutex.RUnlock()
+
+	if cache == nil {
+		return ServerSecrets{}, fmt.Errorf("保险库中没有服务器 %s 的凭据", serverID)
+	}
+	sealed, ok := cache.sealed[serverID]
+	if !ok {
+		if secrets, ok := cache.plain[serverID]; ok {
+			return secrets, nil
+		}
+		return ServerSecrets{}, fmt.Errorf("保险库中没有服务器 %s 的凭据", serverID)
+	}
+
+	plaintext, err := s.open(cache.salt, sealed)
+	if err != nil {
+		if errors.Is(err, ErrVaultLocked) {
+			return ServerSecrets{}, err
+		}
+		return ServerSecrets{}, fmt.Errorf("解密服务器 %s 的凭据失败: %v", serverID, err)
+	}
+
+	var secrets ServerSecrets
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return ServerSecrets{}, err
+	}
+	return secrets, nil
+}
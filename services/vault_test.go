@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVaultSealOpen(t *testing.T) {
+	vault := NewVaultService()
+	vault.Unlock("correct horse")
+	doc, err := vault.NewDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := ServerSecrets{Password: "hunter2", PrivateKey: "-----BEGIN KEY-----", KeyPassphrase: "pass"}
+	sealed, err := vault.SealSecrets(doc, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Entries["a"] = sealed
+
+	tests := []struct {
+		name       string
+		passphrase string
+		verifyErr  error
+	}{
+		{"same passphrase", "correct horse", nil},
+		{"wrong passphrase", "wrong", ErrVaultPassphrase},
+		{"locked", "", ErrVaultLocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := NewVaultService()
+			if tt.passphrase != "" {
+				other.Unlock(tt.passphrase)
+			}
+			if err := other.Verify(doc); !errors.Is(err, tt.verifyErr) {
+				t.Fatalf("Verify = %v; want %v", err, tt.verifyErr)
+			}
+			opened, err := other.OpenSecrets(doc, "a")
+			if tt.verifyErr != nil {
+				if err == nil {
+					t.Fatalf("OpenSecrets = %+v; want error", opened)
+				}
+				return
+			}
+			if err != nil || opened != secrets {
+				t.Fatalf("OpenSecrets = %+v, %v; want %+v", opened, err, secrets)
+			}
+		})
+	}
+}
+
+func TestVaultSecretsByNamespace(t *testing.T) {
+	vault := NewVaultService()
+	vault.Unlock("pass")
+	for _, namespace := range []string{"one.json", "two.json"} {
+		doc, err := vault.NewDocument()
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc.Entries["a"], _ = vault.SealSecrets(doc, ServerSecrets{Password: "sealed-" + namespace})
+		vault.Remember(namespace, doc, []ServerData{{ServerID: "legacy", ServerPassword: "plain-" + namespace}})
+	}
+
+	tests := []struct {
+		namespace, serverID, want string
+	}{
+		{"one.json", "a", "sealed-one.json"},
+		{"two.json", "a", "sealed-two.json"},
+		{"one.json", "legacy", "plain-one.json"},
+		{"", "a", "sealed-two.json"}, // 未指定命名空间时使用最近加载的
+	}
+	for _, tt := range tests {
+		secrets, err := vault.Secrets(tt.namespace, tt.serverID)
+		if err != nil || secrets.Password != tt.want {
+			t.Errorf("Secrets(%q, %q) = %+v, %v; want %q", tt.namespace, tt.serverID, secrets, err, tt.want)
+		}
+	}
+	if _, err := vault.Secrets("three.json", "a"); err == nil {
+		t.Errorf("Secrets of unknown namespace succeeded")
+	}
+}
+
+// vaultServers 已把服务器 b 的密码迁移进保险库的服务
+func vaultServers(t *testing.T) (*JsonService, *MemoryStore) {
+	t.Helper()
+	store := NewMemoryStore()
+	servers := testServers()
+	servers[1].ServerPassword = "hunter2"
+	seedServers(t, store, servers)
+	service := NewJsonServiceWithStore(store)
+	if err := service.UnlockVault("pass", "", testNamespace); err != nil {
+		t.Fatal(err)
+	}
+	return service, store
+}
+
+func TestUnlockMigratesIntoVault(t *testing.T) {
+	service, store := vaultServers(t)
+
+	resp, _ := store.Get(testNamespace, "")
+	if strings.Contains(resp.Data.Value, "hunter2") {
+		t.Fatalf("inventory still contains the password: %s", resp.Data.Value)
+	}
+	servers, _ := service.LoadJsonFile("", testNamespace)
+	if servers[1].ServerPassword != MaskedSecret {
+		t.Fatalf("loaded password = %q; want mask", servers[1].ServerPassword)
+	}
+	secrets, err := service.Vault().Secrets(testNamespace, "b")
+	if err != nil || secrets.Password != "hunter2" {
+		t.Fatalf("vault secrets = %+v, %v", secrets, err)
+	}
+}
+
+func TestRestoreKeepsVaultEntries(t *testing.T) {
+	tests := []struct {
+		name   string
+		locked bool
+	}{
+		{"unlocked", false},
+		{"locked", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, store := vaultServers(t)
+			if err := service.SetServerLogSource("b", "/var/log/b", "", testNamespace); err != nil {
+				t.Fatal(err)
+			}
+			snapshots, _ := service.ListSnapshots("", testNamespace)
+			if tt.locked {
+				service.Vault().Lock()
+			}
+
+			if _, err := service.RestoreSnapshot(snapshots[len(snapshots)-1].Version, "", testNamespace); err != nil {
+				t.Fatalf("RestoreSnapshot: %v", err)
+			}
+
+			doc, _ := service.loadVault("", testNamespace)
+			if doc == nil || doc.Entries["b"] == "" {
+				t.Fatalf("vault entry of b was removed: %+v", doc)
+			}
+			service.Vault().Unlock("pass")
+			servers, _ := service.LoadJsonFile("", testNamespace)
+			if servers[1].ServerPassword != MaskedSecret || servers[1].LogSource != "" {
+				t.Fatalf("server after restore = %+v", servers[1])
+			}
+			if secrets, err := service.Vault().Secrets(testNamespace, "b"); err != nil || secrets.Password != "hunter2" {
+				t.Fatalf("vault secrets after restore = %+v, %v", secrets, err)
+			}
+			resp, _ := store.Get(testNamespace, "")
+			if strings.Contains(resp.Data.Value, "hunter2") {
+				t.Fatalf("inventory contains the password after restore: %s", resp.Data.Value)
+			}
+		})
+	}
+}
+
+func TestSaveCredentialsRequiresVault(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     string
+		err      error
+	}{
+		{"masked keeps legacy password", MaskedSecret, "old", nil},
+		{"unchanged legacy password", "old", "old", nil},
+		{"cleared", "", "", nil},
+		{"new password", "new", "", ErrVaultRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			servers := testServers()
+			servers[1].ServerPassword = "old"
+			seedServers(t, store, servers)
+			service := NewJsonServiceWithStore(store)
+			service.LoadJsonFile("", testNamespace)
+
+			update := ServerData{ServerID: "b", ServerIP: "10.0.0.2", DefaultPath: "/srv", ServerPassword: tt.password}
+			err := service.UpdateServerWithNewID("b", update, "", testNamespace)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("UpdateServerWithNewID = %v; want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			servers, _ = service.LoadJsonFile("", testNamespace)
+			if servers[1].ServerPassword != tt.want {
+				t.Fatalf("stored password = %q; want %q", servers[1].ServerPassword, tt.want)
+			}
+		})
+	}
+
+	// 设置保险库后可以保存新凭据
+	service, _ := vaultServers(t)
+	update := ServerData{ServerID: "a", ServerIP: "10.0.0.1", DefaultPath: "/srv", ServerPassword: "new"}
+	if err := service.UpdateServerWithNewID("a", update, "", testNamespace); err != nil {
+		t.Fatalf("saving with vault: %v", err)
+	}
+}